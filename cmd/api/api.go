@@ -6,29 +6,58 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/outbox"
+	"github.com/zechao158/ecomm/service/audit"
 	"github.com/zechao158/ecomm/service/auth"
 	"github.com/zechao158/ecomm/service/cart"
+	"github.com/zechao158/ecomm/service/cart/order"
 	"github.com/zechao158/ecomm/service/product"
 	"github.com/zechao158/ecomm/service/user"
+	"github.com/zechao158/ecomm/session"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
 	"gorm.io/gorm"
 )
 
 type APIServer struct {
 	addr string
 	db   *gorm.DB
+	// tenantHosts maps the request hosts to their tenant, defaultTenant is used for the other hosts.
+	tenantHosts   map[string]string
+	defaultTenant string
+	// productCache caches the product reads, nil disables it.
+	productCache storage.Cache
+	// session runs the business transactions, retrying them on serialization failures and deadlocks.
+	session session.Session
 }
 
-func NewAPIServer(addr string, db *gorm.DB) *APIServer {
+func NewAPIServer(addr string, db *gorm.DB, tenantHosts map[string]string, defaultTenant string, productCache storage.Cache, s session.Session) *APIServer {
 	return &APIServer{
-		addr: addr,
-		db:   db,
+		addr:          addr,
+		db:            db,
+		tenantHosts:   tenantHosts,
+		defaultTenant: defaultTenant,
+		productCache:  productCache,
+		session:       s,
 	}
 }
 
 func (s *APIServer) Run() error {
+	log.Println("Http servevr listening on:", s.addr)
+	server := &http.Server{
+		Addr:    s.addr,
+		Handler: s.Handler(),
+	}
+	return server.ListenAndServe()
+}
+
+// Handler returns the handler serving the routes of the API.
+func (s *APIServer) Handler() http.Handler {
 	router := mux.NewRouter()
 	router.Use(PanicRecoveryMiddleware)
 	router.Use(RequestLogMiddleware)
+	router.Use(TenantMiddleware(s.tenantHosts, s.defaultTenant))
 	router.Path("/health").Methods(http.MethodGet).
 		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -38,25 +67,54 @@ func (s *APIServer) Run() error {
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
 	userStore := user.NewRepository(s.db)
-	userHandler := user.NewHandler(userStore)
+	activityStore := user.NewActivityRepository(s.db)
+	userHandler := user.NewHandler(s.session, userStore, activityStore, user.NewRefreshTokenRepository(s.db))
 	userHandler.RegisterRoutes(subrouter)
 
+	if inst, ok := storage.InstrumentationOf(s.db); ok {
+		router.Use(QueryTrackingMiddleware)
+		subrouter.Handle("/debug/queries", auth.AuthMiddleware(userStore)(auth.AdminMiddleware(queryStatsHandler(inst)))).
+			Methods(http.MethodGet, http.MethodDelete)
+	}
+
 	productStore := product.NewRepository(s.db)
-	productHandler := product.NewHandler(productStore)
+	if s.productCache != nil {
+		productCache := storage.NewCached(storage.New[types.Product](s.db), s.productCache)
+		productStore = product.NewRepositoryFromStore(productCache)
+		subrouter.Handle("/debug/cache", auth.AuthMiddleware(userStore)(auth.AdminMiddleware(cacheStatsHandler(productCache)))).
+			Methods(http.MethodGet)
+	}
+	productHandler := product.NewHandler(productStore, userStore)
 	productSubrouter := subrouter.PathPrefix("/products").Subrouter()
 	productHandler.RegisterRoutes(productSubrouter)
 
-	cartUOW := cart.NewUnitOfWork(s.db)
-	cartHandler := cart.NewHandler(cartUOW)
+	cartHandler := cart.NewHandler(s.session, productStore, order.NewRepository(s.db), outbox.NewRepository(s.db), activityStore)
 	cartSubrouter := subrouter.PathPrefix("/carts").Subrouter()
 	cartSubrouter.Use(auth.AuthMiddleware(userStore))
 	cartHandler.RegisterRoutes(cartSubrouter)
 
-	log.Println("Http servevr listening on:", s.addr)
-	server := &http.Server{
-		Addr:    s.addr,
-		Handler: router,
-	}
-	return server.ListenAndServe()
+	auditHandler := audit.NewHandler(storage.New[storage.AuditEntry](s.db), userStore)
+	auditSubrouter := subrouter.PathPrefix("/audit").Subrouter()
+	auditHandler.RegisterRoutes(auditSubrouter)
+
+	return router
+}
+
+// queryStatsHandler returns the query metrics of the instrumentation, DELETE resets them.
+func queryStatsHandler(inst *storage.Instrumentation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			inst.Reset()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, inst.Stats())
+	})
+}
 
+// cacheStatsHandler returns the counters of the product cache.
+func cacheStatsHandler(cache *storage.CachedStore[types.Product]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httputil.WriteJSON(w, http.StatusOK, cache.Stats())
+	})
 }
@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zechao158/ecomm/cmd/api"
+	"github.com/zechao158/ecomm/migrations/sqlite"
+	"github.com/zechao158/ecomm/session"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+)
+
+// TestAPIServer boots the whole API against a SQLite database.
+func TestAPIServer(t *testing.T) {
+	db, err := storage.NewStorage(storage.Config{
+		Driver: storage.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "ecom.db"),
+	})
+	require.NoError(t, err)
+	sqldb, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqldb.Close() })
+	require.NoError(t, sqlite.Up(context.Background(), sqldb))
+
+	server := httptest.NewServer(api.NewAPIServer("", db, nil, "default", storage.NewLRUCache(100, 0), session.GORM(db, nil)).Handler())
+	t.Cleanup(server.Close)
+
+	do := func(method, path, token string, body any, out any) int {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	user := types.RegisterUserPayload{FirstName: "John", LastName: "Doe", Email: "john@test.com", Password: "secret"}
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/register", "", user, nil))
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/v1/register", "", user, nil))
+	var login map[string]string
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/login", "", types.LoginUserPayload{Email: user.Email, Password: user.Password}, &login))
+	var refreshed map[string]string
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/token/refresh", "", types.RefreshTokenPayload{RefreshToken: login["refreshToken"]}, &refreshed))
+	assert.NotEmpty(t, refreshed["token"])
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/api/v1/token/refresh", "", types.RefreshTokenPayload{RefreshToken: login["refreshToken"]}, nil))
+
+	var page storage.Page[types.Product]
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/products?limit=5", "", nil, &page))
+	assert.Len(t, page.Items, 5)
+	assert.NotEmpty(t, page.NextCursor)
+
+	productID := uuid.MustParse("55555555-5555-5555-5555-555555555555")
+	var product types.Product
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/products/"+productID.String(), "", nil, &product))
+	assert.Equal(t, 10, product.Quantity)
+
+	cart := types.CartCheckoutPayload{Items: []types.CartItem{{ProductID: productID, Quantity: 3}}}
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/carts/checkout", login["token"], cart, nil))
+
+	// the checkout invalidated the cached product
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/products/"+productID.String(), "", nil, &product))
+	assert.Equal(t, 7, product.Quantity)
+
+	var activity storage.Page[types.UserActivity]
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/me/activity", login["token"], nil, &activity))
+	var actions []string
+	for _, a := range activity.Items {
+		actions = append(actions, a.Action)
+	}
+	assert.Equal(t, []string{types.ActivityCheckout, types.ActivityLogin, types.ActivityRegister}, actions)
+}
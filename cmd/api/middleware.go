@@ -2,8 +2,14 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
 	"runtime"
+
+	"github.com/gorilla/mux"
+	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/storage"
 )
 
 func PanicRecoveryMiddleware(next http.Handler) http.Handler {
@@ -29,3 +35,48 @@ func RequestLogMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// QueryTrackingMiddleware counts the queries run by each request, so the instrumentation can report the ones
+// repeated too many times.
+func QueryTrackingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				scope = tpl
+			}
+		}
+		ctx := storage.TrackQueries(r.Context(), r.Method+" "+scope)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TenantHeader is the request header selecting the tenant explicitly.
+const TenantHeader = "X-Tenant-ID"
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// TenantMiddleware scopes the storage operations of the requests to their tenant, resolved from the TenantHeader
+// header, then from the host of the request with the hosts mapping, and finally set to fallback. The requests
+// with an invalid tenant, or without tenant when fallback is empty, are rejected.
+func TenantMiddleware(hosts map[string]string, fallback string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := r.Header.Get(TenantHeader)
+			if tenant == "" {
+				host, _, err := net.SplitHostPort(r.Host)
+				if err != nil {
+					host = r.Host
+				}
+				tenant = hosts[host]
+			}
+			if tenant == "" {
+				tenant = fallback
+			}
+			if !tenantPattern.MatchString(tenant) {
+				httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid tenant %q", tenant))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(storage.WithTenant(r.Context(), tenant)))
+		})
+	}
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/zechao158/ecomm/storage"
@@ -15,6 +18,26 @@ type Config struct {
 	HTTPPort             string
 	JWTSecret            string
 	JWTExpirationSecoond int
+	// RefreshTokenExpirationSecond is the lifetime of the refresh tokens, each use replaces the token with a new
+	// one of the same lifetime.
+	RefreshTokenExpirationSecond int
+	// OutboxPublisher selects where the outbox relay publishes the events: "file", "webhook" or empty to
+	// disable the relay.
+	OutboxPublisher  string
+	OutboxFile       string
+	OutboxWebhookURL string
+	// TenantHosts maps the request hosts to their tenant, the other requests use DefaultTenant unless they
+	// select a tenant with the X-Tenant-ID header.
+	TenantHosts   map[string]string
+	DefaultTenant string
+	// ProductCacheSize is the number of product reads cached in memory, zero disables the cache.
+	ProductCacheSize int
+	ProductCacheTTL  time.Duration
+	// TxIsolation is the default isolation level of the transactions, such as "serializable", empty keeps the
+	// one of the database. The transactions asking for their own level, like the checkout, ignore it. The
+	// transactions failing with a serialization failure or a deadlock are run again up to TxMaxAttempts times.
+	TxIsolation   string
+	TxMaxAttempts int
 	storage.Config
 }
 
@@ -31,12 +54,24 @@ func initConfig() Config {
 
 	debug, _ := strconv.ParseBool(getEnv("DEBUG_MODE", "false"))
 	return Config{
-		APPEnv:               appEnv,
-		HTTPHost:             getEnv("HTTP_HOST", "localhost"),
-		HTTPPort:             getEnv("HTTP_PORT", "8080"),
-		JWTSecret:            getEnv("JWT_SECRET", "some secret"),
-		JWTExpirationSecoond: getIntEnv("JWT_EXP_SECOND", 60*10),
+		APPEnv:                       appEnv,
+		HTTPHost:                     getEnv("HTTP_HOST", "localhost"),
+		HTTPPort:                     getEnv("HTTP_PORT", "8080"),
+		JWTSecret:                    getEnv("JWT_SECRET", "some secret"),
+		JWTExpirationSecoond:         getIntEnv("JWT_EXP_SECOND", 60*10),
+		RefreshTokenExpirationSecond: getIntEnv("REFRESH_TOKEN_EXP_SECOND", 60*60*24*30),
+		OutboxPublisher:              getEnv("OUTBOX_PUBLISHER", ""),
+		OutboxFile:                   getEnv("OUTBOX_FILE", "outbox.jsonl"),
+		OutboxWebhookURL:             getEnv("OUTBOX_WEBHOOK_URL", ""),
+		TenantHosts:                  getMapEnv("TENANT_HOSTS"),
+		DefaultTenant:                getEnv("DEFAULT_TENANT", "default"),
+		ProductCacheSize:             getIntEnv("PRODUCT_CACHE_SIZE", 1000),
+		ProductCacheTTL:              time.Duration(getIntEnv("PRODUCT_CACHE_TTL_SECOND", 60)) * time.Second,
+		TxIsolation:                  getEnv("TX_ISOLATION", ""),
+		TxMaxAttempts:                getIntEnv("TX_MAX_ATTEMPTS", storage.DefaultRetryPolicy.MaxAttempts),
 		Config: storage.Config{
+			Driver:     getEnv("DB_DRIVER", storage.DriverPostgres),
+			DBPath:     getEnv("DB_PATH", "ecom.db"),
 			DBUser:     getEnv("DB_USER", "ecom"),
 			DBName:     getEnv("DB_NAME", "ecom"),
 			DBHost:     getEnv("DB_HOST", "localhost"),
@@ -44,6 +79,11 @@ func initConfig() Config {
 			DBPort:     getEnv("DB_PORT", "5432"),
 			DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 			DebugMode:  debug,
+			Replicas:   getReplicasEnv("DB_REPLICAS"),
+
+			SlowQueryThreshold:     time.Duration(getIntEnv("DB_SLOW_QUERY_MS", 200)) * time.Millisecond,
+			RepeatedQueryThreshold: getIntEnv("DB_REPEATED_QUERY_THRESHOLD", 10),
+			StatementTimeout:       time.Duration(getIntEnv("DB_STATEMENT_TIMEOUT_MS", 0)) * time.Millisecond,
 		},
 	}
 
@@ -66,3 +106,38 @@ func getIntEnv(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// getReplicasEnv parses a comma separated list of host:port read replicas, the port defaults to the one of the primary.
+func getReplicasEnv(key string) []storage.Replica {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	var replicas []storage.Replica
+	for _, addr := range strings.Split(value, ",") {
+		addr = strings.TrimSpace(addr)
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, ""
+		}
+		replicas = append(replicas, storage.Replica{DBHost: host, DBPort: port})
+	}
+	return replicas
+}
+
+// getMapEnv parses a comma separated list of key=value pairs.
+func getMapEnv(key string) map[string]string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			log.Panicf("invalid key=value pair %q for key %s", pair, key)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
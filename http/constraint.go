@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/zechao158/ecomm/storage"
+)
+
+// FieldError is the error reported for a field of the API payloads.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// constraints maps the database constraints to the fields they validate, see RegisterConstraint.
+var constraints sync.Map
+
+// RegisterConstraint maps a database constraint to the field of the API payloads it validates, so
+// WriteStorageError can name the field when a write violates the constraint. name is the name of the constraint,
+// or "table.column" for the not null constraints, which have no name. When message is empty the error of the
+// storage is reported.
+func RegisterConstraint(name, field, message string) {
+	constraints.Store(name, FieldError{Field: field, Message: message})
+}
+
+// constraintFieldError returns the field error of a storage.ConstraintError with its HTTP status: 409 for the
+// unique violations and 422 for the other ones. The unregistered constraints are reported with their column.
+func constraintFieldError(err error) (FieldError, int, bool) {
+	var ce *storage.ConstraintError
+	if !errors.As(err, &ce) {
+		return FieldError{}, 0, false
+	}
+	status := http.StatusUnprocessableEntity
+	if ce.Kind == storage.ConstraintUnique {
+		status = http.StatusConflict
+	}
+	fe := FieldError{Field: ce.Column}
+	for _, name := range []string{ce.Constraint, ce.Table + "." + ce.Column} {
+		if v, ok := constraints.Load(name); ok {
+			fe = v.(FieldError)
+			break
+		}
+	}
+	if fe.Message == "" {
+		fe.Message = ce.Error()
+	}
+	return fe, status, true
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+
+	"github.com/zechao158/ecomm/storage"
 )
 
 func ParseJSON(r *http.Request, payload any) error {
@@ -23,6 +26,7 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
+// WriteError writes the error with the given status.
 func WriteError(w http.ResponseWriter, status int, err error) error {
 	return WriteJSON(w, status, map[string]string{
 		"error":  err.Error(),
@@ -30,4 +34,76 @@ func WriteError(w http.ResponseWriter, status int, err error) error {
 	})
 }
 
+// WriteStorageError writes the error of a write to the storage. A storage.ConstraintError is written with the
+// status and the field of the violated constraint, see RegisterConstraint, the other errors with the given status.
+func WriteStorageError(w http.ResponseWriter, status int, err error) error {
+	if fe, constraintStatus, ok := constraintFieldError(err); ok {
+		return WriteJSON(w, constraintStatus, map[string]string{
+			"error":  fe.Message,
+			"field":  fe.Field,
+			"status": strconv.Itoa(constraintStatus),
+		})
+	}
+	return WriteError(w, status, err)
+}
+
+// ParsePageRequest reads the pagination parameters "sort", "order", "cursor" and "limit" from the query string.
+func ParsePageRequest(r *http.Request) (storage.PageRequest, error) {
+	q := r.URL.Query()
+	p := storage.PageRequest{
+		SortKey: q.Get("sort"),
+		Cursor:  q.Get("cursor"),
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		p.Desc = true
+	default:
+		return p, fmt.Errorf("invalid order %q, must be asc or desc", q.Get("order"))
+	}
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			return p, fmt.Errorf("invalid limit %q", l)
+		}
+		p.Limit = limit
+	}
+	return p, nil
+}
+
+// ETag formats the version of an entity as a strong entity tag.
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// IfMatchVersion reads the entity version from the If-Match header, ok is false when the header is missing.
+func IfMatchVersion(r *http.Request) (version int, ok bool, err error) {
+	h := r.Header.Get("If-Match")
+	if h == "" {
+		return 0, false, nil
+	}
+	v, err := strconv.Unquote(strings.TrimPrefix(h, "W/"))
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid If-Match header %q", h)
+	}
+	version, err = strconv.Atoi(v)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid If-Match header %q", h)
+	}
+	return version, true, nil
+}
+
+// pageParams are the query parameters read by ParsePageRequest, they are never treated as filters.
+var pageParams = []string{"sort", "order", "cursor", "limit"}
+
+// ParseFilter builds a storage.Predicate from the query string, ignoring the pagination parameters.
+// Only the fields in allowed can be filtered.
+func ParseFilter(r *http.Request, allowed storage.AllowedFields) (storage.Predicate, error) {
+	q := r.URL.Query()
+	for _, p := range pageParams {
+		q.Del(p)
+	}
+	return storage.ParseFilter(q, allowed)
+}
+
 var Validate = validator.New()
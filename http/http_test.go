@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/storage"
+)
+
+func TestWriteStorageError(t *testing.T) {
+	httputil.RegisterConstraint("users_tenant_id_email_key", "email", "email is already registered")
+	write := func(status int, err error) (int, map[string]string) {
+		rr := httptest.NewRecorder()
+		assert.NoError(t, httputil.WriteStorageError(rr, status, err))
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		return rr.Code, body
+	}
+
+	status, body := write(http.StatusBadRequest, errors.New("invalid payload"))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]string{"error": "invalid payload", "status": "400"}, body)
+
+	status, body = write(http.StatusInternalServerError, &storage.ConstraintError{
+		Kind:       storage.ConstraintUnique,
+		Constraint: "users_tenant_id_email_key",
+		Table:      "users",
+		Column:     "email",
+	})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, map[string]string{"error": "email is already registered", "field": "email", "status": "409"}, body)
+
+	// the unregistered constraints are reported with their column
+	status, body = write(http.StatusInternalServerError, &storage.ConstraintError{
+		Kind:   storage.ConstraintNotNull,
+		Table:  "products",
+		Column: "name",
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, map[string]string{"error": "not null constraint violated by products.name", "field": "name", "status": "422"}, body)
+
+	// WriteError keeps the status of the caller
+	rr := httptest.NewRecorder()
+	assert.NoError(t, httputil.WriteError(rr, http.StatusInternalServerError, &storage.ConstraintError{
+		Kind:   storage.ConstraintNotNull,
+		Table:  "products",
+		Column: "name",
+	}))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
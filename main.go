@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
 	"os"
 
 	"github.com/zechao158/ecomm/cmd/api"
 	"github.com/zechao158/ecomm/config"
+	"github.com/zechao158/ecomm/outbox"
+	"github.com/zechao158/ecomm/session"
 	"github.com/zechao158/ecomm/storage"
 	"golang.org/x/exp/slog"
 	"gorm.io/gorm"
@@ -14,7 +18,9 @@ import (
 func main() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
 	log.Println("App running in environment:", config.ENVs.APPEnv)
-	db, err := storage.NewPostgreStorage(storage.Config{
+	db, err := storage.NewStorage(storage.Config{
+		Driver:     config.ENVs.Driver,
+		DBPath:     config.ENVs.DBPath,
 		DBUser:     config.ENVs.DBUser,
 		DBHost:     config.ENVs.DBHost,
 		DBName:     config.ENVs.DBName,
@@ -22,16 +28,64 @@ func main() {
 		DBPort:     config.ENVs.DBPort,
 		DBSSLMode:  config.ENVs.DBSSLMode,
 		DebugMode:  config.ENVs.DebugMode,
+		Replicas:   config.ENVs.Replicas,
+
+		SlowQueryThreshold:     config.ENVs.SlowQueryThreshold,
+		RepeatedQueryThreshold: config.ENVs.RepeatedQueryThreshold,
+		StatementTimeout:       config.ENVs.StatementTimeout,
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	checkStorage(db)
-	server := api.NewAPIServer(config.ENVs.HTTPHost+":"+config.ENVs.HTTPPort, db)
+	startOutboxRelay(db)
+	var productCache storage.Cache
+	if config.ENVs.ProductCacheSize > 0 {
+		productCache = storage.NewLRUCache(config.ENVs.ProductCacheSize, config.ENVs.ProductCacheTTL)
+	}
+	isolation, err := storage.ParseIsolationLevel(config.ENVs.TxIsolation)
+	if err != nil {
+		log.Fatal(err)
+	}
+	retry := storage.DefaultRetryPolicy
+	retry.MaxAttempts = config.ENVs.TxMaxAttempts
+	var txOptions *sql.TxOptions
+	if isolation != sql.LevelDefault {
+		txOptions = &sql.TxOptions{Isolation: isolation}
+	}
+	sess := session.GORM(db, txOptions)
+	sess.Retry = retry
+	server := api.NewAPIServer(config.ENVs.HTTPHost+":"+config.ENVs.HTTPPort, db, config.ENVs.TenantHosts, config.ENVs.DefaultTenant, productCache, sess)
 	err = server.Run()
 	if err != nil {
 		log.Panicf("error initializing server %v", err)
 	}
 }
 
+// startOutboxRelay publishes the outbox events in the background with the configured publisher.
+func startOutboxRelay(db *gorm.DB) {
+	var publisher outbox.Publisher
+	switch config.ENVs.OutboxPublisher {
+	case "":
+		log.Println("Outbox relay disabled")
+		return
+	case "file":
+		p, err := outbox.NewFilePublisher(config.ENVs.OutboxFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		publisher = p
+	case "webhook":
+		publisher = outbox.NewWebhookPublisher(config.ENVs.OutboxWebhookURL)
+	default:
+		log.Fatalf("unknown outbox publisher %q", config.ENVs.OutboxPublisher)
+	}
+	relay := outbox.NewRelay(outbox.NewRepository(db), publisher, outbox.RelayConfig{})
+	go relay.Run(context.Background())
+	log.Println("Outbox relay publishing to:", config.ENVs.OutboxPublisher)
+}
+
 func checkStorage(db *gorm.DB) {
 	conn, err := db.DB()
 	if err != nil {
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE ecom.users ADD COLUMN deleted_at TIMESTAMP NULL;
+ALTER TABLE ecom.products ADD COLUMN deleted_at TIMESTAMP NULL;
+ALTER TABLE ecom.orders ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX idx_users_deleted_at ON ecom.users (deleted_at);
+CREATE INDEX idx_products_deleted_at ON ecom.products (deleted_at);
+CREATE INDEX idx_orders_deleted_at ON ecom.orders (deleted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS ecom.idx_users_deleted_at;
+DROP INDEX IF EXISTS ecom.idx_products_deleted_at;
+DROP INDEX IF EXISTS ecom.idx_orders_deleted_at;
+
+ALTER TABLE ecom.users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE ecom.products DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE ecom.orders DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
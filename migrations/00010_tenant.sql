@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE ecom.users ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE ecom.products ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE ecom.orders ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- the unique values are only unique within a tenant
+ALTER TABLE ecom.users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE ecom.users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+ALTER TABLE ecom.products DROP CONSTRAINT IF EXISTS products_image_key;
+ALTER TABLE ecom.products ADD CONSTRAINT products_tenant_id_image_key UNIQUE (tenant_id, image);
+
+CREATE INDEX idx_products_tenant_id ON ecom.products (tenant_id, created_at);
+CREATE INDEX idx_orders_tenant_id ON ecom.orders (tenant_id, user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS ecom.idx_products_tenant_id;
+DROP INDEX IF EXISTS ecom.idx_orders_tenant_id;
+
+ALTER TABLE ecom.users DROP CONSTRAINT IF EXISTS users_tenant_id_email_key;
+ALTER TABLE ecom.users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE ecom.products DROP CONSTRAINT IF EXISTS products_tenant_id_image_key;
+ALTER TABLE ecom.products ADD CONSTRAINT products_image_key UNIQUE (image);
+
+ALTER TABLE ecom.users DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE ecom.products DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE ecom.orders DROP COLUMN IF EXISTS tenant_id;
+-- +goose StatementEnd
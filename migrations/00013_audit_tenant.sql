@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE ecom.audit_log ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_audit_log_tenant_id ON ecom.audit_log (tenant_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS ecom.idx_audit_log_tenant_id;
+ALTER TABLE ecom.audit_log DROP COLUMN IF EXISTS tenant_id;
+-- +goose StatementEnd
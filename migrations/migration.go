@@ -2,11 +2,13 @@ package main
 
 import (
 	"embed"
+	"io/fs"
 	"log"
 	"os"
 
 	"github.com/pressly/goose/v3"
 	"github.com/zechao158/ecomm/config"
+	"github.com/zechao158/ecomm/migrations/sqlite"
 	"github.com/zechao158/ecomm/storage"
 )
 
@@ -14,7 +16,9 @@ import (
 var embedMigrations embed.FS
 
 func main() {
-	db, err := storage.NewPostgreStorage(storage.Config{
+	db, err := storage.NewStorage(storage.Config{
+		Driver:     config.ENVs.Driver,
+		DBPath:     config.ENVs.DBPath,
 		DBUser:     config.ENVs.DBUser,
 		DBHost:     config.ENVs.DBHost,
 		DBName:     config.ENVs.DBName,
@@ -32,7 +36,14 @@ func main() {
 	}
 
 	log.Println("runnig migration")
-	goose.SetBaseFS(embedMigrations)
+	var migrations fs.FS = embedMigrations
+	if config.ENVs.Driver == storage.DriverSQLite {
+		migrations = sqlite.FS
+		if err := goose.SetDialect(string(goose.DialectSQLite3)); err != nil {
+			log.Panic(err)
+		}
+	}
+	goose.SetBaseFS(migrations)
 
 	dir := ""
 	if len(os.Args) > 1 {
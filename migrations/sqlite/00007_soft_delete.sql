@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL;
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP NULL;
+ALTER TABLE orders ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX idx_products_deleted_at ON products (deleted_at);
+CREATE INDEX idx_orders_deleted_at ON orders (deleted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_products_deleted_at;
+DROP INDEX IF EXISTS idx_orders_deleted_at;
+
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE products DROP COLUMN deleted_at;
+ALTER TABLE orders DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    entity_type VARCHAR(255) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor_id UUID NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity_type, entity_id, created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS audit_log;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE products ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE orders ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- the unique values are only unique within a tenant
+DROP INDEX IF EXISTS users_email_key;
+CREATE UNIQUE INDEX users_tenant_id_email_key ON users (tenant_id, email);
+DROP INDEX IF EXISTS products_image_key;
+CREATE UNIQUE INDEX products_tenant_id_image_key ON products (tenant_id, image);
+
+CREATE INDEX idx_products_tenant_id ON products (tenant_id, created_at);
+CREATE INDEX idx_orders_tenant_id ON orders (tenant_id, user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_products_tenant_id;
+DROP INDEX IF EXISTS idx_orders_tenant_id;
+
+DROP INDEX IF EXISTS users_tenant_id_email_key;
+CREATE UNIQUE INDEX users_email_key ON users (email);
+DROP INDEX IF EXISTS products_tenant_id_image_key;
+CREATE UNIQUE INDEX products_image_key ON products (image);
+
+ALTER TABLE users DROP COLUMN tenant_id;
+ALTER TABLE products DROP COLUMN tenant_id;
+ALTER TABLE orders DROP COLUMN tenant_id;
+-- +goose StatementEnd
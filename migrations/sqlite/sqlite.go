@@ -0,0 +1,28 @@
+// Package sqlite holds the SQLite variants of the migrations. They create the same tables as the PostgreSQL
+// migrations, without the ecom schema that SQLite doesn't support, and with named unique indexes instead of
+// unique constraints since SQLite can't drop constraints.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Up applies all the pending migrations to the database.
+func Up(ctx context.Context, db *sql.DB) error {
+	p, err := goose.NewProvider(goose.DialectSQLite3, db, FS)
+	if err != nil {
+		return fmt.Errorf("failed to load the SQLite migrations: %w", err)
+	}
+	if _, err := p.Up(ctx); err != nil {
+		return fmt.Errorf("failed to apply the SQLite migrations: %w", err)
+	}
+	return nil
+}
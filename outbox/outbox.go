@@ -0,0 +1,134 @@
+// Package outbox implements the transactional outbox pattern: domain events are stored in the ecom.outbox
+// table by the same transaction as the business write that produced them, then a Relay publishes them
+// asynchronously. An event is never lost once the transaction committed, and it is delivered at least once,
+// so consumers must be idempotent, using the message ID for instance.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/zechao158/ecomm/storage"
+)
+
+const (
+	// StatusPending messages are waiting to be published, or to be retried after a failure.
+	StatusPending = "pending"
+	// StatusPublished messages have been handed to the publisher successfully.
+	StatusPublished = "published"
+	// StatusDead messages failed too many times and are not retried anymore.
+	StatusDead = "dead"
+)
+
+// Message is an event stored in the outbox.
+type Message struct {
+	ID      uuid.UUID `gorm:"type:uuid;primarykey"`
+	Topic   string
+	Key     string
+	Payload json.RawMessage `gorm:"type:jsonb"`
+	Status  string
+	// Attempts is the number of times the message has been claimed for publishing.
+	Attempts int
+	// AvailableAt is the time from which the message can be claimed, it is pushed back by the claims and the retries.
+	AvailableAt time.Time
+	LastError   string
+	PublishedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
+}
+
+func (Message) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "outbox")
+}
+
+// SkipAudit implements storage.AuditSkipper, the relay bookkeeping is not worth auditing.
+func (Message) SkipAudit() bool {
+	return true
+}
+
+// Repository stores the outbox messages.
+type Repository interface {
+	storage.CRUDStorer[Message]
+	// Claim locks up to limit pending messages that are available, skipping the ones claimed concurrently, and
+	// makes them unavailable for the lease duration so no other relay picks them while they are published.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error)
+}
+
+type repository struct {
+	storage.CRUDStorer[Message]
+	// db runs the claims in a transaction, it is nil when the repository wraps a store.
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{
+		CRUDStorer: storage.New[Message](db),
+		db:         db,
+	}
+}
+
+// NewRepositoryFromStore creates a Repository on top of any CRUDStorer, like the in-memory one used in tests.
+func NewRepositoryFromStore(store storage.CRUDStorer[Message]) Repository {
+	return &repository{CRUDStorer: store}
+}
+
+// Claim implements Repository.
+func (r *repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error) {
+	if r.db == nil {
+		return claim(ctx, r.CRUDStorer, limit, lease)
+	}
+	var claimed []Message
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		claimed, err = claim(ctx, storage.New[Message](tx), limit, lease)
+		return err
+	})
+	return claimed, err
+}
+
+func claim(ctx context.Context, store storage.CRUDStorer[Message], limit int, lease time.Duration) ([]Message, error) {
+	now := time.Now()
+	ms, err := store.Find(ctx, storage.Query{
+		Where:      storage.And(storage.Eq("status", StatusPending), storage.Lte("available_at", now)),
+		Order:      []storage.Order{{Field: "available_at"}, {Field: "created_at"}},
+		Limit:      limit,
+		ForUpdate:  true,
+		SkipLocked: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range ms {
+		ms[i].Attempts++
+		ms[i].AvailableAt = now.Add(lease)
+		if err := store.Update(ctx, &ms[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ms, nil
+}
+
+// Enqueue stores a new pending message with the JSON encoding of payload. The store must belong to the
+// transaction of the business write, so the message is only visible once that transaction commits.
+func Enqueue(ctx context.Context, store storage.CRUDStorer[Message], topic, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode the %s message: %w", topic, err)
+	}
+	now := time.Now()
+	return store.Create(ctx, &Message{
+		ID:          uuid.New(),
+		Topic:       topic,
+		Key:         key,
+		Payload:     data,
+		Status:      StatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+	})
+}
@@ -0,0 +1,167 @@
+package outbox_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zechao158/ecomm/outbox"
+	"github.com/zechao158/ecomm/storage"
+)
+
+type orderEvent struct {
+	OrderID string `json:"orderId"`
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publish", func(t *testing.T) {
+		repo := outbox.NewRepositoryFromStore(storage.NewMemory[outbox.Message]())
+		assert.NoError(t, outbox.Enqueue(ctx, repo, "order.created", "1", orderEvent{OrderID: "1"}))
+		assert.NoError(t, outbox.Enqueue(ctx, repo, "order.created", "2", orderEvent{OrderID: "2"}))
+		publisher := &outbox.MemoryPublisher{}
+		relay := outbox.NewRelay(repo, publisher, outbox.RelayConfig{})
+
+		n, err := relay.ProcessBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		published := publisher.Messages()
+		assert.Len(t, published, 2)
+		assert.Equal(t, "order.created", published[0].Topic)
+		assert.JSONEq(t, `{"orderId":"1"}`, string(published[0].Payload))
+
+		stored, err := repo.GetByID(ctx, published[0].ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, outbox.StatusPublished, stored.Status)
+		assert.NotNil(t, stored.PublishedAt)
+
+		n, err = relay.ProcessBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("claimed messages are not claimed again", func(t *testing.T) {
+		repo := outbox.NewRepositoryFromStore(storage.NewMemory[outbox.Message]())
+		assert.NoError(t, outbox.Enqueue(ctx, repo, "order.created", "1", orderEvent{OrderID: "1"}))
+
+		claimed, err := repo.Claim(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, 1, claimed[0].Attempts)
+		claimed, err = repo.Claim(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+	})
+
+	t.Run("retry and dead letter", func(t *testing.T) {
+		repo := outbox.NewRepositoryFromStore(storage.NewMemory[outbox.Message]())
+		assert.NoError(t, outbox.Enqueue(ctx, repo, "order.created", "1", orderEvent{OrderID: "1"}))
+		failing := outbox.PublisherFunc(func(ctx context.Context, m outbox.Message) error {
+			return errors.New("broker unavailable")
+		})
+		relay := outbox.NewRelay(repo, failing, outbox.RelayConfig{
+			MaxAttempts: 2,
+			MinBackoff:  time.Hour,
+		})
+
+		n, err := relay.ProcessBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		ms, err := repo.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, outbox.StatusPending, ms[0].Status)
+		assert.Equal(t, "broker unavailable", ms[0].LastError)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), ms[0].AvailableAt, time.Minute)
+
+		// the message is not available until the backoff elapsed
+		n, err = relay.ProcessBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		ms[0].AvailableAt = time.Now()
+		assert.NoError(t, repo.Update(ctx, &ms[0]))
+		n, err = relay.ProcessBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		dead, err := repo.GetByID(ctx, ms[0].ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, outbox.StatusDead, dead.Status)
+		assert.Equal(t, 2, dead.Attempts)
+	})
+
+	t.Run("Run stops with the context", func(t *testing.T) {
+		repo := outbox.NewRepositoryFromStore(storage.NewMemory[outbox.Message]())
+		assert.NoError(t, outbox.Enqueue(ctx, repo, "order.created", "1", orderEvent{OrderID: "1"}))
+		publisher := &outbox.MemoryPublisher{}
+		relay := outbox.NewRelay(repo, publisher, outbox.RelayConfig{PollInterval: time.Millisecond})
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- relay.Run(runCtx) }()
+		assert.Eventually(t, func() bool { return len(publisher.Messages()) == 1 }, time.Second, time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
+
+func TestPublishers(t *testing.T) {
+	ctx := context.Background()
+	m := outbox.Message{
+		ID:        uuid.New(),
+		Topic:     "order.created",
+		Key:       "1",
+		Payload:   json.RawMessage(`{"orderId":"1"}`),
+		CreatedAt: time.Now(),
+	}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.jsonl")
+		publisher, err := outbox.NewFilePublisher(path)
+		assert.NoError(t, err)
+		assert.NoError(t, publisher.Publish(ctx, m))
+		assert.NoError(t, publisher.Publish(ctx, m))
+		assert.NoError(t, publisher.Close())
+
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+		var lines []map[string]any
+		for scanner := bufio.NewScanner(f); scanner.Scan(); {
+			var line map[string]any
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		assert.Len(t, lines, 2)
+		assert.Equal(t, m.ID.String(), lines[0]["id"])
+		assert.Equal(t, map[string]any{"orderId": "1"}, lines[0]["payload"])
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		status := http.StatusOK
+		var received *http.Request
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+		publisher := outbox.NewWebhookPublisher(srv.URL)
+
+		assert.NoError(t, publisher.Publish(ctx, m))
+		assert.Equal(t, m.ID.String(), received.Header.Get("Idempotency-Key"))
+		assert.Equal(t, "order.created", received.Header.Get("X-Outbox-Topic"))
+
+		status = http.StatusServiceUnavailable
+		assert.Error(t, publisher.Publish(ctx, m))
+	})
+}
@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Publisher delivers the outbox messages to their consumers.
+type Publisher interface {
+	Publish(ctx context.Context, m Message) error
+}
+
+// PublisherFunc is an adapter to use an ordinary function as a Publisher.
+type PublisherFunc func(ctx context.Context, m Message) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// MemoryPublisher keeps the published messages in memory, it is meant to be used in tests.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Publish implements Publisher.
+func (p *MemoryPublisher) Publish(ctx context.Context, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+// Messages returns the published messages in publication order.
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.messages)
+}
+
+// event is the representation of a message sent by the file and webhook publishers.
+type event struct {
+	ID        string          `json:"id"`
+	Topic     string          `json:"topic"`
+	Key       string          `json:"key,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func newEvent(m Message) event {
+	return event{
+		ID:        m.ID.String(),
+		Topic:     m.Topic,
+		Key:       m.Key,
+		Payload:   m.Payload,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// FilePublisher appends the messages to a file, one JSON document per line.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher opens, or creates, the file the messages are appended to.
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the outbox file: %w", err)
+	}
+	return &FilePublisher{file: f}, nil
+}
+
+// Publish implements Publisher, the file is synced before returning so a published message is durable.
+func (p *FilePublisher) Publish(ctx context.Context, m Message) error {
+	data, err := json.Marshal(newEvent(m))
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+// Close closes the file.
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// WebhookPublisher posts the messages as JSON to an HTTP endpoint, any status other than 2xx is a failure.
+type WebhookPublisher struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookPublisher creates a WebhookPublisher posting to url with a 10 seconds timeout.
+func NewWebhookPublisher(url string) *WebhookPublisher {
+	return &WebhookPublisher{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Publish implements Publisher. The message ID is sent in the Idempotency-Key header so the receiver can
+// discard the duplicates.
+func (p *WebhookPublisher) Publish(ctx context.Context, m Message) error {
+	data, err := json.Marshal(newEvent(m))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", m.ID.String())
+	req.Header.Set("X-Outbox-Topic", m.Topic)
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// RelayConfig configures a Relay, the zero values are replaced by the defaults.
+type RelayConfig struct {
+	// BatchSize is the maximum number of messages claimed at once, 100 by default.
+	BatchSize int
+	// PollInterval is the wait between two polls when the outbox is empty, 1 second by default.
+	PollInterval time.Duration
+	// Lease is how long a claimed message is hidden from the other relays, 1 minute by default.
+	// It must be longer than publishing a batch, a message whose lease expired is published again.
+	Lease time.Duration
+	// MaxAttempts is the number of failed publications after which a message is dead, 10 by default.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the delay before retrying a failed message, it doubles after every
+	// failure starting from MinBackoff. They are 1 second and 1 hour by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Relay publishes the pending outbox messages. Several relays can run concurrently, every message is
+// claimed by a single one of them.
+type Relay struct {
+	repo      Repository
+	publisher Publisher
+	cfg       RelayConfig
+}
+
+func NewRelay(repo Repository, publisher Publisher, cfg RelayConfig) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		cfg:       cfg,
+	}
+}
+
+// Run publishes the messages until the context is canceled, it polls the outbox when it is empty.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.ProcessBatch(ctx)
+		if err != nil {
+			slog.Error("failed to relay the outbox messages", "error", err)
+		}
+		if n == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.cfg.PollInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// ProcessBatch claims a batch of messages and publishes them, it returns the number of claimed messages.
+// A failed message is retried after a backoff, or marked as dead once it reached the maximum attempts.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	ms, err := r.repo.Claim(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range ms {
+		if err := r.publisher.Publish(ctx, m); err != nil {
+			r.fail(&m, err)
+		} else {
+			now := time.Now()
+			m.Status = StatusPublished
+			m.PublishedAt = &now
+			m.LastError = ""
+		}
+		// the outcome is saved even if ctx is canceled, otherwise the message would be published again
+		if err := r.repo.Update(context.WithoutCancel(ctx), &m); err != nil {
+			return len(ms), err
+		}
+	}
+	return len(ms), nil
+}
+
+func (r *Relay) fail(m *Message, err error) {
+	m.LastError = err.Error()
+	if m.Attempts >= r.cfg.MaxAttempts {
+		m.Status = StatusDead
+		slog.Warn("outbox message is dead", "id", m.ID, "topic", m.Topic, "attempts", m.Attempts, "error", err)
+		return
+	}
+	m.AvailableAt = time.Now().Add(r.backoff(m.Attempts))
+}
+
+// backoff returns the delay before the next attempt, after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.MinBackoff
+	for i := 1; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.cfg.MaxBackoff)
+}
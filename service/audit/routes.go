@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+)
+
+// filterFields are the audit log fields that can be used to filter the entries, the time range is given with
+// created_at[gte] and created_at[lt].
+var filterFields = storage.AllowedFields{
+	"entity_type": storage.StringField,
+	"entity_id":   storage.StringField,
+	"action":      storage.StringField,
+	"actor_id":    storage.UUIDField,
+	"created_at":  storage.TimeField,
+}
+
+// sortFields are the audit log fields the entries can be sorted by, actor_id is left out since the keyset
+// pagination can't sort on a nullable field.
+var sortFields = map[string]bool{
+	"entity_type": true,
+	"entity_id":   true,
+	"action":      true,
+	"created_at":  true,
+}
+
+type Handler struct {
+	store     storage.CRUDStorer[storage.AuditEntry]
+	userStore types.UserRepository
+}
+
+func NewHandler(store storage.CRUDStorer[storage.AuditEntry], userStore types.UserRepository) *Handler {
+	return &Handler{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.Handle("", auth.AuthMiddleware(h.userStore)(auth.AdminMiddleware(http.HandlerFunc(h.handleListEntries)))).Methods("GET")
+}
+
+// handleListEntries lists the audit log entries of the tenant of the request matching the filters, the most
+// recent first unless another order is requested.
+func (h *Handler) handleListEntries(w http.ResponseWriter, r *http.Request) {
+	pageReq, err := httputil.ParsePageRequest(r)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if pageReq.SortKey == "" {
+		pageReq.SortKey = "created_at"
+		pageReq.Desc = r.URL.Query().Get("order") != "asc"
+	}
+	if !sortFields[pageReq.SortKey] {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("%w: %s", storage.ErrInvalidSortKey, pageReq.SortKey))
+		return
+	}
+	filter, err := httputil.ParseFilter(r, filterFields)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	ps, err := h.store.GetPage(r.Context(), pageReq, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) || errors.Is(err, storage.ErrInvalidSortKey) {
+			httputil.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, ps)
+}
@@ -0,0 +1,99 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zechao158/ecomm/config"
+	"github.com/zechao158/ecomm/service/audit"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/service/user"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+)
+
+func TestAuditServiceHandlers(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory[storage.AuditEntry]()
+	userStore := user.NewRepositoryFromStore(storage.NewMemory[types.User]())
+	router := mux.NewRouter()
+	audit.NewHandler(store, userStore).RegisterRoutes(router.PathPrefix("/audit").Subrouter())
+
+	admin := types.User{ID: uuid.New(), Email: "admin@test.com", IsAdmin: true}
+	assert.NoError(t, userStore.Create(ctx, &admin))
+	token, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), admin.ID)
+	assert.NoError(t, err)
+
+	productID := uuid.NewString()
+	start := time.Now().Add(-time.Hour)
+	entries := []storage.AuditEntry{
+		{ID: uuid.New(), EntityType: "Product", EntityID: productID, Action: storage.AuditCreate, ActorID: &admin.ID, Changes: json.RawMessage(`{}`), CreatedAt: start},
+		{ID: uuid.New(), EntityType: "Product", EntityID: productID, Action: storage.AuditUpdate, ActorID: &admin.ID, Changes: json.RawMessage(`{"price":{"old":20,"new":30}}`), CreatedAt: start.Add(time.Minute)},
+		{ID: uuid.New(), EntityType: "User", EntityID: admin.ID.String(), Action: storage.AuditUpdate, Changes: json.RawMessage(`{}`), CreatedAt: start.Add(2 * time.Minute)},
+	}
+	_, err = store.CreateMany(ctx, entries, 0)
+	assert.NoError(t, err)
+
+	list := func(query string) (*httptest.ResponseRecorder, storage.Page[storage.AuditEntry]) {
+		req := httptest.NewRequest(http.MethodGet, "/audit"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var page storage.Page[storage.AuditEntry]
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		}
+		return rr, page
+	}
+
+	t.Run("list most recent first", func(t *testing.T) {
+		rr, page := list("")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, page.Items, 3)
+		assert.Equal(t, "User", page.Items[0].EntityType)
+	})
+
+	t.Run("filter by entity", func(t *testing.T) {
+		rr, page := list("?entity_type=Product&entity_id=" + productID + "&action=update")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, page.Items, 1)
+		assert.JSONEq(t, `{"price":{"old":20,"new":30}}`, string(page.Items[0].Changes))
+	})
+
+	t.Run("filter by actor and time range", func(t *testing.T) {
+		from := start.Add(30 * time.Second).Format(time.RFC3339)
+		rr, page := list("?actor_id=" + admin.ID.String() + "&created_at[gte]=" + from)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, storage.AuditUpdate, page.Items[0].Action)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		rr, _ := list("?changes=x")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		// actor_id can filter the entries but not sort them
+		rr, _ = list("?sort=actor_id")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), storage.ErrInvalidSortKey.Error())
+	})
+
+	t.Run("admin only", func(t *testing.T) {
+		customer := types.User{ID: uuid.New(), Email: "customer@test.com"}
+		assert.NoError(t, userStore.Create(ctx, &customer))
+		customerToken, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), customer.ID)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/audit", nil)
+		req.Header.Set("Authorization", "Bearer "+customerToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
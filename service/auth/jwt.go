@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/zechao158/ecomm/config"
 	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/storage"
 	"github.com/zechao158/ecomm/types"
 )
 
@@ -63,6 +64,7 @@ func AuthMiddleware(store types.UserRepository) func(http.Handler) http.Handler
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, user)
+			ctx = storage.WithActor(ctx, user.ID)
 			r = r.WithContext(ctx)
 
 			// Proceed to the next handler
@@ -71,6 +73,22 @@ func AuthMiddleware(store types.UserRepository) func(http.Handler) http.Handler
 	}
 }
 
+// AdminMiddleware rejects the requests of the users that are not admins, it must be wrapped by AuthMiddleware.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			httputil.WriteError(w, http.StatusUnauthorized, fmt.Errorf("missing user"))
+			return
+		}
+		if !user.IsAdmin {
+			httputil.WriteError(w, http.StatusForbidden, fmt.Errorf("admin permission required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func UserFromContext(ctx context.Context) (*types.User, bool) {
 	user, ok := ctx.Value(UserIDKey).(*types.User)
 	return user, ok
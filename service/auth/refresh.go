@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/zechao158/ecomm/config"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for an unknown, expired or revoked refresh token.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token is used again after it was replaced, its family is
+	// revoked since the token was probably stolen.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// NewRefreshToken returns a random refresh token and the hash to store, the token itself is only given to the
+// client.
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hash stored for the refresh token. The tokens are random so a fast hash is enough.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenExpiration returns the expiration time of a refresh token issued now.
+func RefreshTokenExpiration() time.Time {
+	return time.Now().Add(time.Second * time.Duration(config.ENVs.RefreshTokenExpirationSecond))
+}
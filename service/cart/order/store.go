@@ -1,6 +1,9 @@
 package order
 
 import (
+	"context"
+	"fmt"
+
 	"gorm.io/gorm"
 
 	"github.com/zechao158/ecomm/storage"
@@ -12,7 +15,20 @@ type repository struct {
 }
 
 func NewRepository(db *gorm.DB) types.OrderRepository {
+	return NewRepositoryFromStore(storage.New[types.Order](db))
+}
+
+// NewRepositoryFromStore creates the order repository on store.
+func NewRepositoryFromStore(store storage.CRUDStorer[types.Order]) types.OrderRepository {
 	return &repository{
-		storage.New[types.Order](db),
+		store,
+	}
+}
+
+func (s *repository) CountOrdersByStatus(ctx context.Context) (map[string]int64, error) {
+	counts, err := s.CountBy(ctx, "status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error counting the orders by status %w", err)
 	}
+	return counts, nil
 }
@@ -11,7 +11,12 @@ type repository struct {
 }
 
 func NewRepository(db *gorm.DB) types.OrderItemRepository {
+	return NewRepositoryFromStore(storage.New[types.OrderItem](db))
+}
+
+// NewRepositoryFromStore creates the order item repository on store.
+func NewRepositoryFromStore(store storage.CRUDStorer[types.OrderItem]) types.OrderItemRepository {
 	return &repository{
-		storage.New[types.OrderItem](db),
+		store,
 	}
 }
@@ -1,6 +1,9 @@
 package cart
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -9,17 +12,34 @@ import (
 	"github.com/gorilla/mux"
 
 	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/outbox"
 	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/service/user"
+	"github.com/zechao158/ecomm/session"
 	"github.com/zechao158/ecomm/types"
 )
 
+func init() {
+	httputil.RegisterConstraint("fk_product", "productId", "")
+}
+
 type Handler struct {
-	uowStore UnitOfWork
+	// session runs the checkout in a transaction joined by the repositories, it can be retried so the
+	// transaction must not have side effects outside of the database.
+	session           session.Session
+	productRepository types.ProductRepository
+	orderRepository   types.OrderRepository
+	outboxRepository  outbox.Repository
+	activities        types.UserActivityRepository
 }
 
-func NewHandler(uow UnitOfWork) *Handler {
+func NewHandler(s session.Session, products types.ProductRepository, orders types.OrderRepository, messages outbox.Repository, activities types.UserActivityRepository) *Handler {
 	return &Handler{
-		uowStore: uow,
+		session:           s,
+		productRepository: products,
+		orderRepository:   orders,
+		outboxRepository:  messages,
+		activities:        activities,
 	}
 }
 
@@ -50,14 +70,18 @@ func (h *Handler) handlerCheckout(w http.ResponseWriter, r *http.Request) {
 	// create the order
 	// create order items
 
-	user, ok := auth.UserFromContext(r.Context())
+	buyer, ok := auth.UserFromContext(r.Context())
 	if !ok {
 		http.Error(w, "user not found", http.StatusUnauthorized)
+		return
 	}
-	h.uowStore.Do(func(store OrderUOWStore) error {
-		ps, err := store.productRepository.GetProductsByIDs(r.Context(), getItemsIds(cart))
+	// the transaction can be retried, so the response is only written once it committed or failed for good.
+	// It is serializable so concurrent checkouts can't oversell the stock.
+	var order types.Order
+	err := h.session.Transaction(r.Context(), func(ctx context.Context) error {
+		// lock the products so the stock can't change until the order is created
+		ps, err := h.productRepository.GetProductsByIDs(ctx, getItemsIds(cart), true)
 		if err != nil {
-			httputil.WriteError(w, http.StatusInternalServerError, err)
 			return err
 		}
 		productMap := make(map[uuid.UUID]types.Product)
@@ -66,8 +90,7 @@ func (h *Handler) handlerCheckout(w http.ResponseWriter, r *http.Request) {
 		}
 		// check if all products are actually in stock
 		if err := checkIfCartIsInStock(cart.Items, productMap); err != nil {
-			httputil.WriteError(w, http.StatusBadRequest, err)
-			return err
+			return invalidCartError{err}
 		}
 		// calculate total price
 
@@ -77,33 +100,59 @@ func (h *Handler) handlerCheckout(w http.ResponseWriter, r *http.Request) {
 		for _, item := range cart.Items {
 			product := productMap[item.ProductID]
 			product.Quantity -= item.Quantity
-			if err := store.productRepository.Update(r.Context(), &product); err != nil {
-				httputil.WriteError(w, http.StatusInternalServerError, err)
+			productMap[item.ProductID] = product
+		}
+		// the versioned update bumps the version and fails if a product is gone, in the order of the locks
+		for _, locked := range ps {
+			product := productMap[locked.ID]
+			if err := h.productRepository.Update(ctx, &product); err != nil {
 				return err
 			}
 		}
 
-		order := types.Order{
+		order = types.Order{
 			ID:      uuid.New(),
-			UserID:  user.ID,
+			UserID:  buyer.ID,
 			Total:   totalPrice,
 			Status:  "pending",
 			Address: "some address",
 		}
-		err = store.orderRepository.Create(r.Context(), &order)
+		err = h.orderRepository.Create(ctx, &order)
 		if err != nil {
-			httputil.WriteError(w, http.StatusInternalServerError, err)
 			return err
 		}
-
-		httputil.WriteJSON(w, http.StatusOK, map[string]any{
-			"order_id": order.ID,
-			"total":    totalPrice,
+		if err := h.activities.Create(ctx, user.NewActivity(r, buyer.ID, types.ActivityCheckout, order.ID.String())); err != nil {
+			return err
+		}
+		// the event is committed with the order, the outbox relay publishes it afterwards
+		return outbox.Enqueue(ctx, h.outboxRepository, types.TopicOrderCreated, order.ID.String(), types.OrderCreatedEvent{
+			OrderID: order.ID,
+			UserID:  order.UserID,
+			Total:   order.Total,
+			Items:   cart.Items,
 		})
-		return nil
+	}, session.TxOptions{Isolation: sql.LevelSerializable})
+	var invalid invalidCartError
+	if errors.As(err, &invalid) {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		httputil.WriteStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, map[string]any{
+		"order_id": order.ID,
+		"total":    order.Total,
 	})
 }
 
+// invalidCartError is returned by the checkout transaction when the cart can't be ordered.
+type invalidCartError struct {
+	error
+}
+
 func calculateTotalPrice(cartItem []types.CartItem, productMap map[uuid.UUID]types.Product) float64 {
 	var total float64
 	for _, item := range cartItem {
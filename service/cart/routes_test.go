@@ -0,0 +1,112 @@
+package cart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zechao158/ecomm/config"
+	"github.com/zechao158/ecomm/outbox"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/service/cart/order"
+	"github.com/zechao158/ecomm/service/product"
+	"github.com/zechao158/ecomm/service/user"
+	"github.com/zechao158/ecomm/session/sessiontest"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+)
+
+func TestCartServiceHandlers(t *testing.T) {
+	ctx := context.Background()
+	userStore := user.NewRepositoryFromStore(storage.NewMemory[types.User]())
+	orders := storage.NewMemory[types.Order]()
+	messages := storage.NewMemory[outbox.Message]()
+	activities := storage.NewMemory[types.UserActivity]()
+	activityRepository := user.NewActivityRepositoryFromStore(activities)
+	products := product.NewRepositoryFromStore(storage.NewMemory[types.Product]())
+	orderRepository := order.NewRepositoryFromStore(orders)
+	outboxRepository := outbox.NewRepositoryFromStore(messages)
+	router := mux.NewRouter()
+	router.Use(auth.AuthMiddleware(userStore))
+	NewHandler(sessiontest.Memory{}, products, orderRepository, outboxRepository, activityRepository).RegisterRoutes(router)
+
+	buyer := types.User{ID: uuid.New(), Email: "buyer@test.com"}
+	assert.NoError(t, userStore.Create(ctx, &buyer))
+	token, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), buyer.ID)
+	assert.NoError(t, err)
+
+	hat := types.Product{ID: uuid.New(), Name: "hat", Price: 20, Quantity: 3}
+	assert.NoError(t, products.Create(ctx, &hat))
+
+	checkout := func(items ...types.CartItem) *httptest.ResponseRecorder {
+		body, err := json.Marshal(types.CartCheckoutPayload{Items: items})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("checkout", func(t *testing.T) {
+		rr := checkout(types.CartItem{ProductID: hat.ID, Quantity: 2})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var res map[string]any
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+		assert.Equal(t, 40.0, res["total"])
+
+		stored, err := products.GetByID(ctx, hat.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, stored.Quantity)
+		assert.Equal(t, hat.Version+1, stored.Version, "the stock update bumps the version")
+		all, err := orders.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+		assert.Equal(t, buyer.ID, all[0].UserID)
+
+		events, err := messages.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, types.TopicOrderCreated, events[0].Topic)
+		var event types.OrderCreatedEvent
+		assert.NoError(t, json.Unmarshal(events[0].Payload, &event))
+		assert.Equal(t, all[0].ID, event.OrderID)
+		assert.Equal(t, 40.0, event.Total)
+
+		recorded, err := activities.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		if assert.Len(t, recorded, 1) {
+			assert.Equal(t, buyer.ID, recorded[0].UserID)
+			assert.Equal(t, types.ActivityCheckout, recorded[0].Action)
+			assert.Equal(t, all[0].ID.String(), recorded[0].Details)
+		}
+	})
+
+	t.Run("checkout out of stock", func(t *testing.T) {
+		rr := checkout(types.CartItem{ProductID: hat.ID, Quantity: 2})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("checkout unknown product", func(t *testing.T) {
+		rr := checkout(types.CartItem{ProductID: uuid.New(), Quantity: 1})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("checkout invalid quantity", func(t *testing.T) {
+		rr := checkout(types.CartItem{ProductID: hat.ID, Quantity: 0})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("orders per status", func(t *testing.T) {
+		counts, err := orderRepository.CountOrdersByStatus(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pending": 1}, counts)
+	})
+}
@@ -1,33 +1,243 @@
 package product
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	httputil "github.com/zechao158/ecomm/http"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/storage"
 	"github.com/zechao158/ecomm/types"
 )
 
+// filterFields are the product fields that can be used to filter and sort the listing.
+var filterFields = storage.AllowedFields{
+	"id":          storage.UUIDField,
+	"name":        storage.StringField,
+	"description": storage.StringField,
+	"price":       storage.FloatField,
+	"quantity":    storage.IntField,
+	"created_at":  storage.TimeField,
+}
+
+func init() {
+	httputil.RegisterConstraint("products_tenant_id_image_key", "image", "image is used by another product")
+}
+
 type Handler struct {
-	store types.ProductRepository
+	store     types.ProductRepository
+	userStore types.UserRepository
 }
 
-func NewHandler(store types.ProductRepository) *Handler {
+func NewHandler(store types.ProductRepository, userStore types.UserRepository) *Handler {
 	return &Handler{
-		store: store,
+		store:     store,
+		userStore: userStore,
 	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("", h.handlerlistProducts).Methods("GET")
+	router.Handle("/deleted", h.admin(h.handleListDeletedProducts)).Methods("GET")
+	router.HandleFunc("/{id}", h.handleGetProduct).Methods("GET")
+	router.Handle("/{id}", h.admin(h.handleUpdateProduct)).Methods("PUT")
+	router.Handle("/{id}", h.admin(h.handleDeleteProduct)).Methods("DELETE")
+	router.Handle("/{id}/restore", h.admin(h.handleRestoreProduct)).Methods("POST")
+}
+
+// admin restricts the handler to the authenticated admins.
+func (h *Handler) admin(fn http.HandlerFunc) http.Handler {
+	return auth.AuthMiddleware(h.userStore)(auth.AdminMiddleware(fn))
 }
 
 func (h *Handler) handlerlistProducts(w http.ResponseWriter, r *http.Request) {
-	ps, err := h.store.GetAll(r.Context(), nil)
+	h.listProducts(w, r, nil)
+}
+
+// handleListDeletedProducts lists the soft deleted products, so they can be restored.
+func (h *Handler) handleListDeletedProducts(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(storage.IncludeDeleted(r.Context()))
+	h.listProducts(w, r, storage.Ne(storage.DeletedAtColumn, nil))
+}
+
+// listProducts writes a page of the products matching the request filters and the scope predicate.
+func (h *Handler) listProducts(w http.ResponseWriter, r *http.Request, scope storage.Predicate) {
+	pageReq, err := httputil.ParsePageRequest(r)
 	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := filterFields[pageReq.SortKey]; pageReq.SortKey != "" && !ok {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("%w: %s", storage.ErrInvalidSortKey, pageReq.SortKey))
+		return
+	}
+	filter, err := httputil.ParseFilter(r, filterFields)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	ps, err := h.store.GetPage(r.Context(), pageReq, storage.And(scope, filter))
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) || errors.Is(err, storage.ErrInvalidSortKey) {
+			httputil.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
 		httputil.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	httputil.WriteJSON(w, http.StatusOK, ps)
 }
+
+func (h *Handler) handleGetProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid product id"))
+		return
+	}
+	p, err := h.store.GetByID(r.Context(), id, false)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("ETag", httputil.ETag(p.Version))
+	httputil.WriteJSON(w, http.StatusOK, p)
+}
+
+// handleUpdateProduct replaces a product using optimistic locking, the expected version is taken from the
+// If-Match header or from the payload. An outdated version is rejected with 412 when it comes from If-Match
+// and with 409 when it comes from the payload.
+func (h *Handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid product id"))
+		return
+	}
+	var payload types.UpdateProductPayload
+	if err := httputil.ParseJSON(r, &payload); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := httputil.Validate.Struct(payload); err != nil {
+		validationErr := err.(validator.ValidationErrors)
+		httputil.WriteError(w, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	version, ifMatch, err := httputil.IfMatchVersion(r)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	staleStatus := http.StatusPreconditionFailed
+	if !ifMatch {
+		if payload.Version == 0 {
+			httputil.WriteError(w, http.StatusPreconditionRequired, fmt.Errorf("missing If-Match header or version"))
+			return
+		}
+		version = payload.Version
+		staleStatus = http.StatusConflict
+	}
+
+	p, err := h.store.GetByID(r.Context(), id, false)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if p.Version != version {
+		httputil.WriteError(w, staleStatus, storage.ErrStaleObject)
+		return
+	}
+
+	p.Name = payload.Name
+	p.Description = payload.Description
+	p.Image = payload.Image
+	p.Price = payload.Price
+	p.Quantity = payload.Quantity
+	if err := h.store.Update(r.Context(), p); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrStaleObject):
+			httputil.WriteError(w, staleStatus, err)
+		case errors.Is(err, storage.ErrRecordNotFound):
+			httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		default:
+			httputil.WriteStorageError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", httputil.ETag(p.Version))
+	httputil.WriteJSON(w, http.StatusOK, p)
+}
+
+// handleDeleteProduct soft deletes a product, it is permanently removed when the purge query parameter is true.
+func (h *Handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid product id"))
+		return
+	}
+	purge := r.URL.Query().Get("purge") == "true"
+	ctx := r.Context()
+	if purge {
+		ctx = storage.IncludeDeleted(ctx)
+	}
+	p, err := h.store.GetByID(ctx, id, false)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if purge {
+		err = h.store.Purge(r.Context(), p)
+	} else {
+		err = h.store.Delete(r.Context(), p)
+	}
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRestoreProduct restores a soft deleted product.
+func (h *Handler) handleRestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid product id"))
+		return
+	}
+	if err := h.store.Restore(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("deleted product not found"))
+			return
+		}
+		httputil.WriteStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	p, err := h.store.GetByID(r.Context(), id, false)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("ETag", httputil.ETag(p.Version))
+	httputil.WriteJSON(w, http.StatusOK, p)
+}
@@ -0,0 +1,169 @@
+package product_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zechao158/ecomm/config"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/service/product"
+	"github.com/zechao158/ecomm/service/user"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+)
+
+func TestProductServiceHandlers(t *testing.T) {
+	ctx := context.Background()
+	store := product.NewRepositoryFromStore(storage.NewMemory[types.Product]())
+	userStore := user.NewRepositoryFromStore(storage.NewMemory[types.User]())
+	router := mux.NewRouter()
+	product.NewHandler(store, userStore).RegisterRoutes(router.PathPrefix("/products").Subrouter())
+
+	admin := types.User{ID: uuid.New(), Email: "admin@test.com", IsAdmin: true}
+	assert.NoError(t, userStore.Create(ctx, &admin))
+	token, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), admin.ID)
+	assert.NoError(t, err)
+	customer := types.User{ID: uuid.New(), Email: "customer@test.com"}
+	assert.NoError(t, userStore.Create(ctx, &customer))
+	customerToken, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), customer.ID)
+	assert.NoError(t, err)
+
+	products := []types.Product{
+		{ID: uuid.New(), Name: "hat", Price: 20, Quantity: 1},
+		{ID: uuid.New(), Name: "shoe", Price: 50, Quantity: 1},
+		{ID: uuid.New(), Name: "shirt", Price: 30, Quantity: 0},
+	}
+	_, err = store.CreateMany(ctx, products, 0)
+	assert.NoError(t, err)
+
+	do := func(method, path string, payload any, header http.Header) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		if payload != nil {
+			assert.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req := httptest.NewRequest(method, path, &body)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("total in stock", func(t *testing.T) {
+		total, err := store.GetTotalInStock(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("list products", func(t *testing.T) {
+		rr := do(http.MethodGet, "/products?sort=price&order=desc&limit=2", nil, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var page storage.Page[types.Product]
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, "shoe", page.Items[0].Name)
+		assert.Equal(t, "shirt", page.Items[1].Name)
+		assert.NotEmpty(t, page.NextCursor)
+
+		rr = do(http.MethodGet, "/products?sort=price&order=desc&limit=2&cursor="+page.NextCursor, nil, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		page = storage.Page[types.Product]{}
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "hat", page.Items[0].Name)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("list products with filter", func(t *testing.T) {
+		rr := do(http.MethodGet, "/products?quantity[gt]=0&name[like]=s%25", nil, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var page storage.Page[types.Product]
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "shoe", page.Items[0].Name)
+	})
+
+	t.Run("list products invalid filter", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/products?version=1", nil, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/products?sort=image", nil, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/products?cursor=invalid", nil, nil).Code)
+	})
+
+	t.Run("get product", func(t *testing.T) {
+		rr := do(http.MethodGet, "/products/"+products[0].ID.String(), nil, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+		rr = do(http.MethodGet, "/products/"+uuid.NewString(), nil, nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("update product", func(t *testing.T) {
+		path := "/products/" + products[1].ID.String()
+		payload := types.UpdateProductPayload{Name: "boot", Description: "boot", Image: "boot.png", Price: 60, Quantity: 2}
+		auth := http.Header{"Authorization": {"Bearer " + token}}
+
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPut, path, payload, nil).Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPut, path, payload, http.Header{"Authorization": {"Bearer " + customerToken}}).Code)
+		assert.Equal(t, http.StatusPreconditionRequired, do(http.MethodPut, path, payload, auth).Code)
+
+		withIfMatch := http.Header{"Authorization": auth["Authorization"], "If-Match": {`"1"`}}
+		rr := do(http.MethodPut, path, payload, withIfMatch)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+		rr = do(http.MethodPut, path, payload, withIfMatch)
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+		payload.Version = 1
+		rr = do(http.MethodPut, path, payload, auth)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		stored, err := store.GetByID(ctx, products[1].ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "boot", stored.Name)
+		assert.Equal(t, 2, stored.Version)
+	})
+
+	t.Run("delete and restore product", func(t *testing.T) {
+		path := "/products/" + products[0].ID.String()
+		auth := http.Header{"Authorization": {"Bearer " + token}}
+
+		assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, path, nil, http.Header{"Authorization": {"Bearer " + customerToken}}).Code)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, path, nil, auth).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, path, nil, nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, path, nil, auth).Code)
+
+		rr := do(http.MethodGet, "/products/deleted", nil, auth)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var page storage.Page[types.Product]
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, products[0].ID, page.Items[0].ID)
+
+		rr = do(http.MethodPost, path+"/restore", nil, auth)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, path+"/restore", nil, auth).Code)
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, path, nil, nil).Code)
+	})
+
+	t.Run("purge product", func(t *testing.T) {
+		path := "/products/" + products[2].ID.String()
+		auth := http.Header{"Authorization": {"Bearer " + token}}
+
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, path, nil, auth).Code)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, path+"?purge=true", nil, auth).Code)
+		_, err := store.GetByID(storage.IncludeDeleted(ctx), products[2].ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, path+"/restore", nil, auth).Code)
+	})
+}
@@ -16,8 +16,13 @@ type repository struct {
 }
 
 func NewRepository(db *gorm.DB) types.ProductRepository {
+	return NewRepositoryFromStore(storage.New[types.Product](db))
+}
+
+// NewRepositoryFromStore creates the repository on top of the given store, such as storage.NewMemory in tests.
+func NewRepositoryFromStore(store storage.CRUDStorer[types.Product]) types.ProductRepository {
 	return &repository{
-		storage.New[types.Product](db),
+		store,
 	}
 }
 
@@ -29,12 +34,18 @@ func (s *repository) GetProducts(ctx context.Context) ([]types.Product, error) {
 	return res, nil
 }
 
-func (s *repository) GetProductsByIDs(ctx context.Context, ids []uuid.UUID) ([]types.Product, error) {
-	res, err := s.GetAll(ctx, func(db *gorm.DB) *gorm.DB {
-		return db.Where("id in (?)", ids)
-	})
+func (s *repository) GetProductsByIDs(ctx context.Context, ids []uuid.UUID, forUpdate bool) ([]types.Product, error) {
+	res, err := s.Find(ctx, storage.Query{Where: storage.In("id", ids), ForUpdate: forUpdate})
 	if err != nil {
 		return nil, fmt.Errorf("error getting all products %w", err)
 	}
 	return res, nil
 }
+
+func (s *repository) GetTotalInStock(ctx context.Context) (int64, error) {
+	total, err := s.Sum(ctx, "quantity", storage.Gt("quantity", 0))
+	if err != nil {
+		return 0, fmt.Errorf("error getting the total in stock %w", err)
+	}
+	return int64(total), nil
+}
@@ -0,0 +1,48 @@
+package user
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+	"gorm.io/gorm"
+)
+
+type activityRepository struct {
+	storage.CRUDStorer[types.UserActivity]
+}
+
+func NewActivityRepository(db *gorm.DB) types.UserActivityRepository {
+	return NewActivityRepositoryFromStore(storage.New[types.UserActivity](db))
+}
+
+// NewActivityRepositoryFromStore creates the user activity repository on store.
+func NewActivityRepositoryFromStore(store storage.CRUDStorer[types.UserActivity]) types.UserActivityRepository {
+	return &activityRepository{
+		store,
+	}
+}
+
+func (s *activityRepository) GetUserActivity(ctx context.Context, userID uuid.UUID, req storage.PageRequest, filter storage.Predicate) (*storage.Page[types.UserActivity], error) {
+	return s.CRUDStorer.GetPage(ctx, req, storage.And(storage.Eq("user_id", userID), filter))
+}
+
+// NewActivity returns the activity of the user for the action made with the request, with the client address and
+// user agent of the request.
+func NewActivity(r *http.Request, userID uuid.UUID, action, details string) *types.UserActivity {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return &types.UserActivity{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Action:    action,
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+		Details:   details,
+	}
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,23 +12,47 @@ import (
 	"github.com/zechao158/ecomm/config"
 	httputil "github.com/zechao158/ecomm/http"
 	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/session"
 	"github.com/zechao158/ecomm/storage"
 	"github.com/zechao158/ecomm/types"
 )
 
+// activityFields are the activity fields that can be used to filter and sort the history, the time range is
+// given with created_at[gte] and created_at[lt].
+var activityFields = storage.AllowedFields{
+	"action":     storage.StringField,
+	"ip":         storage.StringField,
+	"created_at": storage.TimeField,
+}
+
+func init() {
+	httputil.RegisterConstraint("users_tenant_id_email_key", "email", "email is already registered")
+}
+
 type Handler struct {
-	store types.UserRepository
+	// session runs the changes of the users in a transaction with the activity recording them.
+	session    session.Session
+	store      types.UserRepository
+	activities types.UserActivityRepository
+	tokens     types.RefreshTokenRepository
 }
 
-func NewHandler(store types.UserRepository) *Handler {
+func NewHandler(s session.Session, store types.UserRepository, activities types.UserActivityRepository, tokens types.RefreshTokenRepository) *Handler {
 	return &Handler{
-		store: store,
+		session:    s,
+		store:      store,
+		activities: activities,
+		tokens:     tokens,
 	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.handleLogin).Methods("POST")
 	router.HandleFunc("/register", h.handleRegister).Methods("POST")
+	router.HandleFunc("/token/refresh", h.handleRefreshToken).Methods("POST")
+	router.Handle("/me/password", auth.AuthMiddleware(h.store)(http.HandlerFunc(h.handleChangePassword))).Methods("PUT")
+	router.Handle("/me/activity", auth.AuthMiddleware(h.store)(http.HandlerFunc(h.handleListMyActivity))).Methods("GET")
+	router.Handle("/users/{id}/activity", auth.AuthMiddleware(h.store)(auth.AdminMiddleware(http.HandlerFunc(h.handleListUserActivity)))).Methods("GET")
 }
 
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -42,22 +67,74 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	storedUser, err := h.store.GetUserByEmail(r.Context(), payload.Email)
-	if err != nil && errors.Is(err, storage.ErrRecordNotFound) {
-		httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("user not found"))
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, fmt.Errorf("user not found"))
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 	if !auth.ComparePassword(storedUser.Password, payload.Password) {
+		if err := h.activities.Create(r.Context(), NewActivity(r, storedUser.ID, types.ActivityLoginFailed, "wrong password")); err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
 		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("wrong password"))
 		return
 	}
 
-	authToken, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), storedUser.ID)
+	var refreshToken string
+	err = h.session.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := h.activities.Create(ctx, NewActivity(r, storedUser.ID, types.ActivityLogin, "")); err != nil {
+			return err
+		}
+		token, err := h.issueRefreshToken(ctx, storedUser.ID, uuid.New())
+		refreshToken = token
+		return err
+	})
 	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeTokens(w, storedUser.ID, refreshToken)
+}
+
+// handleRefreshToken exchanges a refresh token for a new access token and a new refresh token, the given one
+// can't be used again.
+func (h *Handler) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var payload types.RefreshTokenPayload
+	if err := httputil.ParseJSON(r, &payload); err != nil {
 		httputil.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
+	if err := httputil.Validate.Struct(payload); err != nil {
+		validationErr := err.(validator.ValidationErrors)
+		httputil.WriteError(w, http.StatusBadRequest, validationErr)
+		return
+	}
+	user, refreshToken, err := h.rotateRefreshToken(r.Context(), payload.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+			httputil.WriteError(w, http.StatusUnauthorized, err)
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeTokens(w, user.ID, refreshToken)
+}
+
+// writeTokens writes a new access token of the user along with the refresh token.
+func writeTokens(w http.ResponseWriter, userID uuid.UUID, refreshToken string) {
+	authToken, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), userID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 	httputil.WriteJSON(w, http.StatusOK, map[string]string{
-		"token": authToken,
+		"token":        authToken,
+		"refreshToken": refreshToken,
 	})
 }
 
@@ -86,10 +163,15 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Email:     payload.Email,
 		Password:  hashedPass,
 	}
-	err = h.store.Create(r.Context(), &user)
+	err = h.session.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := h.store.Create(ctx, &user); err != nil {
+			return err
+		}
+		return h.activities.Create(ctx, NewActivity(r, user.ID, types.ActivityRegister, ""))
+	})
 	if err != nil {
 		if errors.Is(err, storage.ErrDuplicateKey) {
-			httputil.WriteError(w, http.StatusConflict, err)
+			httputil.WriteStorageError(w, http.StatusConflict, err)
 			return
 		}
 		httputil.WriteError(w, http.StatusInternalServerError, err)
@@ -104,3 +186,108 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	})
 
 }
+
+// handleChangePassword replaces the password of the authenticated user, the current password is required. The
+// refresh tokens of the user are revoked along with it.
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, fmt.Errorf("user not found"))
+		return
+	}
+	var payload types.ChangePasswordPayload
+	if err := httputil.ParseJSON(r, &payload); err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := httputil.Validate.Struct(payload); err != nil {
+		validationErr := err.(validator.ValidationErrors)
+		httputil.WriteError(w, http.StatusBadRequest, validationErr)
+		return
+	}
+	if !auth.ComparePassword(user.Password, payload.CurrentPassword) {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("wrong password"))
+		return
+	}
+
+	hashedPass, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = h.session.Transaction(r.Context(), func(ctx context.Context) error {
+		stored, err := h.store.GetByID(ctx, user.ID, true)
+		if err != nil {
+			return err
+		}
+		stored.Password = hashedPass
+		if err := h.store.Update(ctx, stored); err != nil {
+			return err
+		}
+		// the sessions opened with the old password must not outlive it
+		if err := h.tokens.RevokeUser(ctx, user.ID); err != nil {
+			return err
+		}
+		return h.activities.Create(ctx, NewActivity(r, user.ID, types.ActivityPasswordChange, ""))
+	})
+	if err != nil {
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListMyActivity lists the activity of the authenticated user.
+func (h *Handler) handleListMyActivity(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, fmt.Errorf("user not found"))
+		return
+	}
+	h.listActivity(w, r, user.ID)
+}
+
+// handleListUserActivity lists the activity of any user, for the admins.
+func (h *Handler) handleListUserActivity(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid user id"))
+		return
+	}
+	h.listActivity(w, r, id)
+}
+
+// listActivity writes a page of the activity of the user matching the request filters, the most recent first
+// unless another order is requested.
+func (h *Handler) listActivity(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	pageReq, err := httputil.ParsePageRequest(r)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if pageReq.SortKey == "" {
+		pageReq.SortKey = "created_at"
+		pageReq.Desc = r.URL.Query().Get("order") != "asc"
+	}
+	if _, ok := activityFields[pageReq.SortKey]; !ok {
+		httputil.WriteError(w, http.StatusBadRequest, fmt.Errorf("%w: %s", storage.ErrInvalidSortKey, pageReq.SortKey))
+		return
+	}
+	filter, err := httputil.ParseFilter(r, activityFields)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	ps, err := h.activities.GetUserActivity(r.Context(), userID, pageReq, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) || errors.Is(err, storage.ErrInvalidSortKey) {
+			httputil.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		httputil.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, ps)
+}
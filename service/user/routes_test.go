@@ -1,9 +1,219 @@
 package user_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zechao158/ecomm/config"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/service/user"
+	"github.com/zechao158/ecomm/session/sessiontest"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
 )
 
 func TestUserServiceHandlers(t *testing.T) {
+	store := user.NewRepositoryFromStore(storage.NewMemory[types.User]("email"))
+	activities := user.NewActivityRepositoryFromStore(storage.NewMemory[types.UserActivity]())
+	tokens := user.NewRefreshTokenRepositoryFromStore(storage.NewMemory[types.RefreshToken]())
+	router := mux.NewRouter()
+	user.NewHandler(sessiontest.Memory{}, store, activities, tokens).RegisterRoutes(router)
+
+	send := func(method, path, token string, payload any) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		if payload != nil {
+			assert.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req := httptest.NewRequest(method, path, &body)
+		req.Header.Set("User-Agent", "test-agent")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	do := func(path string, payload any) *httptest.ResponseRecorder {
+		return send(http.MethodPost, path, "", payload)
+	}
+	listActivity := func(path, token string) (*httptest.ResponseRecorder, storage.Page[types.UserActivity]) {
+		rr := send(http.MethodGet, path, token, nil)
+		var page storage.Page[types.UserActivity]
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		}
+		return rr, page
+	}
+	actions := func(page storage.Page[types.UserActivity]) []string {
+		var res []string
+		for _, a := range page.Items {
+			res = append(res, a.Action)
+		}
+		return res
+	}
+
+	register := types.RegisterUserPayload{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@test.com",
+		Password:  "secret",
+	}
+
+	t.Run("register", func(t *testing.T) {
+		rr := do("/register", register)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		stored, err := store.GetUserByEmail(context.Background(), register.Email)
+		assert.NoError(t, err)
+		assert.NotEqual(t, register.Password, stored.Password)
+	})
+
+	t.Run("register invalid payload", func(t *testing.T) {
+		rr := do("/register", types.RegisterUserPayload{Email: "invalid"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("register already exist", func(t *testing.T) {
+		rr := do("/register", register)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	var loggedIn map[string]string
+	t.Run("login", func(t *testing.T) {
+		rr := do("/login", types.LoginUserPayload{Email: register.Email, Password: register.Password})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&loggedIn))
+		assert.NotEmpty(t, loggedIn["token"])
+		assert.NotEmpty(t, loggedIn["refreshToken"])
+	})
+
+	t.Run("login wrong password", func(t *testing.T) {
+		rr := do("/login", types.LoginUserPayload{Email: register.Email, Password: "wrong"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("login unknown user", func(t *testing.T) {
+		rr := do("/login", types.LoginUserPayload{Email: "unknown@test.com", Password: "secret"})
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	stored, err := store.GetUserByEmail(context.Background(), register.Email)
+	require.NoError(t, err)
+	token, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), stored.ID)
+	require.NoError(t, err)
+
+	t.Run("change password", func(t *testing.T) {
+		rr := send(http.MethodPut, "/me/password", token, types.ChangePasswordPayload{CurrentPassword: "wrong", NewPassword: "changed"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = send(http.MethodPut, "/me/password", "", types.ChangePasswordPayload{CurrentPassword: register.Password, NewPassword: "changed"})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = send(http.MethodPut, "/me/password", token, types.ChangePasswordPayload{CurrentPassword: register.Password, NewPassword: "changed"})
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, http.StatusBadRequest, do("/login", types.LoginUserPayload{Email: register.Email, Password: register.Password}).Code)
+		assert.Equal(t, http.StatusOK, do("/login", types.LoginUserPayload{Email: register.Email, Password: "changed"}).Code)
+		// the refresh tokens issued before the change are revoked
+		rr = do("/token/refresh", types.RefreshTokenPayload{RefreshToken: loggedIn["refreshToken"]})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("my activity", func(t *testing.T) {
+		rr, page := listActivity("/me/activity", token)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{
+			types.ActivityLogin, types.ActivityLoginFailed, types.ActivityPasswordChange,
+			types.ActivityLoginFailed, types.ActivityLogin, types.ActivityRegister,
+		}, actions(page))
+		assert.Equal(t, "test-agent", page.Items[0].UserAgent)
+		assert.NotEmpty(t, page.Items[0].IP)
+
+		rr, page = listActivity("/me/activity?action=login_failed&limit=1", token)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{types.ActivityLoginFailed}, actions(page))
+		assert.NotEmpty(t, page.NextCursor)
+
+		rr, _ = listActivity("/me/activity?sort=user_agent", token)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr, _ = listActivity("/me/activity", "")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("user activity for the admins", func(t *testing.T) {
+		path := "/users/" + stored.ID.String() + "/activity"
+		rr, _ := listActivity(path, token)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		admin := types.User{ID: uuid.New(), Email: "admin@test.com", IsAdmin: true}
+		require.NoError(t, store.Create(context.Background(), &admin))
+		adminToken, err := auth.CreateJWT([]byte(config.ENVs.JWTSecret), admin.ID)
+		require.NoError(t, err)
+		rr, page := listActivity(path+"?order=asc", adminToken)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, types.ActivityRegister, page.Items[0].Action)
+		assert.Len(t, page.Items, 6)
+
+		rr, page = listActivity(path+"?action=register", adminToken)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, page.Items, 1)
+		rr, _ = listActivity("/users/invalid/activity", adminToken)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("refresh token", func(t *testing.T) {
+		login := func() map[string]string {
+			rr := do("/login", types.LoginUserPayload{Email: register.Email, Password: "changed"})
+			require.Equal(t, http.StatusOK, rr.Code)
+			var res map[string]string
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+			return res
+		}
+		refresh := func(token string) (*httptest.ResponseRecorder, map[string]string) {
+			rr := do("/token/refresh", types.RefreshTokenPayload{RefreshToken: token})
+			var res map[string]string
+			if rr.Code == http.StatusOK {
+				assert.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+			}
+			return rr, res
+		}
+
+		first := login()
+		rr, second := refresh(first["refreshToken"])
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEmpty(t, second["token"])
+		assert.NotEqual(t, first["refreshToken"], second["refreshToken"])
+		rr, _ = listActivity("/me/activity", second["token"])
+		assert.Equal(t, http.StatusOK, rr.Code, "the refreshed access token is valid")
+		rr, third := refresh(second["refreshToken"])
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		// another login is a family of its own
+		other := login()
+
+		// replaying a used token revokes its family
+		rr, _ = refresh(first["refreshToken"])
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Body.String(), auth.ErrRefreshTokenReused.Error())
+		rr, _ = refresh(third["refreshToken"])
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "the latest token of the family is revoked")
+		rr, _ = refresh(other["refreshToken"])
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		all, err := tokens.GetAll(context.Background(), nil)
+		require.NoError(t, err)
+		for _, tok := range all {
+			assert.NotEqual(t, first["refreshToken"], tok.TokenHash, "only the hash is stored")
+		}
 
+		rr, _ = refresh("unknown")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		rr, _ = refresh("")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
@@ -14,8 +14,13 @@ type repository struct {
 }
 
 func NewRepository(db *gorm.DB) types.UserRepository {
+	return NewRepositoryFromStore(storage.New[types.User](db))
+}
+
+// NewRepositoryFromStore creates the user repository on store.
+func NewRepositoryFromStore(store storage.CRUDStorer[types.User]) types.UserRepository {
 	return &repository{
-		storage.New[types.User](db),
+		store,
 	}
 }
 
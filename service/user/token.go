@@ -0,0 +1,120 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zechao158/ecomm/service/auth"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+	"gorm.io/gorm"
+)
+
+type refreshTokenRepository struct {
+	storage.CRUDStorer[types.RefreshToken]
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) types.RefreshTokenRepository {
+	return NewRefreshTokenRepositoryFromStore(storage.New[types.RefreshToken](db))
+}
+
+// NewRefreshTokenRepositoryFromStore creates the refresh token repository on store.
+func NewRefreshTokenRepositoryFromStore(store storage.CRUDStorer[types.RefreshToken]) types.RefreshTokenRepository {
+	return &refreshTokenRepository{
+		store,
+	}
+}
+
+func (s *refreshTokenRepository) GetByHash(ctx context.Context, hash string, forUpdate bool) (*types.RefreshToken, error) {
+	return s.CRUDStorer.GetByFields(ctx, map[string]string{
+		"token_hash": hash,
+	}, forUpdate)
+}
+
+func (s *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := s.CRUDStorer.UpdateWhere(ctx, storage.And(
+		storage.Eq("family_id", familyID),
+		storage.Eq("revoked_at", nil),
+	), map[string]any{"revoked_at": time.Now()})
+	return err
+}
+
+func (s *refreshTokenRepository) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.CRUDStorer.UpdateWhere(ctx, storage.And(
+		storage.Eq("user_id", userID),
+		storage.Eq("revoked_at", nil),
+	), map[string]any{"revoked_at": time.Now()})
+	return err
+}
+
+// issueRefreshToken creates a refresh token of the family for the user and returns it, a login starts a new family.
+func (h *Handler) issueRefreshToken(ctx context.Context, userID, familyID uuid.UUID) (string, error) {
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	err = h.tokens.Create(ctx, &types.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: auth.RefreshTokenExpiration(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// rotateRefreshToken replaces the refresh token with a new one of the same family and returns its user with the
+// new token. A token already replaced or revoked revokes its whole family and returns auth.ErrRefreshTokenReused.
+func (h *Handler) rotateRefreshToken(ctx context.Context, token string) (*types.User, string, error) {
+	var (
+		user    *types.User
+		renewed string
+		reused  bool
+	)
+	err := h.session.Transaction(ctx, func(ctx context.Context) error {
+		reused = false
+		// lock the token so concurrent uses can't both replace it
+		stored, err := h.tokens.GetByHash(ctx, auth.HashRefreshToken(token), true)
+		if err != nil {
+			if errors.Is(err, storage.ErrRecordNotFound) {
+				return auth.ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if stored.UsedAt != nil || stored.RevokedAt != nil {
+			// the revocation is committed, the error is only returned once the transaction is over
+			reused = true
+			return h.tokens.RevokeFamily(ctx, stored.FamilyID)
+		}
+		if time.Now().After(stored.ExpiresAt) {
+			return auth.ErrInvalidRefreshToken
+		}
+		user, err = h.store.GetByID(ctx, stored.UserID, false)
+		if err != nil {
+			if errors.Is(err, storage.ErrRecordNotFound) {
+				return auth.ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		now := time.Now()
+		stored.UsedAt = &now
+		if err := h.tokens.Update(ctx, stored); err != nil {
+			return err
+		}
+		renewed, err = h.issueRefreshToken(ctx, stored.UserID, stored.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if reused {
+		return nil, "", auth.ErrRefreshTokenReused
+	}
+	return user, renewed, nil
+}
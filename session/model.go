@@ -14,11 +14,3 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
-
-type History struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	UserID    uuid.UUID
-	Action    string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-}
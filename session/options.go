@@ -0,0 +1,84 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrIsolationLevel is returned when a nested transaction asks for a stronger isolation level than the one of
+// the transaction it is nested in, which can't be changed once it started.
+var ErrIsolationLevel = errors.New("isolation level stronger than the enclosing transaction")
+
+// TxOptions are the options of a single transaction, given to Begin or Transaction. The zero value keeps the
+// options of the session.
+type TxOptions struct {
+	// Isolation is the isolation level of the transaction, sql.LevelDefault keeps the one of the session.
+	// A nested transaction runs with the isolation level of the enclosing one, so it can only ask for the
+	// same level or a weaker one.
+	Isolation sql.IsolationLevel
+	// ReadOnly starts a read-only transaction. A nested transaction has the access mode of the enclosing one.
+	ReadOnly bool
+	// Deadline is the time after which the transaction is rolled back, including its retries. The zero time
+	// sets no deadline.
+	Deadline time.Time
+}
+
+// txOptions returns the options of a transaction started with opts, the ones of the session apply when they
+// are not given.
+func (s Gorm) txOptions(opts []TxOptions) TxOptions {
+	var o TxOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if s.TxOptions != nil {
+		if o.Isolation == sql.LevelDefault {
+			o.Isolation = s.TxOptions.Isolation
+		}
+		o.ReadOnly = o.ReadOnly || s.TxOptions.ReadOnly
+	}
+	return o
+}
+
+// sqlTxOptions returns the options of database/sql, nil for the default ones.
+func (o TxOptions) sqlTxOptions() *sql.TxOptions {
+	if o.Isolation == sql.LevelDefault && !o.ReadOnly {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly}
+}
+
+// withDeadline returns ctx with the deadline of the options.
+func (o TxOptions) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.Deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, o.Deadline)
+}
+
+// checkNested returns ErrIsolationLevel when a transaction nested in one of the parent isolation level asks
+// for a stronger one. sql.LevelDefault is read committed, the default of postgres.
+func checkNested(parent, nested sql.IsolationLevel) error {
+	if nested == sql.LevelDefault || isolationStrength(nested) <= isolationStrength(parent) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s requested in a %s transaction", ErrIsolationLevel, nested, isolationName(parent))
+}
+
+// isolationStrength orders the isolation levels, the levels of database/sql are declared from the weakest to
+// the strongest.
+func isolationStrength(level sql.IsolationLevel) sql.IsolationLevel {
+	if level == sql.LevelDefault {
+		return sql.LevelReadCommitted
+	}
+	return level
+}
+
+func isolationName(level sql.IsolationLevel) string {
+	if level == sql.LevelDefault {
+		return "Default (Read Committed)"
+	}
+	return level.String()
+}
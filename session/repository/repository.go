@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/zechao158/ecomm/session"
+	"github.com/zechao158/ecomm/types"
 	"gorm.io/gorm"
 )
 
@@ -22,7 +23,7 @@ func (r *User) Create(ctx context.Context, user *session.User) error {
 	return db.Error
 }
 
-func (r *User) CreateHistory(ctx context.Context, history *session.History) error {
-	db := session.DB(ctx, r.DB).Create(&history)
+func (r *User) CreateActivity(ctx context.Context, activity *types.UserActivity) error {
+	db := session.DB(ctx, r.DB).Create(&activity)
 	return db.Error
 }
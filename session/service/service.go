@@ -3,12 +3,15 @@ package user
 import (
 	"context"
 
+	"github.com/google/uuid"
+
 	"github.com/zechao158/ecomm/session"
+	"github.com/zechao158/ecomm/types"
 )
 
 type Repository interface {
 	Create(ctx context.Context, user *session.User) error
-	CreateHistory(ctx context.Context, history *session.History) error
+	CreateActivity(ctx context.Context, activity *types.UserActivity) error
 }
 
 type Service struct {
@@ -23,7 +26,7 @@ func NewService(session session.Session, repository Repository) *Service {
 	}
 }
 
-// Register create a new user with an associated "register" history.
+// Register create a new user with an associated "register" activity.
 func (s *Service) Register(ctx context.Context, user *session.User) (*session.User, error) {
 
 	err := s.session.Transaction(ctx, func(ctx context.Context) error {
@@ -34,15 +37,16 @@ func (s *Service) Register(ctx context.Context, user *session.User) (*session.Us
 			return err
 		}
 
-		history := &session.History{
+		activity := &types.UserActivity{
+			ID:     uuid.New(),
 			UserID: user.ID,
-			Action: "register",
+			Action: types.ActivityRegister,
 		}
-		err = s.repository.CreateHistory(ctx, history)
+		err = s.repository.CreateActivity(ctx, activity)
 		return err
 	})
 	if err != nil {
-		return user, nil
+		return nil, err
 	}
 
 	return user, nil
@@ -3,8 +3,13 @@ package session
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync/atomic"
 
+	"golang.org/x/exp/slog"
 	"gorm.io/gorm"
+
+	"github.com/zechao158/ecomm/storage"
 )
 
 // Session aims at facilitating business transactions while abstracting the underlying mechanism,
@@ -19,12 +24,14 @@ type Session interface {
 	// Begin returns a new session with the given context and a started transaction.
 	// Using the returned session should have no side-effect on the parent session.
 	// The underlying transaction mechanism is injected as a value into the new session's context.
-	Begin(ctx context.Context) (Session, error)
+	// The options, if given, override the ones of the session for this transaction.
+	Begin(ctx context.Context, opts ...TxOptions) (Session, error)
 
 	// Transaction executes a transaction. If the given function returns an error, the transaction
 	// is rolled back. Otherwise it is automatically committed before `Transaction()` returns.
 	// The underlying transaction mechanism is injected into the context as a value.
-	Transaction(ctx context.Context, f func(context.Context) error) error
+	// The options, if given, override the ones of the session for this transaction.
+	Transaction(ctx context.Context, f func(context.Context) error, opts ...TxOptions) error
 
 	// Rollback the changes in the transaction. This action is final.
 	Rollback() error
@@ -36,21 +43,55 @@ type Session interface {
 	// If it's a child session started with `Begin()`, then the context will contain the associated
 	// transaction mechanism as a value.
 	Context() context.Context
+
+	// OnCommit registers f to run once the changes of the session are committed. The callbacks of a nested
+	// session only run once the outermost transaction committed. The errors of f are reported but can't undo
+	// the commit. On the root session, f runs right away.
+	OnCommit(f func(context.Context) error)
+
+	// OnRollback registers f to run once the changes of the session are rolled back, by its own rollback or
+	// by the rollback of an enclosing transaction. The errors of f are reported.
+	OnRollback(f func(context.Context) error)
 }
 
 // Gorm session implementation.
+//
+// A session begun inside a transaction, because its parent session or the context already holds one, doesn't
+// start a new transaction: it creates a SAVEPOINT in the enclosing one. Rolling it back only undoes its own
+// changes, the enclosing transaction can carry on, and committing it releases the savepoint so its changes
+// are committed, or rolled back, with the enclosing transaction.
+//
+// The options of a transaction default to the TxOptions of the session, they can be overridden for a single
+// transaction by passing TxOptions to Begin or Transaction.
 type Gorm struct {
-	db        *gorm.DB
+	db *gorm.DB
+	// TxOptions are the default options of the transactions, nil uses the ones of the database.
 	TxOptions *sql.TxOptions
-	ctx       context.Context
+	// Retry is the policy used by Transaction to run again the transactions failing with a serialization
+	// failure or a deadlock.
+	Retry storage.RetryPolicy
+	ctx   context.Context
+	// savepoint is the savepoint of a session nested in a transaction, it is empty for the other sessions.
+	savepoint string
+	// isolation is the isolation level of the transaction of the session.
+	isolation sql.IsolationLevel
+	// cancel releases the deadline of the transaction, it is nil without deadline.
+	cancel context.CancelFunc
 }
 
+// isolationKey is the key of the isolation level of the transaction carried by a context.
+type isolationKey struct{}
+
+// savepoints numbers the savepoints so nested sessions never share one.
+var savepoints atomic.Uint64
+
 // GORM create a new root session for Gorm.
-// The transaction options are optional.
+// The transaction options are optional. The transactions are retried with storage.DefaultRetryPolicy.
 func GORM(db *gorm.DB, opt *sql.TxOptions) Gorm {
 	return Gorm{
 		db:        db,
 		TxOptions: opt,
+		Retry:     storage.DefaultRetryPolicy,
 		ctx:       context.Background(),
 	}
 }
@@ -60,27 +101,117 @@ func GORM(db *gorm.DB, opt *sql.TxOptions) Gorm {
 // is executed before the session is expired (eligible for garbage collection).
 // The Gorm DB associated with this session is injected as a value into the new session's context.
 // If a Gorm DB is found in the given context, it will be used instead of this Session's DB, allowing for
-// nested transactions.
-func (s Gorm) Begin(ctx context.Context) (Session, error) {
-	tx := DB(ctx, s.db).WithContext(ctx).Begin(s.TxOptions)
+// nested transactions: when that DB, or this Session's one, is a transaction, a savepoint is created in it
+// instead of a new transaction. The savepoint keeps the isolation level and the access mode of the enclosing
+// transaction, ErrIsolationLevel is returned when the options ask for a stronger isolation level.
+// A new transaction gets the statement timeout of the context, see storage.ApplyStatementTimeout.
+func (s Gorm) Begin(ctx context.Context, opts ...TxOptions) (Session, error) {
+	parentIsolation := s.isolation
+	if _, ok := storage.TxFromContext(ctx); ok {
+		parentIsolation, _ = ctx.Value(isolationKey{}).(sql.IsolationLevel)
+	} else {
+		// a child session nests in the transaction of its parent
+		ctx = storage.WithTxFrom(ctx, s.ctx)
+	}
+	db := DB(ctx, s.db)
+	if inTransaction(db) {
+		var requested TxOptions
+		if len(opts) > 0 {
+			requested = opts[0]
+		}
+		if err := checkNested(parentIsolation, requested.Isolation); err != nil {
+			return nil, err
+		}
+		ctx, cancel := requested.withDeadline(ctx)
+		savepoint := fmt.Sprintf("sp_%d", savepoints.Add(1))
+		if err := db.WithContext(ctx).Exec("SAVEPOINT " + savepoint).Error; err != nil {
+			cancel()
+			return nil, err
+		}
+		return Gorm{
+			ctx:       storage.WithTx(context.WithValue(ctx, isolationKey{}, parentIsolation), db),
+			TxOptions: s.TxOptions,
+			Retry:     s.Retry,
+			db:        db,
+			savepoint: savepoint,
+			isolation: parentIsolation,
+			cancel:    cancel,
+		}, nil
+	}
+	o := s.txOptions(opts)
+	ctx, cancel := o.withDeadline(ctx)
+	tx := db.WithContext(ctx).Begin(o.sqlTxOptions())
 	if tx.Error != nil {
+		cancel()
 		return nil, tx.Error
 	}
+	// the stores of the transaction don't have to set the statement timeout around each of their operations
+	txCtx, err := storage.ApplyStatementTimeout(storage.WithTx(context.WithValue(ctx, isolationKey{}, o.Isolation), tx))
+	if err != nil {
+		tx.Rollback()
+		cancel()
+		return nil, err
+	}
 	return Gorm{
-		ctx:       context.WithValue(ctx, dbKey{}, tx),
+		ctx:       txCtx,
 		TxOptions: s.TxOptions,
+		Retry:     s.Retry,
 		db:        tx,
+		isolation: o.Isolation,
+		cancel:    cancel,
 	}, nil
 }
 
 // Rollback the changes in the transaction. This action is final.
+// A nested session is rolled back to its savepoint, the enclosing transaction can still be used.
+// The OnRollback callbacks of the session, and of the sessions committed in it, run afterwards.
 func (s Gorm) Rollback() error {
+	defer s.release()
+	defer storage.RolledBack(s.ctx)
+	if s.savepoint != "" {
+		if err := s.db.Exec("ROLLBACK TO SAVEPOINT " + s.savepoint).Error; err != nil {
+			return err
+		}
+		return s.db.Exec("RELEASE SAVEPOINT " + s.savepoint).Error
+	}
 	return s.db.Rollback().Error
 }
 
 // Commit the changes in the transaction. This action is final.
+// A nested session releases its savepoint, its changes are committed with the enclosing transaction which
+// inherits its callbacks. The OnCommit callbacks run once the outermost transaction committed, the OnRollback
+// ones when the commit fails.
 func (s Gorm) Commit() error {
-	return s.db.Commit().Error
+	defer s.release()
+	var err error
+	if s.savepoint != "" {
+		err = s.db.Exec("RELEASE SAVEPOINT " + s.savepoint).Error
+	} else {
+		err = s.db.Commit().Error
+	}
+	if err != nil {
+		storage.RolledBack(s.ctx)
+		return err
+	}
+	storage.Committed(s.ctx)
+	return nil
+}
+
+// release releases the deadline of the transaction.
+func (s Gorm) release() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// OnCommit registers f to run once the changes of the session are committed, see OnCommit.
+func (s Gorm) OnCommit(f func(context.Context) error) {
+	OnCommit(s.ctx, f)
+}
+
+// OnRollback registers f to run once the changes of the session are rolled back, see OnRollback.
+func (s Gorm) OnRollback(f func(context.Context) error) {
+	OnRollback(s.ctx, f)
 }
 
 // Context returns the session's context. If it's the root session, `context.Background()`
@@ -90,34 +221,84 @@ func (s Gorm) Context() context.Context {
 	return s.ctx
 }
 
-// dbKey the key used to store the database in the context.
-type dbKey struct{}
-
 // Transaction executes a transaction. If the given function returns an error, the transaction
 // is rolled back. Otherwise it is automatically committed before `Transaction()` returns.
+// Like with Begin, a transaction nested in another one is a savepoint, so its failure doesn't abort the
+// enclosing transaction.
 //
 // The Gorm DB associated with this session is injected into the context as a value so `session.DB()`
 // can be used to retrieve it.
-func (s Gorm) Transaction(ctx context.Context, f func(context.Context) error) error {
-	tx := DB(ctx, s.db).WithContext(ctx).Begin(s.TxOptions)
-	if tx.Error != nil {
-		return tx.Error
+//
+// When the transaction fails with a serialization failure or a deadlock, f is run again in a new transaction
+// following the Retry policy. A transaction nested in another one is never retried on its own, the error is
+// returned so the outermost transaction is retried instead.
+//
+// The deadline of the options bounds all the attempts.
+func (s Gorm) Transaction(ctx context.Context, f func(context.Context) error, opts ...TxOptions) error {
+	if inTransaction(DB(ctx, s.db)) {
+		return s.transaction(ctx, f, opts)
 	}
-	c := context.WithValue(ctx, dbKey{}, tx)
-	err := f(c)
+	ctx, cancel := s.txOptions(opts).withDeadline(ctx)
+	defer cancel()
+	return storage.Retry(ctx, s.Retry, func(ctx context.Context) error {
+		return s.transaction(ctx, f, opts)
+	})
+}
+
+// transaction runs f once in a transaction, or in a savepoint when nested.
+func (s Gorm) transaction(ctx context.Context, f func(context.Context) error, opts []TxOptions) error {
+	tx, err := s.Begin(ctx, opts...)
 	if err != nil {
+		return err
+	}
+	if err := f(tx.Context()); err != nil {
 		tx.Rollback()
 		return err
 	}
-	return tx.Commit().Error
+	return tx.Commit()
+}
+
+// OnCommit registers f to run once the changes made in the transaction of ctx, the context given to the function
+// of Transaction or the one of a session started with Begin, are committed by the outermost transaction. f runs
+// right away when ctx has no transaction. It gets ctx without the transaction, so it can use the repositories.
+//
+// The errors of f are logged, the commit can't be undone.
+func OnCommit(ctx context.Context, f func(context.Context) error) {
+	storage.AfterCommit(ctx, func(ctx context.Context) {
+		report("commit", f(ctx))
+	})
+}
+
+// OnRollback registers f to run once the changes made in the transaction of ctx are rolled back, by the
+// rollback of that transaction or of an enclosing one. f never runs when ctx has no transaction. It gets ctx
+// without the transaction, so it can use the repositories.
+//
+// The errors of f are logged.
+func OnRollback(ctx context.Context, f func(context.Context) error) {
+	storage.AfterRollback(ctx, func(ctx context.Context) {
+		report("rollback", f(ctx))
+	})
+}
+
+// report logs the error of a callback.
+func report(hook string, err error) {
+	if err != nil {
+		slog.Error("transaction callback failed", "hook", hook, "error", err)
+	}
+}
+
+// inTransaction reports whether db is bound to a transaction.
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
 }
 
 // DB returns the Gorm instance stored in the given context. Returns the given fallback
 // if no Gorm DB could be found in the context.
+// The stores created with storage.New resolve it the same way, see storage.WithTx.
 func DB(ctx context.Context, fallback *gorm.DB) *gorm.DB {
-	db := ctx.Value(dbKey{})
-	if db == nil {
-		return fallback
+	if tx, ok := storage.TxFromContext(ctx); ok {
+		return tx
 	}
-	return db.(*gorm.DB)
+	return fallback
 }
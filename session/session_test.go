@@ -0,0 +1,409 @@
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	pgContainer "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"golang.org/x/exp/slog"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/zechao158/ecomm/session"
+	"github.com/zechao158/ecomm/session/repository"
+	user "github.com/zechao158/ecomm/session/service"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+)
+
+func TestGormSQLite(t *testing.T) {
+	db, err := storage.NewStorage(storage.Config{
+		Driver: storage.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	testGorm(t, db)
+}
+
+func TestGormPostgres(t *testing.T) {
+	ctx := context.Background()
+	ctr, err := pgContainer.Run(
+		ctx,
+		"postgres:17-alpine",
+		pgContainer.WithDatabase("yourdb"),
+		pgContainer.WithUsername("youruser"),
+		pgContainer.WithPassword("yourpassword"),
+		pgContainer.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	require.NoError(t, err)
+	dbURL, err := ctr.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	db, err := gorm.Open(storage.NewPostgresDialector(postgres.Config{DSN: dbURL}), &gorm.Config{TranslateError: true})
+	require.NoError(t, err)
+	// the activities are stored in the schema of the application
+	require.NoError(t, db.Exec("CREATE SCHEMA IF NOT EXISTS "+storage.DefaultSchema).Error)
+	testGorm(t, db)
+}
+
+// failingActivity fails to create the activities.
+type failingActivity struct {
+	user.Repository
+}
+
+func (failingActivity) CreateActivity(context.Context, *types.UserActivity) error {
+	return errors.New("activity unavailable")
+}
+
+func testGorm(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	require.NoError(t, db.AutoMigrate(&session.User{}, &types.UserActivity{}))
+	s := session.GORM(db, nil)
+	repo := repository.NewUser(db)
+	failure := errors.New("failure")
+
+	exists := func(t *testing.T, u *session.User) bool {
+		var n int64
+		assert.NoError(t, db.Model(&session.User{}).Where("id = ?", u.ID).Count(&n).Error)
+		return n > 0
+	}
+	newUser := func(name string) *session.User {
+		return &session.User{ID: uuid.New(), Name: name, Email: name + "@test.com"}
+	}
+
+	t.Run("a failed nested transaction only rolls back to its savepoint", func(t *testing.T) {
+		outer, inner := newUser("outer"), newUser("inner")
+		err := s.Transaction(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, outer); err != nil {
+				return err
+			}
+			err := s.Transaction(ctx, func(ctx context.Context) error {
+				if err := repo.Create(ctx, inner); err != nil {
+					return err
+				}
+				return failure
+			})
+			assert.ErrorIs(t, err, failure)
+			// the outer transaction carries on
+			return repo.Create(ctx, newUser("after"))
+		})
+		assert.NoError(t, err)
+		assert.True(t, exists(t, outer))
+		assert.False(t, exists(t, inner))
+	})
+
+	t.Run("a failed statement doesn't abort the enclosing transaction", func(t *testing.T) {
+		taken := newUser("taken")
+		assert.NoError(t, repo.Create(ctx, taken))
+		outer := newUser("outer-statement")
+		err := s.Transaction(ctx, func(ctx context.Context) error {
+			err := s.Transaction(ctx, func(ctx context.Context) error {
+				// duplicate primary key
+				return repo.Create(ctx, &session.User{ID: taken.ID, Name: "duplicate"})
+			})
+			assert.Error(t, err)
+			return repo.Create(ctx, outer)
+		})
+		assert.NoError(t, err)
+		assert.True(t, exists(t, outer))
+	})
+
+	t.Run("a nested transaction is committed with the enclosing one", func(t *testing.T) {
+		inner := newUser("rolled-back-inner")
+		err := s.Transaction(ctx, func(ctx context.Context) error {
+			if err := s.Transaction(ctx, func(ctx context.Context) error {
+				return repo.Create(ctx, inner)
+			}); err != nil {
+				return err
+			}
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.False(t, exists(t, inner))
+	})
+
+	t.Run("Begin", func(t *testing.T) {
+		tx, err := s.Begin(ctx)
+		require.NoError(t, err)
+		kept, discarded := newUser("kept"), newUser("discarded")
+		require.NoError(t, repo.Create(tx.Context(), kept))
+
+		nested, err := tx.Begin(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, repo.Create(nested.Context(), discarded))
+		assert.NoError(t, nested.Rollback())
+
+		committed, err := s.Begin(tx.Context())
+		require.NoError(t, err)
+		committedUser := newUser("nested-commit")
+		require.NoError(t, repo.Create(committed.Context(), committedUser))
+		assert.NoError(t, committed.Commit())
+
+		assert.NoError(t, tx.Commit())
+		assert.True(t, exists(t, kept))
+		assert.False(t, exists(t, discarded))
+		assert.True(t, exists(t, committedUser))
+	})
+
+	t.Run("OnCommit and OnRollback", func(t *testing.T) {
+		var ran []string
+		callback := func(name string) func(context.Context) error {
+			return func(context.Context) error {
+				ran = append(ran, name)
+				return nil
+			}
+		}
+		notified := newUser("notified")
+		err := s.Transaction(ctx, func(ctx context.Context) error {
+			session.OnCommit(ctx, callback("outer"))
+			assert.NoError(t, s.Transaction(ctx, func(ctx context.Context) error {
+				session.OnCommit(ctx, callback("nested"))
+				// the callbacks can use the repositories once the transaction committed
+				session.OnCommit(ctx, func(ctx context.Context) error {
+					return repo.Create(ctx, notified)
+				})
+				return nil
+			}))
+			err := s.Transaction(ctx, func(ctx context.Context) error {
+				session.OnCommit(ctx, callback("never"))
+				session.OnRollback(ctx, callback("nested rolled back"))
+				return failure
+			})
+			assert.ErrorIs(t, err, failure)
+			assert.Equal(t, []string{"nested rolled back"}, ran, "the commit callbacks wait for the outermost commit")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"nested rolled back", "outer", "nested"}, ran)
+		assert.True(t, exists(t, notified))
+
+		ran = nil
+		tx, err := s.Begin(ctx)
+		require.NoError(t, err)
+		tx.OnCommit(callback("never"))
+		tx.OnRollback(callback("rolled back"))
+		nested, err := tx.Begin(ctx)
+		require.NoError(t, err)
+		nested.OnRollback(callback("nested rolled back"))
+		assert.NoError(t, nested.Commit())
+		assert.NoError(t, tx.Rollback())
+		assert.Equal(t, []string{"rolled back", "nested rolled back"}, ran)
+	})
+
+	t.Run("the errors of the callbacks don't undo the commit", func(t *testing.T) {
+		var logs bytes.Buffer
+		defaultLogger := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+		defer slog.SetDefault(defaultLogger)
+
+		committed := newUser("committed")
+		err := s.Transaction(ctx, func(ctx context.Context) error {
+			session.OnCommit(ctx, func(context.Context) error {
+				return errors.New("mail server unavailable")
+			})
+			return repo.Create(ctx, committed)
+		})
+		assert.NoError(t, err)
+		assert.True(t, exists(t, committed))
+		assert.Contains(t, logs.String(), "mail server unavailable")
+	})
+
+	t.Run("TxOptions", func(t *testing.T) {
+		repeatable := session.TxOptions{Isolation: sql.LevelRepeatableRead}
+		serializable := session.TxOptions{Isolation: sql.LevelSerializable}
+		err := s.Transaction(ctx, func(ctx context.Context) error {
+			err := s.Transaction(ctx, func(context.Context) error { return nil }, serializable)
+			assert.ErrorIs(t, err, session.ErrIsolationLevel)
+			assert.ErrorContains(t, err, "Serializable requested in a Repeatable Read transaction")
+			assert.NoError(t, s.Transaction(ctx, func(context.Context) error { return nil }, repeatable))
+			assert.NoError(t, s.Transaction(ctx, func(context.Context) error { return nil },
+				session.TxOptions{Isolation: sql.LevelReadCommitted}))
+			assert.NoError(t, s.Transaction(ctx, func(context.Context) error { return nil }))
+			return nil
+		}, repeatable)
+		assert.NoError(t, err)
+
+		// the default isolation level is read committed
+		tx, err := s.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Begin(ctx, repeatable)
+		assert.ErrorIs(t, err, session.ErrIsolationLevel)
+		assert.NoError(t, tx.Rollback())
+
+		// the options of the session are the default ones
+		strict := session.GORM(db, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		assert.NoError(t, strict.Transaction(ctx, func(ctx context.Context) error {
+			return s.Transaction(ctx, func(context.Context) error { return nil }, serializable)
+		}))
+
+		err = s.Transaction(ctx, func(context.Context) error {
+			t.Error("the transaction ran after its deadline")
+			return nil
+		}, session.TxOptions{Deadline: time.Now().Add(-time.Second)})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		if db.Dialector.Name() != storage.DriverPostgres {
+			return
+		}
+		err = s.Transaction(ctx, func(ctx context.Context) error {
+			var isolation string
+			assert.NoError(t, session.DB(ctx, db).Raw("SHOW transaction_isolation").Scan(&isolation).Error)
+			assert.Equal(t, "serializable", isolation)
+			return nil
+		}, serializable)
+		assert.NoError(t, err)
+		err = s.Transaction(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, newUser("read-only"))
+		}, session.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		assert.ErrorContains(t, err, "read-only transaction")
+		err = s.Transaction(storage.WithStatementTimeout(ctx, 2*time.Second), func(ctx context.Context) error {
+			var timeout string
+			assert.NoError(t, session.DB(ctx, db).Raw("SHOW statement_timeout").Scan(&timeout).Error)
+			assert.Equal(t, "2s", timeout)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		retrying := session.GORM(db, nil)
+		retrying.Retry = storage.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+		serialization := &pgconn.PgError{Code: "40001", Message: "could not serialize access"}
+		deadlock := &pgconn.PgError{Code: "40P01", Message: "deadlock detected"}
+
+		var created []*session.User
+		committed := 0
+		err := retrying.Transaction(ctx, func(ctx context.Context) error {
+			u := newUser(fmt.Sprintf("retried-%d", len(created)))
+			created = append(created, u)
+			if err := repo.Create(ctx, u); err != nil {
+				return err
+			}
+			session.OnCommit(ctx, func(context.Context) error {
+				committed++
+				return nil
+			})
+			if len(created) == 1 {
+				return serialization
+			}
+			return nil
+		}, session.TxOptions{Isolation: sql.LevelSerializable})
+		assert.NoError(t, err)
+		if assert.Len(t, created, 2) {
+			assert.False(t, exists(t, created[0]), "the failed attempt is rolled back")
+			assert.True(t, exists(t, created[1]))
+		}
+		assert.Equal(t, 1, committed, "only the attempt that committed runs its callbacks")
+
+		attempts := 0
+		err = retrying.Transaction(ctx, func(context.Context) error {
+			attempts++
+			return deadlock
+		})
+		assert.ErrorIs(t, err, deadlock)
+		assert.True(t, storage.IsRetryable(err))
+		assert.Equal(t, 3, attempts, "gives up after MaxAttempts")
+
+		attempts = 0
+		err = retrying.Transaction(ctx, func(context.Context) error {
+			attempts++
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, attempts, "the other errors aren't retried")
+
+		attempts = 0
+		err = retrying.Transaction(ctx, func(ctx context.Context) error {
+			err := retrying.Transaction(ctx, func(context.Context) error {
+				attempts++
+				return deadlock
+			})
+			assert.ErrorIs(t, err, deadlock)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempts, "a nested transaction isn't retried on its own")
+
+		if db.Dialector.Name() != storage.DriverPostgres {
+			return
+		}
+		// two serializable transactions each inserting a row the other one read can't both commit, the one
+		// failing with a serialization failure runs again and sees the row of the other
+		var (
+			read sync.WaitGroup
+			wg   sync.WaitGroup
+			runs atomic.Int32
+			errs = make([]error, 2)
+		)
+		read.Add(2)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				first := true
+				errs[i] = retrying.Transaction(ctx, func(ctx context.Context) error {
+					runs.Add(1)
+					var n int64
+					if err := session.DB(ctx, db).Model(&session.User{}).Where("name LIKE ?", "concurrent-%").Count(&n).Error; err != nil {
+						return err
+					}
+					if first {
+						first = false
+						read.Done()
+						read.Wait()
+					}
+					return repo.Create(ctx, newUser(fmt.Sprintf("concurrent-%d-%d", i, n)))
+				}, session.TxOptions{Isolation: sql.LevelSerializable})
+			}()
+		}
+		wg.Wait()
+		assert.NoError(t, errs[0])
+		assert.NoError(t, errs[1])
+		assert.Equal(t, int32(3), runs.Load())
+		var names []string
+		assert.NoError(t, db.Model(&session.User{}).Where("name LIKE ?", "concurrent-%").Order("name").Pluck("name", &names).Error)
+		assert.Len(t, names, 2)
+	})
+
+	t.Run("Service.Register", func(t *testing.T) {
+		registered, err := user.NewService(s, repo).Register(ctx, newUser("registered"))
+		assert.NoError(t, err)
+		assert.True(t, exists(t, registered))
+		var activities []types.UserActivity
+		assert.NoError(t, db.Where("user_id = ?", registered.ID).Find(&activities).Error)
+		if assert.Len(t, activities, 1) {
+			assert.Equal(t, types.ActivityRegister, activities[0].Action)
+		}
+
+		failed := newUser("failed")
+		_, err = user.NewService(s, failingActivity{repo}).Register(ctx, failed)
+		assert.Error(t, err)
+		assert.False(t, exists(t, failed), "the user is rolled back with its activity")
+
+		// a registration failing inside a larger transaction doesn't abort it
+		other := newUser("other")
+		err = s.Transaction(ctx, func(ctx context.Context) error {
+			_, err := user.NewService(s, failingActivity{repo}).Register(ctx, failed)
+			assert.Error(t, err)
+			_, err = user.NewService(s, repo).Register(ctx, other)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.False(t, exists(t, failed))
+		assert.True(t, exists(t, other))
+	})
+}
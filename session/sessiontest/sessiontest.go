@@ -0,0 +1,40 @@
+// Package sessiontest provides a session.Session for the tests of the handlers running on in-memory stores.
+package sessiontest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zechao158/ecomm/session"
+)
+
+// Memory runs the transactions against the in-memory stores, which have no transaction: the function of a
+// transaction runs once with the context it is given, and the OnCommit callbacks run right away.
+type Memory struct{}
+
+var _ session.Session = Memory{}
+
+// Begin implements session.Session, a Memory session can't be started manually.
+func (Memory) Begin(context.Context, ...session.TxOptions) (session.Session, error) {
+	return nil, errors.New("not supported")
+}
+
+// Transaction implements session.Session.
+func (Memory) Transaction(ctx context.Context, f func(context.Context) error, _ ...session.TxOptions) error {
+	return f(ctx)
+}
+
+// Rollback implements session.Session.
+func (Memory) Rollback() error { return nil }
+
+// Commit implements session.Session.
+func (Memory) Commit() error { return nil }
+
+// Context implements session.Session.
+func (Memory) Context() context.Context { return context.Background() }
+
+// OnCommit implements session.Session.
+func (Memory) OnCommit(f func(context.Context) error) { f(context.Background()) }
+
+// OnRollback implements session.Session.
+func (Memory) OnRollback(func(context.Context) error) {}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// aggregate is a SQL aggregate function applied to a numeric column.
+type aggregate string
+
+const (
+	aggregateSum aggregate = "SUM"
+	aggregateMin aggregate = "MIN"
+	aggregateMax aggregate = "MAX"
+)
+
+// Count implements CRUDStorer, it returns the number of rows matching filter.
+func (c CRUDStore[T]) Count(ctx context.Context, filter Predicate) (int64, error) {
+	return timedResult(ctx, c, true, func(ctx context.Context, c CRUDStore[T]) (int64, error) {
+		var n int64
+		if r := c.aggregated(ctx, filter).Count(&n); r.Error != nil {
+			return 0, r.Error
+		}
+		return n, nil
+	})
+}
+
+// Exists implements CRUDStorer, it selects a single row matching filter instead of counting them.
+func (c CRUDStore[T]) Exists(ctx context.Context, filter Predicate) (bool, error) {
+	return timedResult(ctx, c, true, func(ctx context.Context, c CRUDStore[T]) (bool, error) {
+		var found []int
+		if r := c.aggregated(ctx, filter).Select("1").Limit(1).Scan(&found); r.Error != nil {
+			return false, r.Error
+		}
+		return len(found) > 0, nil
+	})
+}
+
+// Sum implements CRUDStorer.
+func (c CRUDStore[T]) Sum(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return c.aggregate(ctx, aggregateSum, column, filter)
+}
+
+// Min implements CRUDStorer.
+func (c CRUDStore[T]) Min(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return c.aggregate(ctx, aggregateMin, column, filter)
+}
+
+// Max implements CRUDStorer.
+func (c CRUDStore[T]) Max(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return c.aggregate(ctx, aggregateMax, column, filter)
+}
+
+// CountBy implements CRUDStorer, it runs SELECT column, COUNT(*) ... GROUP BY column.
+func (c CRUDStore[T]) CountBy(ctx context.Context, column string, filter Predicate) (map[string]int64, error) {
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return nil, err
+	}
+	f, err := aggregateField(s, column, false)
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		GroupKey   sql.NullString
+		GroupCount int64
+	}
+	err = c.timed(ctx, true, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.aggregated(ctx, filter).
+			Select("? AS group_key, COUNT(*) AS group_count", clause.Column{Name: f.DBName}).
+			Group(f.DBName).
+			Scan(&groups).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(groups))
+	for _, g := range groups {
+		counts[g.GroupKey.String] += g.GroupCount
+	}
+	return counts, nil
+}
+
+// aggregate applies fn to the numeric column of the rows matching filter. SUM of no rows is 0, MIN and MAX return
+// ErrRecordNotFound since they have no value.
+func (c CRUDStore[T]) aggregate(ctx context.Context, fn aggregate, column string, filter Predicate) (float64, error) {
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return 0, err
+	}
+	f, err := aggregateField(s, column, true)
+	if err != nil {
+		return 0, err
+	}
+	var v sql.NullFloat64
+	err = c.timed(ctx, true, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.aggregated(ctx, filter).Select(string(fn)+"(?)", clause.Column{Name: f.DBName}).Scan(&v).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !v.Valid && fn != aggregateSum {
+		return 0, ErrRecordNotFound
+	}
+	return v.Float64, nil
+}
+
+// aggregated returns the scoped read of the rows of T matching filter.
+func (c CRUDStore[T]) aggregated(ctx context.Context, filter Predicate) *gorm.DB {
+	return Query{Where: filter}.Apply(c.read(ctx).Model(new(T)))
+}
+
+// aggregateField returns the field of the column, which must be numeric when numeric is true.
+func aggregateField(s *schema.Schema, column string, numeric bool) (*schema.Field, error) {
+	f := s.LookUpField(column)
+	if f == nil || f.DBName == "" {
+		return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, column)
+	}
+	if numeric && f.DataType != schema.Int && f.DataType != schema.Uint && f.DataType != schema.Float {
+		return nil, fmt.Errorf("%w: %s is not numeric", ErrInvalidFilter, column)
+	}
+	return f, nil
+}
@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+const auditName = "storage:audit"
+
+// The actions recorded in the audit log.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// auditLoadBatchSize is the number of rows read per statement by the audit of the set-based writes.
+const auditLoadBatchSize = 1000
+
+// redacted replaces the values of the fields tagged with `audit:"redact"` in the audit log.
+const redacted = "[REDACTED]"
+
+// AuditEntry is a change made to an entity through a CRUDStore.
+type AuditEntry struct {
+	ID uuid.UUID `gorm:"type:uuid;primarykey"`
+	// TenantID is the tenant of the change, the entries are isolated per tenant like the entities.
+	TenantID string
+	// EntityType is the name of the Go type of the entity, like Product.
+	EntityType string
+	EntityID   string
+	Action     string
+	// ActorID is the user that made the change, nil when the context has no actor.
+	ActorID *uuid.UUID `gorm:"type:uuid"`
+	// Changes maps the changed columns to their old and new values, see FieldChange.
+	Changes   json.RawMessage `gorm:"type:jsonb"`
+	CreatedAt time.Time
+}
+
+func (AuditEntry) TableName(namer schema.Namer) string {
+	return TableName(namer, "audit_log")
+}
+
+// FieldChange is the old and new value of a column, Old is nil for a creation and New is nil for a deletion.
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// AuditSkipper is implemented by the entities whose changes must not be audited.
+type AuditSkipper interface {
+	SkipAudit() bool
+}
+
+// Auditor is a GORM plugin enabling the audit log: once it is registered the Create, Update, Delete, Restore
+// and Purge operations of the CRUDStores record an AuditEntry in the same transaction as the change. The
+// set-based CreateMany, UpsertMany, UpdateWhere and DeleteWhere record an entry for every row they change.
+// The fields tagged with `audit:"redact"` are recorded as changed without their values.
+type Auditor struct{}
+
+var _ gorm.Plugin = Auditor{}
+
+// Name implements gorm.Plugin.
+func (Auditor) Name() string {
+	return auditName
+}
+
+// Initialize implements gorm.Plugin.
+func (Auditor) Initialize(*gorm.DB) error {
+	return nil
+}
+
+func auditEnabled(db *gorm.DB) bool {
+	_, ok := db.Config.Plugins[auditName]
+	return ok
+}
+
+type actorKey struct{}
+
+// WithActor returns a context recording id as the author of the changes made with it.
+func WithActor(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, id)
+}
+
+// ActorFromContext returns the author of the changes made with the context.
+func ActorFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(actorKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// auditing reports whether the changes of T must be audited.
+func (c CRUDStore[T]) auditing() bool {
+	if !auditEnabled(c.db) {
+		return false
+	}
+	if s, ok := any(new(T)).(AuditSkipper); ok && s.SkipAudit() {
+		return false
+	}
+	return true
+}
+
+// auditLoad reads the stored state of the row with the given primary key, including the soft deleted rows,
+// it returns nil if the row doesn't exist.
+func (c CRUDStore[T]) auditLoad(ctx context.Context, s *schema.Schema, id any) (*T, error) {
+	if s.PrioritizedPrimaryField == nil {
+		return nil, nil
+	}
+	var stored T
+	r := tenantScoped(ctx, c.db.WithContext(ctx), s).Unscoped().Clauses(dbresolver.Write).
+		Where(clause.Eq{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Value: id}).
+		Limit(1).Find(&stored)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	if r.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+// auditID returns the primary key of t.
+func auditID[T any](ctx context.Context, s *schema.Schema, t *T) any {
+	if s.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	v, _ := s.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(t).Elem())
+	return v
+}
+
+// audited runs the write, when the changes of T are audited it runs it in a transaction, or a savepoint if c
+// already belongs to one, and records how it changed the row identified by key. The key is evaluated before and
+// after the write, since a creation may only know its primary key once the row is inserted.
+func (c CRUDStore[T]) audited(ctx context.Context, s *schema.Schema, action string, key func() any, write func(tx CRUDStore[T]) error) error {
+	if !c.auditing() {
+		return write(c)
+	}
+	return c.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		tx := CRUDStore[T]{db: db}
+		before, err := tx.auditLoad(ctx, s, key())
+		if err != nil {
+			return err
+		}
+		if err := write(tx); err != nil {
+			return err
+		}
+		id := key()
+		after, err := tx.auditLoad(ctx, s, id)
+		if err != nil {
+			return err
+		}
+		if action == AuditUpdate && before == nil {
+			// Update inserts the rows that don't exist
+			action = AuditCreate
+		}
+		return tx.audit(ctx, s, action, id, before, after)
+	})
+}
+
+// auditedMany is audited for the set-based writes, it records an entry for every row the write changed. load
+// returns the rows the write is about to change, it runs before the write, and created returns the primary keys
+// of the rows the write may create, it runs after the write.
+func (c CRUDStore[T]) auditedMany(ctx context.Context, s *schema.Schema, action string, load func(tx CRUDStore[T]) ([]T, error), created func() []any, write func(tx CRUDStore[T]) (int64, error)) (int64, error) {
+	if !c.auditing() || s.PrioritizedPrimaryField == nil {
+		return write(c)
+	}
+	var affected int64
+	err := c.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		tx := CRUDStore[T]{db: db}
+		rows, err := load(tx)
+		if err != nil {
+			return err
+		}
+		before := make(map[string]*T, len(rows))
+		var ids []any
+		for i := range rows {
+			id := auditID(ctx, s, &rows[i])
+			before[fmt.Sprint(id)] = &rows[i]
+			ids = append(ids, id)
+		}
+		if affected, err = write(tx); err != nil {
+			return err
+		}
+		if created != nil {
+			ids = append(ids, created()...)
+		}
+		rows, err = tx.auditLoadMany(ctx, s, ids)
+		if err != nil {
+			return err
+		}
+		after := make(map[string]*T, len(rows))
+		for i := range rows {
+			after[fmt.Sprint(auditID(ctx, s, &rows[i]))] = &rows[i]
+		}
+
+		var entries []AuditEntry
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			key := fmt.Sprint(id)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			rowAction := action
+			if rowAction == AuditUpdate && before[key] == nil {
+				// UpsertMany inserts the rows that don't exist
+				rowAction = AuditCreate
+			}
+			entry, err := auditEntry(ctx, s, rowAction, id, before[key], after[key])
+			if err != nil {
+				return err
+			}
+			if entry != nil {
+				entries = append(entries, *entry)
+			}
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return translateError(tx.db.WithContext(ctx).CreateInBatches(entries, DefaultBatchSize).Error)
+	})
+	return affected, err
+}
+
+// auditLoadMany reads the stored state of the rows with the given primary keys, including the soft deleted rows.
+func (c CRUDStore[T]) auditLoadMany(ctx context.Context, s *schema.Schema, ids []any) ([]T, error) {
+	var rows []T
+	for chunk := range slices.Chunk(ids, auditLoadBatchSize) {
+		var batch []T
+		r := tenantScoped(ctx, c.db.WithContext(ctx), s).Unscoped().Clauses(dbresolver.Write).
+			Where(clause.IN{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Values: chunk}).
+			Find(&batch)
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		rows = append(rows, batch...)
+	}
+	return rows, nil
+}
+
+// auditIDs returns the primary keys of ts.
+func auditIDs[T any](ctx context.Context, s *schema.Schema, ts []T) []any {
+	ids := make([]any, len(ts))
+	for i := range ts {
+		ids[i] = auditID(ctx, s, &ts[i])
+	}
+	return ids
+}
+
+// audit records the change from before to after, one of them is nil for a creation or a deletion.
+func (c CRUDStore[T]) audit(ctx context.Context, s *schema.Schema, action string, id any, before, after *T) error {
+	entry, err := auditEntry(ctx, s, action, id, before, after)
+	if err != nil || entry == nil {
+		return err
+	}
+	return translateError(c.db.WithContext(ctx).Create(entry).Error)
+}
+
+// auditEntry returns the entry recording the change from before to after, nil when nothing changed.
+func auditEntry[T any](ctx context.Context, s *schema.Schema, action string, id any, before, after *T) (*AuditEntry, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+	changes := auditDiff(ctx, s, before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the audit changes: %w", err)
+	}
+	entry := &AuditEntry{
+		ID:         uuid.New(),
+		EntityType: s.Name,
+		EntityID:   fmt.Sprint(id),
+		Action:     action,
+		Changes:    data,
+		CreatedAt:  time.Now(),
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		entry.ActorID = &actor
+	}
+	if tenant, ok := TenantFromContext(ctx); ok {
+		entry.TenantID = tenant
+	} else if f := tenantField(s); f != nil {
+		// a change made without tenant, like a system task, belongs to the tenant of the entity
+		entity := after
+		if entity == nil {
+			entity = before
+		}
+		entry.TenantID, _ = f.ReflectValueOf(ctx, reflect.ValueOf(entity).Elem()).Interface().(string)
+	}
+	return entry, nil
+}
+
+// auditDiff returns the columns whose value differs between before and after. The update timestamps
+// are left out of the updates since they always change.
+func auditDiff[T any](ctx context.Context, s *schema.Schema, before, after *T) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for _, f := range s.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		if before != nil && after != nil && f.AutoUpdateTime > 0 {
+			continue
+		}
+		var change FieldChange
+		if before != nil {
+			change.Old = auditValue(ctx, f, before)
+		}
+		if after != nil {
+			change.New = auditValue(ctx, f, after)
+		}
+		if before != nil && after != nil && reflect.DeepEqual(change.Old, change.New) {
+			continue
+		}
+		if f.Tag.Get("audit") == "redact" {
+			change = FieldChange{Old: redactValue(before), New: redactValue(after)}
+		}
+		changes[f.DBName] = change
+	}
+	return changes
+}
+
+// auditValue returns the value of a field, the times are normalized to the precision of the database so
+// the values read back are equal to the ones written.
+func auditValue[T any](ctx context.Context, f *schema.Field, t *T) any {
+	v := reflect.Indirect(f.ReflectValueOf(ctx, reflect.ValueOf(t).Elem()))
+	if !v.IsValid() {
+		return nil
+	}
+	switch x := v.Interface().(type) {
+	case gorm.DeletedAt:
+		if !x.Valid {
+			return nil
+		}
+		return x.Time.UTC().Truncate(time.Microsecond)
+	case time.Time:
+		if x.IsZero() {
+			return nil
+		}
+		return x.UTC().Truncate(time.Microsecond)
+	case json.RawMessage:
+		return string(x)
+	default:
+		return x
+	}
+}
+
+func redactValue[T any](t *T) any {
+	if t == nil {
+		return nil
+	}
+	return redacted
+}
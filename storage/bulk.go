@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+// DefaultBatchSize is the number of rows inserted per statement when no batch size is given.
+const DefaultBatchSize = 100
+
+// ErrMissingFilter is returned by the set-based operations when no filter is given, to avoid
+// updating or deleting a whole table by mistake.
+var ErrMissingFilter = errors.New("missing filter")
+
+// UpsertOptions configures UpsertMany.
+//
+// ConflictColumns are the columns of the unique constraint used to detect existing rows, the primary key
+// is used when empty. UpdateColumns are the columns overwritten with the new values when the row already
+// exists, when empty the existing rows are left untouched. BatchSize is the number of rows per statement.
+type UpsertOptions struct {
+	ConflictColumns []string
+	UpdateColumns   []string
+	BatchSize       int
+}
+
+// CreateMany implements CRUDStorer, it inserts the records in chunks of batchSize rows inside a single
+// transaction, so either all of them are created or none.
+func (c CRUDStore[T]) CreateMany(ctx context.Context, ts []T, batchSize int) (int64, error) {
+	if len(ts) == 0 {
+		return 0, nil
+	}
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return 0, err
+	}
+	for i := range ts {
+		if err := initVersion(ctx, s, &ts[i]); err != nil {
+			return 0, err
+		}
+		if err := stampTenant(ctx, s, &ts[i]); err != nil {
+			return 0, err
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return timedResult(ctx, c, false, func(ctx context.Context, c CRUDStore[T]) (int64, error) {
+		return c.auditedMany(ctx, s, AuditCreate, noRows[T], func() []any { return auditIDs(ctx, s, ts) }, func(tx CRUDStore[T]) (int64, error) {
+			r := tx.db.WithContext(ctx).CreateInBatches(ts, batchSize)
+			if r.Error != nil {
+				return 0, translateError(r.Error)
+			}
+			return r.RowsAffected, nil
+		})
+	})
+}
+
+// UpsertMany implements CRUDStorer, it inserts the records or updates the existing ones as described by opts.
+// Versions are not checked, callers that need optimistic locking must lock the rows beforehand.
+func (c CRUDStore[T]) UpsertMany(ctx context.Context, ts []T, opts UpsertOptions) (int64, error) {
+	if len(ts) == 0 {
+		return 0, nil
+	}
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return 0, err
+	}
+	for i := range ts {
+		if err := initVersion(ctx, s, &ts[i]); err != nil {
+			return 0, err
+		}
+		if err := stampTenant(ctx, s, &ts[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	onConflict := clause.OnConflict{DoNothing: len(opts.UpdateColumns) == 0}
+	for _, col := range opts.ConflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: col})
+	}
+	if len(onConflict.Columns) == 0 {
+		for _, f := range s.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: f.DBName})
+		}
+	}
+	if !onConflict.DoNothing {
+		onConflict.DoUpdates = clause.AssignmentColumns(opts.UpdateColumns)
+		// a conflicting row of another tenant is left untouched
+		if cond := tenantCondition(ctx, s); cond != nil {
+			onConflict.Where = clause.Where{Exprs: []clause.Expression{cond}}
+		}
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return timedResult(ctx, c, false, func(ctx context.Context, c CRUDStore[T]) (int64, error) {
+		load := func(tx CRUDStore[T]) ([]T, error) {
+			return tx.auditLoadMany(ctx, s, auditIDs(ctx, s, ts))
+		}
+		return c.auditedMany(ctx, s, AuditUpdate, load, func() []any { return auditIDs(ctx, s, ts) }, func(tx CRUDStore[T]) (int64, error) {
+			r := tx.db.WithContext(ctx).Clauses(onConflict).CreateInBatches(ts, batchSize)
+			if r.Error != nil {
+				return 0, translateError(r.Error)
+			}
+			return r.RowsAffected, nil
+		})
+	})
+}
+
+// UpdateWhere implements CRUDStorer, it sets the given column values on every row matching filter.
+// Values can be plain values or expressions such as gorm.Expr("quantity - ?", 1). The version of
+// versioned entities is incremented. When the entity is audited the matching rows are read before and after
+// the update to record their changes.
+func (c CRUDStore[T]) UpdateWhere(ctx context.Context, filter Predicate, values map[string]any) (int64, error) {
+	expr, err := filterExpression(filter)
+	if err != nil {
+		return 0, err
+	}
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return 0, err
+	}
+	if vf := versionField(s); vf != nil {
+		updates := make(map[string]any, len(values)+1)
+		for k, v := range values {
+			updates[k] = v
+		}
+		updates[vf.DBName] = gorm.Expr("? + 1", clause.Column{Name: vf.DBName})
+		values = updates
+	}
+	return timedResult(ctx, c, false, func(ctx context.Context, c CRUDStore[T]) (int64, error) {
+		return c.auditedMany(ctx, s, AuditUpdate, matching[T](ctx, s, expr), nil, func(tx CRUDStore[T]) (int64, error) {
+			r := tenantScoped(ctx, tx.db.WithContext(ctx), s).Model(new(T)).Where(expr).Updates(values)
+			if r.Error != nil {
+				return 0, translateError(r.Error)
+			}
+			return r.RowsAffected, nil
+		})
+	})
+}
+
+// DeleteWhere implements CRUDStorer, it deletes every row matching filter.
+func (c CRUDStore[T]) DeleteWhere(ctx context.Context, filter Predicate) (int64, error) {
+	expr, err := filterExpression(filter)
+	if err != nil {
+		return 0, err
+	}
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return 0, err
+	}
+	return timedResult(ctx, c, false, func(ctx context.Context, c CRUDStore[T]) (int64, error) {
+		return c.auditedMany(ctx, s, AuditDelete, matching[T](ctx, s, expr), nil, func(tx CRUDStore[T]) (int64, error) {
+			r := tenantScoped(ctx, tx.db.WithContext(ctx), s).Where(expr).Delete(new(T))
+			if r.Error != nil {
+				return 0, translateError(r.Error)
+			}
+			return r.RowsAffected, nil
+		})
+	})
+}
+
+// noRows loads no row, the rows of CreateMany don't exist before the write.
+func noRows[T any](CRUDStore[T]) ([]T, error) {
+	return nil, nil
+}
+
+// matching returns the loader of the rows matching the filter of a set-based write, from the primary.
+func matching[T any](ctx context.Context, s *schema.Schema, expr clause.Expression) func(tx CRUDStore[T]) ([]T, error) {
+	return func(tx CRUDStore[T]) ([]T, error) {
+		var rows []T
+		r := tenantScoped(ctx, tx.db.WithContext(ctx), s).Clauses(dbresolver.Write).Where(expr).Find(&rows)
+		return rows, r.Error
+	}
+}
+
+// filterExpression returns the expression of a mandatory filter.
+func filterExpression(filter Predicate) (clause.Expression, error) {
+	if filter == nil {
+		return nil, ErrMissingFilter
+	}
+	expr := filter.Expression()
+	if expr == nil {
+		return nil, ErrMissingFilter
+	}
+	return expr, nil
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cache stores the results of the reads of a CachedStore. The values must be returned as they were set,
+// so implementations storing them out of process have to be able to encode the entities.
+type Cache interface {
+	// Get returns the value of the key, false if it is missing or expired.
+	Get(key string) (any, bool)
+	// Set stores the value of the key, possibly evicting other keys.
+	Set(key string, value any)
+}
+
+type lruEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+// LRUCache is an in-process Cache keeping up to a fixed number of entries, the least recently used ones are
+// evicted first. It is safe for concurrent use.
+type LRUCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+var _ Cache = &LRUCache{}
+
+// NewLRUCache creates a cache holding up to size entries for at most ttl, a zero ttl keeps the entries until
+// they are evicted.
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:    max(size, 1),
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+// Set implements Cache.
+func (c *LRUCache) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(c.ttl)}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of entries, including the expired ones not evicted yet.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// CacheStats are the counters of a CachedStore.
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+type cacheCounters struct {
+	hits, misses, invalidations atomic.Uint64
+}
+
+// cacheIDs numbers the CachedStores so they don't share entries.
+var cacheIDs atomic.Uint64
+
+// CachedStore is a read-through cache in front of a CRUDStorer. The reads are served from the cache when possible
+// and any write made through the store invalidates all the cached reads of T. The reads that lock rows, or whose
+// context comes from UsePrimary, are never cached, neither are the GetAll with a SQLModifier since closures can't
+// be compared.
+//
+// When the context carries a transaction, see WithTx, the reads bypass the cache since they can see uncommitted
+// changes, and the writes only invalidate it once the transaction committed.
+//
+// The writes made to the table without going through the store are only seen once the
+// entries expired. Every store of T should therefore share a single CachedStore.
+type CachedStore[T any] struct {
+	CRUDStorer[T]
+	cache Cache
+	id    uint64
+	// generation is part of the keys, incrementing it invalidates all the entries at once. A read captures it
+	// before querying the store, so a result read before a write is never cached as the result after it.
+	generation *atomic.Uint64
+	counters   *cacheCounters
+}
+
+var _ CRUDStorer[struct{ ID uuid.UUID }] = &CachedStore[struct{ ID uuid.UUID }]{}
+
+// NewCached creates a CachedStore reading through store and keeping the results in cache.
+func NewCached[T any](store CRUDStorer[T], cache Cache) *CachedStore[T] {
+	return &CachedStore[T]{
+		CRUDStorer: store,
+		cache:      cache,
+		id:         cacheIDs.Add(1),
+		generation: &atomic.Uint64{},
+		counters:   &cacheCounters{},
+	}
+}
+
+// Stats returns the counters of the store.
+func (c *CachedStore[T]) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.counters.hits.Load(),
+		Misses:        c.counters.misses.Load(),
+		Invalidations: c.counters.invalidations.Load(),
+	}
+}
+
+// Invalidate drops all the cached reads.
+func (c *CachedStore[T]) Invalidate() {
+	c.generation.Add(1)
+	c.counters.invalidations.Add(1)
+}
+
+// invalidate invalidates the cache once the transaction of ctx committed, right away without transaction.
+func (c *CachedStore[T]) invalidate(ctx context.Context) {
+	AfterCommit(ctx, func(context.Context) {
+		c.Invalidate()
+	})
+}
+
+// GetAll implements CRUDStorer.
+func (c *CachedStore[T]) GetAll(ctx context.Context, m SQLModifier) ([]T, error) {
+	if m != nil {
+		return c.CRUDStorer.GetAll(ctx, m)
+	}
+	return cachedRead(ctx, c, "all", nil, func() ([]T, error) {
+		return c.CRUDStorer.GetAll(ctx, nil)
+	})
+}
+
+// Find implements CRUDStorer.
+func (c *CachedStore[T]) Find(ctx context.Context, q Query) ([]T, error) {
+	if q.ForUpdate {
+		return c.CRUDStorer.Find(ctx, q)
+	}
+	return cachedRead(ctx, c, "find", q, func() ([]T, error) {
+		return c.CRUDStorer.Find(ctx, q)
+	})
+}
+
+// GetPage implements CRUDStorer.
+func (c *CachedStore[T]) GetPage(ctx context.Context, p PageRequest, filter Predicate) (*Page[T], error) {
+	return cachedRead(ctx, c, "page", []any{p, filter}, func() (*Page[T], error) {
+		return c.CRUDStorer.GetPage(ctx, p, filter)
+	})
+}
+
+// GetByID implements CRUDStorer.
+func (c *CachedStore[T]) GetByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*T, error) {
+	if forUpdate {
+		return c.CRUDStorer.GetByID(ctx, id, true)
+	}
+	return cachedRead(ctx, c, "id", id, func() (*T, error) {
+		return c.CRUDStorer.GetByID(ctx, id, false)
+	})
+}
+
+// GetByFields implements CRUDStorer.
+func (c *CachedStore[T]) GetByFields(ctx context.Context, fields map[string]string, forUpdate bool) (*T, error) {
+	if forUpdate {
+		return c.CRUDStorer.GetByFields(ctx, fields, true)
+	}
+	return cachedRead(ctx, c, "fields", fields, func() (*T, error) {
+		return c.CRUDStorer.GetByFields(ctx, fields, false)
+	})
+}
+
+// Count implements CRUDStorer.
+func (c *CachedStore[T]) Count(ctx context.Context, filter Predicate) (int64, error) {
+	return cachedRead(ctx, c, "count", filter, func() (int64, error) {
+		return c.CRUDStorer.Count(ctx, filter)
+	})
+}
+
+// Exists implements CRUDStorer.
+func (c *CachedStore[T]) Exists(ctx context.Context, filter Predicate) (bool, error) {
+	return cachedRead(ctx, c, "exists", filter, func() (bool, error) {
+		return c.CRUDStorer.Exists(ctx, filter)
+	})
+}
+
+// Sum implements CRUDStorer.
+func (c *CachedStore[T]) Sum(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return cachedRead(ctx, c, "sum", []any{column, filter}, func() (float64, error) {
+		return c.CRUDStorer.Sum(ctx, column, filter)
+	})
+}
+
+// Min implements CRUDStorer.
+func (c *CachedStore[T]) Min(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return cachedRead(ctx, c, "min", []any{column, filter}, func() (float64, error) {
+		return c.CRUDStorer.Min(ctx, column, filter)
+	})
+}
+
+// Max implements CRUDStorer.
+func (c *CachedStore[T]) Max(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return cachedRead(ctx, c, "max", []any{column, filter}, func() (float64, error) {
+		return c.CRUDStorer.Max(ctx, column, filter)
+	})
+}
+
+// CountBy implements CRUDStorer.
+func (c *CachedStore[T]) CountBy(ctx context.Context, column string, filter Predicate) (map[string]int64, error) {
+	return cachedRead(ctx, c, "countBy", []any{column, filter}, func() (map[string]int64, error) {
+		return c.CRUDStorer.CountBy(ctx, column, filter)
+	})
+}
+
+// Create implements CRUDStorer.
+func (c *CachedStore[T]) Create(ctx context.Context, t *T) error {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.Create(ctx, t)
+}
+
+// Update implements CRUDStorer.
+func (c *CachedStore[T]) Update(ctx context.Context, t *T) error {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.Update(ctx, t)
+}
+
+// Delete implements CRUDStorer.
+func (c *CachedStore[T]) Delete(ctx context.Context, t *T) error {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.Delete(ctx, t)
+}
+
+// CreateMany implements CRUDStorer.
+func (c *CachedStore[T]) CreateMany(ctx context.Context, ts []T, batchSize int) (int64, error) {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.CreateMany(ctx, ts, batchSize)
+}
+
+// UpsertMany implements CRUDStorer.
+func (c *CachedStore[T]) UpsertMany(ctx context.Context, ts []T, opts UpsertOptions) (int64, error) {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.UpsertMany(ctx, ts, opts)
+}
+
+// UpdateWhere implements CRUDStorer.
+func (c *CachedStore[T]) UpdateWhere(ctx context.Context, filter Predicate, values map[string]any) (int64, error) {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.UpdateWhere(ctx, filter, values)
+}
+
+// DeleteWhere implements CRUDStorer.
+func (c *CachedStore[T]) DeleteWhere(ctx context.Context, filter Predicate) (int64, error) {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.DeleteWhere(ctx, filter)
+}
+
+// Restore implements CRUDStorer.
+func (c *CachedStore[T]) Restore(ctx context.Context, id uuid.UUID) error {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.Restore(ctx, id)
+}
+
+// Purge implements CRUDStorer.
+func (c *CachedStore[T]) Purge(ctx context.Context, t *T) error {
+	defer c.invalidate(ctx)
+	return c.CRUDStorer.Purge(ctx, t)
+}
+
+// cachedRead returns the cached result of the read identified by op and args, or runs it and caches its result.
+// The errors are not cached.
+func cachedRead[T, R any](ctx context.Context, c *CachedStore[T], op string, args any, read func() (R, error)) (R, error) {
+	if _, inTx := TxFromContext(ctx); inTx || usePrimary(ctx) {
+		return read()
+	}
+	tenant, _ := TenantFromContext(ctx)
+	key := fmt.Sprintf("%d:%d:%s:%t:%s:%#v", c.id, c.generation.Load(), tenant, includeDeleted(ctx), op, args)
+	if v, ok := c.cache.Get(key); ok {
+		if r, ok := v.(R); ok {
+			c.counters.hits.Add(1)
+			return clone(r), nil
+		}
+	}
+	c.counters.misses.Add(1)
+	r, err := read()
+	if err != nil {
+		return r, err
+	}
+	c.cache.Set(key, clone(r))
+	return r, nil
+}
@@ -0,0 +1,132 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/gorm"
+)
+
+func TestLRUCache(t *testing.T) {
+	t.Run("eviction", func(t *testing.T) {
+		cache := storage.NewLRUCache(2, 0)
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		_, ok := cache.Get("a")
+		assert.True(t, ok)
+		cache.Set("c", 3)
+
+		_, ok = cache.Get("b")
+		assert.False(t, ok, "the least recently used entry is evicted")
+		v, ok := cache.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+		assert.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		cache := storage.NewLRUCache(10, time.Millisecond)
+		cache.Set("a", 1)
+		time.Sleep(5 * time.Millisecond)
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestCachedStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads are cached until a write", func(t *testing.T) {
+		backend := storage.NewMemory[MemoryTestModel]()
+		store := storage.NewCached[MemoryTestModel](backend, storage.NewLRUCache(100, 0))
+		model := MemoryTestModel{ID: uuid.New(), Name: "a"}
+		assert.NoError(t, store.Create(ctx, &model))
+
+		for range 2 {
+			result, err := store.GetByID(ctx, model.ID, false)
+			assert.NoError(t, err)
+			assert.Equal(t, "a", result.Name)
+			// the cached value is a copy
+			result.Name = "changed"
+		}
+		assert.Equal(t, storage.CacheStats{Hits: 1, Misses: 1, Invalidations: 1}, store.Stats())
+
+		// the writes made behind the back of the store are not seen
+		changed := model
+		changed.Name = "b"
+		assert.NoError(t, backend.Update(ctx, &changed))
+		result, err := store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", result.Name)
+
+		model.Version = changed.Version
+		model.Name = "c"
+		assert.NoError(t, store.Update(ctx, &model))
+		all, err := store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c"}, memoryModelNames(all))
+		result, err = store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "c", result.Name)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		store := storage.NewCached[MemoryTestModel](storage.NewMemory[MemoryTestModel](), storage.NewLRUCache(100, 0))
+		_, err := store.CreateMany(storage.WithTenant(ctx, "acme"), []MemoryTestModel{{ID: uuid.New(), Name: "a"}, {ID: uuid.New(), Name: "b"}}, 0)
+		assert.NoError(t, err)
+
+		_, err = store.Find(ctx, storage.Query{Where: storage.Eq("name", "a")})
+		assert.NoError(t, err)
+		_, err = store.Find(ctx, storage.Query{Where: storage.Eq("name", "b")})
+		assert.NoError(t, err)
+		_, err = store.Find(storage.WithTenant(ctx, "acme"), storage.Query{Where: storage.Eq("name", "a")})
+		assert.NoError(t, err)
+		_, err = store.Find(storage.UsePrimary(ctx), storage.Query{Where: storage.Eq("name", "a")})
+		assert.NoError(t, err)
+		_, err = store.Find(ctx, storage.Query{Where: storage.Eq("name", "a"), ForUpdate: true})
+		assert.NoError(t, err)
+		assert.Equal(t, storage.CacheStats{Misses: 3, Invalidations: 1}, store.Stats())
+
+		_, err = store.Find(ctx, storage.Query{Where: storage.Eq("name", "a")})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), store.Stats().Hits)
+	})
+
+	t.Run("transactions invalidate after commit", func(t *testing.T) {
+		backend := storage.NewMemory[MemoryTestModel]()
+		store := storage.NewCached[MemoryTestModel](backend, storage.NewLRUCache(100, 0))
+		model := MemoryTestModel{ID: uuid.New(), Name: "a"}
+		assert.NoError(t, store.Create(ctx, &model))
+		_, err := store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+
+		// the memory store ignores the transaction, only its hooks matter
+		txCtx := storage.WithTx(ctx, &gorm.DB{})
+		model.Name = "b"
+		assert.NoError(t, store.Update(txCtx, &model))
+		// the reads of the transaction bypass the cache
+		result, err := store.GetByID(txCtx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", result.Name)
+		// the cache is only invalidated once the transaction committed
+		result, err = store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", result.Name)
+
+		storage.Committed(txCtx)
+		result, err = store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", result.Name)
+		assert.Equal(t, uint64(2), store.Stats().Invalidations)
+
+		// a transaction without writes doesn't invalidate the cache
+		txCtx = storage.WithTx(ctx, &gorm.DB{})
+		storage.Committed(txCtx)
+		assert.Equal(t, uint64(2), store.Stats().Invalidations)
+	})
+}
@@ -7,8 +7,18 @@
 // It supports features such as:
 //   - Generic CRUD operations for any entity type
 //   - Support for custom query modifications through SQLModifier
+//   - Typed and composable query specifications through Query and Predicate
+//   - Keyset (cursor) pagination through GetPage
+//   - Streaming of large result sets through Stream
+//   - Aggregates (Count, Exists, Sum, Min, Max and CountBy) computed by the database
 //   - Row-level locking support for PostgreSQL
+//   - Transactions carried by the context, see WithTx, so repositories join the transaction of their caller
+//   - Optimistic locking for entities with a version field
+//   - Bulk inserts, upserts, updates and deletes
 //   - Soft delete capability when entities include a deleted_at field
+//   - Audit log of the changes, with their author, once the Auditor plugin is registered
+//   - Tenant isolation when entities include a tenant_id field
+//   - Read-through caching with invalidation on writes through CachedStore
 //
 // Example usage:
 //
@@ -45,11 +55,12 @@ package storage
 
 import (
 	"context"
-	"errors"
+	"iter"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
 )
 
 // SQLModifier is a type alias for a function that takes a *gorm.DB instance
@@ -60,13 +71,29 @@ type SQLModifier func(db *gorm.DB) *gorm.DB
 // CRUDStorer defines a generic interface for basic CRUD operations.
 // T represents the type of the entity that the CRUD operations will be performed on.
 //
+// The read operations skip the soft deleted records unless the context comes from IncludeDeleted.
+// For tenant aware entities, the operations only see the records of the tenant of the context, see WithTenant.
+// When read replicas are configured, the reads that don't lock rows go to a replica unless they run inside a
+// transaction or the context comes from UsePrimary.
+//
 // GetAll retrieves all records that match the given SQLModifier.
+// Stream iterates over the records that match the given SQLModifier without loading all of them in memory.
+// Find retrieves all records that match the given Query.
+// GetPage retrieves a single page of records using keyset pagination.
 // GetByID retrieves a record by its ID. The forUpdate parameter is used to lock the record for update and is only supported by PostgreSQL.
 // Delete removes the specified record.
 // Create adds a new record and returns the created record.
 // Update modifies an existing record and returns the updated record.
+// CreateMany, UpsertMany, UpdateWhere and DeleteWhere write many records at once and return the number of affected rows.
 type CRUDStorer[T any] interface {
 	GetAll(context.Context, SQLModifier) ([]T, error)
+	// Stream reads the records in batches of batchSize, the iteration stops at the first error, which is yielded
+	// with the zero value of T, and when the context is canceled.
+	Stream(ctx context.Context, m SQLModifier, batchSize int) iter.Seq2[T, error]
+	Find(context.Context, Query) ([]T, error)
+	// GetPage retrieves a page of records matching the given Predicate sorted by the requested key, the returned
+	// cursors can be used to read the next or previous page.
+	GetPage(context.Context, PageRequest, Predicate) (*Page[T], error)
 	// GetByID retrieves a record by its ID.  for Update is used to lock the record for update. and only supportted by
 	// postgresql
 	GetByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*T, error)
@@ -76,6 +103,32 @@ type CRUDStorer[T any] interface {
 	// Create It will insert the data into the table only if the record is new,It doesn't update existing records; if the record already exists, GORM will return an error.
 	Create(context.Context, *T) error
 	Update(context.Context, *T) error
+	// CreateMany inserts the records in chunks of batchSize rows, DefaultBatchSize is used when batchSize is not positive.
+	CreateMany(ctx context.Context, ts []T, batchSize int) (int64, error)
+	// UpsertMany inserts the records, or updates the configured columns of the ones that already exist.
+	UpsertMany(ctx context.Context, ts []T, opts UpsertOptions) (int64, error)
+	// UpdateWhere sets the given column values on every record matching the filter.
+	UpdateWhere(ctx context.Context, filter Predicate, values map[string]any) (int64, error)
+	// DeleteWhere deletes every record matching the filter.
+	DeleteWhere(ctx context.Context, filter Predicate) (int64, error)
+	// Restore undeletes a soft deleted record, it returns ErrRecordNotFound if no deleted record has the given ID
+	// and ErrNotSoftDeletable if T has no deleted_at field.
+	Restore(ctx context.Context, id uuid.UUID) error
+	// Purge permanently removes the record, bypassing the soft delete.
+	Purge(context.Context, *T) error
+	// Count returns the number of records matching the filter, a nil filter counts all of them.
+	Count(ctx context.Context, filter Predicate) (int64, error)
+	// Exists reports whether at least one record matches the filter.
+	Exists(ctx context.Context, filter Predicate) (bool, error)
+	// Sum adds up the numeric column of the records matching the filter, it is 0 when none matches.
+	Sum(ctx context.Context, column string, filter Predicate) (float64, error)
+	// Min returns the smallest value of the numeric column, ErrRecordNotFound when no record matches the filter.
+	Min(ctx context.Context, column string, filter Predicate) (float64, error)
+	// Max returns the largest value of the numeric column, ErrRecordNotFound when no record matches the filter.
+	Max(ctx context.Context, column string, filter Predicate) (float64, error)
+	// CountBy counts the records matching the filter per value of the column, the values are formatted as strings
+	// and NULL is counted under the empty string.
+	CountBy(ctx context.Context, column string, filter Predicate) (map[string]int64, error)
 }
 
 type CRUDStore[T any] struct {
@@ -84,68 +137,136 @@ type CRUDStore[T any] struct {
 
 // Create implements CRUDStorer.
 func (c CRUDStore[T]) Create(ctx context.Context, t *T) error {
-	result := c.db.WithContext(ctx).Create(&t)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-			return ErrDuplicateKey
-		}
-		return result.Error
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return err
+	}
+	if err := initVersion(ctx, s, t); err != nil {
+		return err
 	}
-	return nil
+	if err := stampTenant(ctx, s, t); err != nil {
+		return err
+	}
+	return c.timed(ctx, false, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.audited(ctx, s, AuditCreate, func() any { return auditID(ctx, s, t) }, func(tx CRUDStore[T]) error {
+			result := tx.db.WithContext(ctx).Create(&t)
+			if result.Error != nil {
+				return translateError(result.Error)
+			}
+			return nil
+		})
+	})
 }
 
-// Update implements CRUDStorer, it will create new row if the primary key of given T doesn't exist
+// Update implements CRUDStorer, it will create new row if the primary key of given T doesn't exist.
+// If T has a version field the update is only applied when the stored row has the same version, otherwise
+// ErrStaleObject is returned, and the version of t is incremented on success.
+// For tenant aware entities only the rows of the tenant of the context are updated.
 func (c CRUDStore[T]) Update(ctx context.Context, t *T) error {
-	r := c.db.Save(t)
-
-	return r.Error
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return err
+	}
+	if err := stampTenant(ctx, s, t); err != nil {
+		return err
+	}
+	return c.timed(ctx, false, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.audited(ctx, s, AuditUpdate, func() any { return auditID(ctx, s, t) }, func(tx CRUDStore[T]) error {
+			if vf := versionField(s); vf != nil {
+				return updateVersioned(ctx, tenantScoped(ctx, tx.db, s), s, vf, t)
+			}
+			if tenantCondition(ctx, s) != nil {
+				return updateInTenant(ctx, tx.db, s, t)
+			}
+			return translateError(tx.db.WithContext(ctx).Save(t).Error)
+		})
+	})
 }
 
 // Delete implements CRUDStorer, If value contains primary key it is included in the conditions. If value includes a deleted_at field, then Delete performs a soft delete instead by setting deleted_at with the current time if null.
 func (c CRUDStore[T]) Delete(ctx context.Context, t *T) error {
-	return c.db.Delete(t).Error
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return err
+	}
+	return c.timed(ctx, false, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.audited(ctx, s, AuditDelete, func() any { return auditID(ctx, s, t) }, func(tx CRUDStore[T]) error {
+			return tenantScoped(ctx, tx.db.WithContext(ctx), s).Delete(t).Error
+		})
+	})
 }
 
 // GetAll implements CRUDStorer, SQLModifier allow us to add extra condition to the select statment
 func (c CRUDStore[T]) GetAll(ctx context.Context, m SQLModifier) ([]T, error) {
-	var results []T
-	if m != nil {
-		c.db = m(c.db)
-	}
-	if r := c.db.Find(&results); r.Error != nil {
-		return nil, r.Error
-	}
-	return results, nil
+	return timedResult(ctx, c, !locks(c.db, m), func(ctx context.Context, c CRUDStore[T]) ([]T, error) {
+		var results []T
+		db := c.read(ctx)
+		if m != nil {
+			db = m(db)
+		}
+		if r := db.Find(&results); r.Error != nil {
+			return nil, r.Error
+		}
+		return results, nil
+	})
+}
+
+// Find implements CRUDStorer, it translates the Query into GORM conditions.
+func (c CRUDStore[T]) Find(ctx context.Context, q Query) ([]T, error) {
+	return c.GetAll(ctx, q.Apply)
 }
 
 // GetByID implements CRUDStorer, when forUpdate is true, it perform select for update query, which will lock the row
 func (c CRUDStore[T]) GetByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*T, error) {
-	var r *T
-	if forUpdate {
-		c.db.Clauses(clause.Locking{Strength: "UPDATE"})
-	}
-	if r := c.db.First(&r, "id = ?", id.String()); r.Error != nil {
-		if r.Error == gorm.ErrRecordNotFound {
-			return nil, ErrRecordNotFound
+	return timedResult(ctx, c, !forUpdate, func(ctx context.Context, c CRUDStore[T]) (*T, error) {
+		var r *T
+		db := c.read(ctx)
+		if forUpdate {
+			db = db.Clauses(clause.Locking{Strength: "UPDATE"})
 		}
-		return nil, r.Error
-	}
-	return r, nil
+		if r := db.First(&r, "id = ?", id.String()); r.Error != nil {
+			if r.Error == gorm.ErrRecordNotFound {
+				return nil, ErrRecordNotFound
+			}
+			return nil, r.Error
+		}
+		return r, nil
+	})
 }
 
 // GetByID implements CRUDStorer, when forUpdate is true, it perform select for update query, which will lock the row
 func (c CRUDStore[T]) GetByFields(ctx context.Context, fields map[string]string, forUpdate bool) (*T, error) {
-	var r *T
-	if forUpdate {
-		c.db.Clauses(clause.Locking{Strength: "UPDATE"})
-	}
-	if r := c.db.Where(fields).First(&r); r.Error != nil {
-		if r.Error == gorm.ErrRecordNotFound {
-			return nil, ErrRecordNotFound
+	return timedResult(ctx, c, !forUpdate, func(ctx context.Context, c CRUDStore[T]) (*T, error) {
+		var r *T
+		db := c.read(ctx)
+		if forUpdate {
+			db = db.Clauses(clause.Locking{Strength: "UPDATE"})
 		}
-		return nil, r.Error
+		if r := db.Where(fields).First(&r); r.Error != nil {
+			if r.Error == gorm.ErrRecordNotFound {
+				return nil, ErrRecordNotFound
+			}
+			return nil, r.Error
+		}
+		return r, nil
+	})
+}
+
+// read returns the connection used by the read operations, scoped to the tenant of the context.
+func (c CRUDStore[T]) read(ctx context.Context) *gorm.DB {
+	db := c.db.WithContext(ctx)
+	if s, err := parseSchema[T](c.db); err != nil {
+		db.AddError(err)
+	} else {
+		db = tenantScoped(ctx, db, s)
+	}
+	if includeDeleted(ctx) {
+		db = db.Unscoped()
+	}
+	if usePrimary(ctx) {
+		db = db.Clauses(dbresolver.Write)
 	}
-	return r, nil
+	return db
 }
 
 // New creates a new instance of CRUDStorer for the given type T.
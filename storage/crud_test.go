@@ -2,10 +2,13 @@ package storage_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/testcontainers/testcontainers-go"
 	pgContainer "github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -21,12 +24,28 @@ type TestModel struct {
 	UpdatedAt time.Time
 }
 
-func TestCRUDStore(t *testing.T) {
+type VersionedTestModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name      string
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type SoftDeleteTestModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+// runPostgres starts a postgres container for the test and returns its connection string.
+func runPostgres(t *testing.T) string {
 	ctx := context.Background()
 	dbname := "yourdb"
 	user := "youruser"
 	password := "yourpassword"
-	// 1. Start the postgres ctr and run any migrations on it
 	ctr, err := pgContainer.Run(
 		ctx,
 		"postgres:17-alpine",
@@ -36,18 +55,30 @@ func TestCRUDStore(t *testing.T) {
 		pgContainer.BasicWaitStrategies(),
 	)
 	testcontainers.CleanupContainer(t, ctr)
-	assert.NoError(t, err)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
 
 	dbURL, err := ctr.ConnectionString(ctx, "sslmode=disable")
 	assert.NoError(t, err)
+	return dbURL
+}
+
+func TestCRUDStore(t *testing.T) {
+	ctx := context.Background()
+	// 1. Start the postgres ctr and run any migrations on it
+	dbURL := runPostgres(t)
 
-	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{
+	db, err := gorm.Open(storage.NewPostgresDialector(postgres.Config{DSN: dbURL}), &gorm.Config{
 		TranslateError: true,
 	})
 	db = db.Debug()
 	assert.NoError(t, err)
 
-	err = db.AutoMigrate(&TestModel{})
+	err = db.AutoMigrate(&TestModel{}, &VersionedTestModel{}, &SoftDeleteTestModel{}, &TenantTestModel{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec("CREATE SCHEMA IF NOT EXISTS ecom").Error)
+	err = db.AutoMigrate(&storage.AuditEntry{})
 	assert.NoError(t, err)
 
 	RunTest := func(name string, f func(t *testing.T, tx *gorm.DB)) {
@@ -117,6 +148,89 @@ func TestCRUDStore(t *testing.T) {
 		assert.Equal(t, "Updated", result.Name)
 	})
 
+	RunTest("Update versioned", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[VersionedTestModel](tx)
+		model := VersionedTestModel{ID: uuid.New(), Name: "Test"}
+		assert.NoError(t, store.Create(ctx, &model))
+		assert.Equal(t, 1, model.Version)
+
+		stale := model
+		model.Name = "Updated"
+		assert.NoError(t, store.Update(ctx, &model))
+		assert.Equal(t, 2, model.Version)
+
+		stale.Name = "Stale"
+		err := store.Update(ctx, &stale)
+		assert.ErrorIs(t, err, storage.ErrStaleObject)
+		assert.Equal(t, 1, stale.Version)
+
+		var result VersionedTestModel
+		assert.NoError(t, tx.First(&result, "id = ?", model.ID).Error)
+		assert.Equal(t, "Updated", result.Name)
+		assert.Equal(t, 2, result.Version)
+
+		missing := VersionedTestModel{ID: uuid.New(), Version: 1}
+		assert.ErrorIs(t, store.Update(ctx, &missing), storage.ErrRecordNotFound)
+	})
+
+	RunTest("CreateMany", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		models := []TestModel{
+			{ID: uuid.New(), Name: "Test1"},
+			{ID: uuid.New(), Name: "Test2"},
+			{ID: uuid.New(), Name: "Test3"},
+		}
+		n, err := store.CreateMany(ctx, models, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		_, err = store.CreateMany(ctx, models[:1], 2)
+		assert.ErrorIs(t, err, storage.ErrDuplicateKey)
+	})
+
+	RunTest("UpsertMany", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		existing := TestModel{ID: uuid.New(), Name: "Test1"}
+		assert.NoError(t, tx.Create(&existing).Error)
+
+		existing.Name = "Updated"
+		n, err := store.UpsertMany(ctx, []TestModel{existing, {ID: uuid.New(), Name: "Test2"}}, storage.UpsertOptions{
+			UpdateColumns: []string{"name"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		var result TestModel
+		assert.NoError(t, tx.First(&result, "id = ?", existing.ID).Error)
+		assert.Equal(t, "Updated", result.Name)
+	})
+
+	RunTest("UpdateWhere and DeleteWhere", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[VersionedTestModel](tx)
+		models := []VersionedTestModel{
+			{ID: uuid.New(), Name: "a"},
+			{ID: uuid.New(), Name: "a"},
+			{ID: uuid.New(), Name: "b"},
+		}
+		_, err := store.CreateMany(ctx, models, 0)
+		assert.NoError(t, err)
+
+		n, err := store.UpdateWhere(ctx, storage.Eq("name", "a"), map[string]any{"name": "c"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		var result VersionedTestModel
+		assert.NoError(t, tx.First(&result, "id = ?", models[0].ID).Error)
+		assert.Equal(t, "c", result.Name)
+		assert.Equal(t, 2, result.Version)
+
+		n, err = store.DeleteWhere(ctx, storage.In("name", []string{"b", "c"}))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		_, err = store.DeleteWhere(ctx, nil)
+		assert.ErrorIs(t, err, storage.ErrMissingFilter)
+	})
+
 	RunTest("GetAll", func(t *testing.T, tx *gorm.DB) {
 		store := storage.New[TestModel](tx)
 		model1 := &TestModel{ID: uuid.New(), Name: "Test1"}
@@ -129,6 +243,127 @@ func TestCRUDStore(t *testing.T) {
 		assert.Len(t, results, 2)
 	})
 
+	RunTest("Find", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		for _, name := range []string{"a", "b", "c"} {
+			assert.NoError(t, tx.Create(&TestModel{ID: uuid.New(), Name: name}).Error)
+		}
+
+		results, err := store.Find(ctx, storage.Query{
+			Where: storage.Or(storage.Eq("name", "a"), storage.Eq("name", "c")),
+			Order: []storage.Order{{Field: "name", Desc: true}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c", "a"}, testModelNames(results))
+	})
+
+	RunTest("GetPage", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		for _, name := range []string{"e", "b", "d", "a", "c"} {
+			assert.NoError(t, tx.Create(&TestModel{ID: uuid.New(), Name: name}).Error)
+		}
+
+		page, err := store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, testModelNames(page.Items))
+		assert.Empty(t, page.PrevCursor)
+
+		page, err = store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2, Cursor: page.NextCursor}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c", "d"}, testModelNames(page.Items))
+
+		last, err := store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2, Cursor: page.NextCursor}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"e"}, testModelNames(last.Items))
+		assert.Empty(t, last.NextCursor)
+
+		prev, err := store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2, Cursor: page.PrevCursor}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, testModelNames(prev.Items))
+		assert.Empty(t, prev.PrevCursor)
+
+		desc, err := store.GetPage(ctx, storage.PageRequest{SortKey: "name", Desc: true, Limit: 3}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"e", "d", "c"}, testModelNames(desc.Items))
+
+		_, err = store.GetPage(ctx, storage.PageRequest{SortKey: "unknown"}, nil)
+		assert.ErrorIs(t, err, storage.ErrInvalidSortKey)
+		_, err = store.GetPage(ctx, storage.PageRequest{SortKey: "id", Cursor: page.NextCursor}, nil)
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor)
+	})
+
+	RunTest("Soft delete, Restore and Purge", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[SoftDeleteTestModel](tx)
+		model := SoftDeleteTestModel{ID: uuid.New(), Name: "Test"}
+		assert.NoError(t, store.Create(ctx, &model))
+
+		assert.NoError(t, store.Delete(ctx, &model))
+		_, err := store.GetByID(ctx, model.ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		deleted, err := store.GetByID(storage.IncludeDeleted(ctx), model.ID, false)
+		assert.NoError(t, err)
+		assert.True(t, deleted.DeletedAt.Valid)
+		all, err := store.Find(storage.IncludeDeleted(ctx), storage.Query{Where: storage.Ne(storage.DeletedAtColumn, nil)})
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		assert.NoError(t, store.Restore(ctx, model.ID))
+		assert.ErrorIs(t, store.Restore(ctx, model.ID), storage.ErrRecordNotFound)
+		restored, err := store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.False(t, restored.DeletedAt.Valid)
+
+		assert.NoError(t, store.Purge(ctx, &model))
+		_, err = store.GetByID(storage.IncludeDeleted(ctx), model.ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+
+		assert.ErrorIs(t, storage.New[TestModel](tx).Restore(ctx, model.ID), storage.ErrNotSoftDeletable)
+	})
+
+	RunTest("Audit", func(t *testing.T, tx *gorm.DB) {
+		assert.NoError(t, tx.Use(storage.Auditor{}))
+		defer delete(tx.Config.Plugins, storage.Auditor{}.Name())
+		store := storage.New[SoftDeleteTestModel](tx)
+		actor := uuid.New()
+		ctx := storage.WithActor(ctx, actor)
+
+		model := SoftDeleteTestModel{ID: uuid.New(), Name: "Test"}
+		assert.NoError(t, store.Create(ctx, &model))
+		model.Name = "Updated"
+		assert.NoError(t, store.Update(ctx, &model))
+		assert.NoError(t, store.Delete(ctx, &model))
+
+		var entries []storage.AuditEntry
+		assert.NoError(t, tx.Order("created_at").Find(&entries, "entity_id = ?", model.ID.String()).Error)
+		assert.Len(t, entries, 3)
+		for i, action := range []string{storage.AuditCreate, storage.AuditUpdate, storage.AuditDelete} {
+			assert.Equal(t, action, entries[i].Action)
+			assert.Equal(t, "SoftDeleteTestModel", entries[i].EntityType)
+			assert.Equal(t, &actor, entries[i].ActorID)
+		}
+		var changes map[string]storage.FieldChange
+		assert.NoError(t, json.Unmarshal(entries[1].Changes, &changes))
+		assert.Equal(t, map[string]storage.FieldChange{"name": {Old: "Test", New: "Updated"}}, changes)
+		changes = nil
+		assert.NoError(t, json.Unmarshal(entries[2].Changes, &changes))
+		assert.Contains(t, changes, "deleted_at")
+
+		// the set-based writes record an entry per changed row
+		bulk := []SoftDeleteTestModel{{ID: uuid.New(), Name: "bulk"}, {ID: uuid.New(), Name: "bulk"}}
+		_, err := store.CreateMany(ctx, bulk, 0)
+		assert.NoError(t, err)
+		n, err := store.UpdateWhere(ctx, storage.Eq("name", "bulk"), map[string]any{"name": "renamed"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		entries = nil
+		assert.NoError(t, tx.Find(&entries, "entity_id = ? AND action = ?", bulk[1].ID.String(), storage.AuditUpdate).Error)
+		if assert.Len(t, entries, 1) {
+			changes = nil
+			assert.NoError(t, json.Unmarshal(entries[0].Changes, &changes))
+			assert.Equal(t, map[string]storage.FieldChange{"name": {Old: "bulk", New: "renamed"}}, changes)
+		}
+	})
+
 	RunTest("GetByFields", func(t *testing.T, tx *gorm.DB) {
 		store := storage.New[TestModel](tx)
 		model1 := &TestModel{ID: uuid.New(), Name: "Test1"}
@@ -141,6 +376,127 @@ func TestCRUDStore(t *testing.T) {
 		equalTestModel(t, model1, result)
 	})
 
+	RunTest("Tenant isolation", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TenantTestModel](tx)
+		acme, globex := storage.WithTenant(ctx, "acme"), storage.WithTenant(ctx, "globex")
+		a := TenantTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.NoError(t, store.Create(acme, &a))
+		b := TenantTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.NoError(t, store.Create(globex, &b))
+
+		_, err := store.GetByID(globex, a.ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		all, err := store.GetAll(acme, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []TenantTestModel{a}, all)
+		all, err = store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Len(t, all, 2)
+
+		assert.NoError(t, store.Delete(globex, &a))
+		n, err := store.UpdateWhere(globex, storage.Eq("id", a.ID), map[string]any{"email": "stolen@test.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+		stored, err := store.GetByID(acme, a.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, a, *stored)
+
+		// the update doesn't overwrite the row of another tenant, it fails to insert it again
+		stolen := a
+		stolen.Email = "stolen@test.com"
+		assert.ErrorIs(t, store.Update(globex, &stolen), storage.ErrDuplicateKey)
+	})
+
+	RunTest("Constraint error", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TenantTestModel](tx)
+		ctx := storage.WithTenant(ctx, "acme")
+		assert.NoError(t, store.Create(ctx, &TenantTestModel{ID: uuid.New(), Email: "a@test.com"}))
+
+		err := store.Create(ctx, &TenantTestModel{ID: uuid.New(), Email: "a@test.com"})
+		assert.ErrorIs(t, err, storage.ErrDuplicateKey)
+		var ce *storage.ConstraintError
+		assert.ErrorAs(t, err, &ce)
+		assert.Equal(t, storage.ConstraintUnique, ce.Kind)
+		assert.Equal(t, "idx_tenant_test_models_email", ce.Constraint)
+		assert.Equal(t, "tenant_test_models", ce.Table)
+		assert.Equal(t, "email", ce.Column)
+	})
+
+	RunTest("Stream", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		models := make([]TestModel, 5)
+		for i := range models {
+			models[i] = TestModel{ID: uuid.New(), Name: fmt.Sprintf("stream-%d", i)}
+		}
+		_, err := store.CreateMany(ctx, models, 0)
+		assert.NoError(t, err)
+
+		var names []string
+		for model, err := range store.Stream(ctx, func(db *gorm.DB) *gorm.DB {
+			return db.Where("name LIKE ?", "stream-%")
+		}, 2) {
+			assert.NoError(t, err)
+			names = append(names, model.Name)
+		}
+		assert.ElementsMatch(t, testModelNames(models), names)
+	})
+
+	RunTest("Statement timeout", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		model := TestModel{ID: uuid.New(), Name: "timed"}
+		assert.NoError(t, store.Create(storage.WithStatementTimeout(ctx, time.Second), &model))
+
+		// the timeout of the transaction is restored after the operation
+		var timeout string
+		assert.NoError(t, tx.Raw("SHOW statement_timeout").Scan(&timeout).Error)
+		assert.Equal(t, "0", timeout)
+
+		// the timeout applied to the transaction isn't set again around the operations
+		txCtx, err := storage.ApplyStatementTimeout(storage.WithTx(storage.WithStatementTimeout(ctx, 2*time.Second), tx))
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Raw("SHOW statement_timeout").Scan(&timeout).Error)
+		assert.Equal(t, "2s", timeout)
+		assert.NoError(t, tx.Exec("SELECT set_config('statement_timeout', '3s', true)").Error)
+		_, err = store.GetByID(txCtx, model.ID, false)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Raw("SHOW statement_timeout").Scan(&timeout).Error)
+		assert.Equal(t, "3s", timeout)
+		// another timeout is restored to the applied one
+		_, err = store.GetByID(storage.WithStatementTimeout(txCtx, time.Second), model.ID, false)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Raw("SHOW statement_timeout").Scan(&timeout).Error)
+		assert.Equal(t, "2s", timeout)
+
+		_, err = store.GetAll(storage.WithStatementTimeout(ctx, 50*time.Millisecond), func(db *gorm.DB) *gorm.DB {
+			return db.Where("pg_sleep(1) IS NOT NULL")
+		})
+		assert.ErrorIs(t, err, storage.ErrTimeout)
+	})
+
+	RunTest("Canceled statements", func(t *testing.T, tx *gorm.DB) {
+		store := storage.New[TestModel](tx)
+		var pid int
+		assert.NoError(t, tx.Raw("SELECT pg_backend_pid()").Scan(&pid).Error)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			// cancel the statement once it runs, like an operator would
+			for canceled := false; !canceled; {
+				select {
+				case <-done:
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+				db.Raw("SELECT pg_cancel_backend(pid) FROM pg_stat_activity WHERE pid = ? AND query LIKE '%pg_sleep%'", pid).Scan(&canceled)
+			}
+		}()
+		_, err := store.GetAll(storage.WithStatementTimeout(ctx, time.Minute), func(db *gorm.DB) *gorm.DB {
+			return db.Where("pg_sleep(10) IS NOT NULL")
+		})
+		var pgErr *pgconn.PgError
+		assert.ErrorAs(t, err, &pgErr)
+		assert.NotErrorIs(t, err, storage.ErrTimeout, "only the statement timeout is a timeout")
+	})
 }
 
 func equalTestModel(t *testing.T, expected, actual *TestModel) {
@@ -149,3 +505,11 @@ func equalTestModel(t *testing.T, expected, actual *TestModel) {
 	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Microsecond)
 	assert.WithinDuration(t, expected.UpdatedAt, actual.UpdatedAt, time.Microsecond)
 }
+
+func testModelNames(models []TestModel) []string {
+	names := make([]string, len(models))
+	for i := range models {
+		names[i] = models[i].Name
+	}
+	return names
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// The database drivers supported by NewStorage.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// DefaultSchema is the schema of the application tables in PostgreSQL.
+const DefaultSchema = "ecom"
+
+// NewStorage opens the database of the configured driver, PostgreSQL when the driver is empty.
+func NewStorage(cfg Config) (*gorm.DB, error) {
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		return NewPostgreStorage(cfg)
+	case DriverSQLite:
+		return NewSQLiteStorage(cfg)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// NamingStrategy is the GORM naming strategy of the databases opened by NewStorage, it tells the entities
+// the schema qualifying their tables, see TableName.
+type NamingStrategy struct {
+	schema.NamingStrategy
+	// Schema qualifies the application tables, it is empty for the dialects without schemas, like SQLite.
+	Schema string
+}
+
+// TableName returns the name of an application table for the naming strategy of the database, the entities
+// use it to implement schema.TablerWithNamer:
+//
+//	func (User) TableName(namer schema.Namer) string {
+//	    return storage.TableName(namer, "users")
+//	}
+//
+// The table is qualified with DefaultSchema when the database doesn't use a NamingStrategy.
+func TableName(namer schema.Namer, table string) string {
+	s := DefaultSchema
+	if ns, ok := namer.(NamingStrategy); ok {
+		s = ns.Schema
+	}
+	if s == "" {
+		return table
+	}
+	return s + "." + table
+}
+
+// setup registers the plugins shared by all the drivers.
+func setup(db *gorm.DB, cfg Config) (*gorm.DB, error) {
+	err := db.Use(NewInstrumentation(InstrumentationConfig{
+		SlowThreshold:   cfg.SlowQueryThreshold,
+		RepeatThreshold: cfg.RepeatedQueryThreshold,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register the query instrumentation: %w", err)
+	}
+
+	if err := db.Use(Auditor{}); err != nil {
+		return nil, fmt.Errorf("failed to register the audit log: %w", err)
+	}
+	if cfg.StatementTimeout > 0 {
+		if err := db.Use(StatementTimeout{Timeout: cfg.StatementTimeout}); err != nil {
+			return nil, fmt.Errorf("failed to register the statement timeout: %w", err)
+		}
+	}
+	return db, nil
+}
@@ -2,6 +2,11 @@ package storage
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
 )
 
 var (
@@ -12,4 +17,120 @@ var (
 	// This typically occurs when trying to insert a record with a unique key constraint
 	// that conflicts with an existing entry.
 	ErrDuplicateKey = errors.New("record already exist")
+
+	// ErrStaleObject is returned when updating a versioned record whose version has been changed
+	// by someone else since it was read.
+	ErrStaleObject = errors.New("record has been modified by another transaction")
 )
+
+// ConstraintKind is the kind of integrity constraint violated by a write.
+type ConstraintKind string
+
+const (
+	ConstraintUnique     ConstraintKind = "unique"
+	ConstraintForeignKey ConstraintKind = "foreign key"
+	ConstraintCheck      ConstraintKind = "check"
+	ConstraintNotNull    ConstraintKind = "not null"
+)
+
+// The SQLSTATE of the postgres integrity constraint violations.
+var pgConstraintKinds = map[string]ConstraintKind{
+	"23505": ConstraintUnique,
+	"23503": ConstraintForeignKey,
+	"23514": ConstraintCheck,
+	"23502": ConstraintNotNull,
+}
+
+// ConstraintError is returned when a write violates an integrity constraint of the database. Constraint is the
+// name of the constraint, empty for the not null ones, and Column the column holding the offending value, the last
+// one for the constraints over several columns. A unique violation is also ErrDuplicateKey.
+type ConstraintError struct {
+	Kind       ConstraintKind
+	Constraint string
+	Table      string
+	Column     string
+	// Err is the error of the driver.
+	Err error
+}
+
+func (e *ConstraintError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s constraint", e.Kind)
+	if e.Constraint != "" {
+		fmt.Fprintf(&b, " %s", e.Constraint)
+	}
+	b.WriteString(" violated")
+	if e.Column != "" {
+		fmt.Fprintf(&b, " by %s.%s", e.Table, e.Column)
+	}
+	return b.String()
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes the unique violations match ErrDuplicateKey and gorm.ErrDuplicatedKey, and the foreign key violations
+// match gorm.ErrForeignKeyViolated, like the errors translated by GORM do.
+func (e *ConstraintError) Is(target error) bool {
+	switch e.Kind {
+	case ConstraintUnique:
+		return target == ErrDuplicateKey || target == gorm.ErrDuplicatedKey
+	case ConstraintForeignKey:
+		return target == gorm.ErrForeignKeyViolated
+	}
+	return false
+}
+
+// pgConstraintError converts a postgres integrity constraint violation into a ConstraintError, it returns nil for
+// the other errors.
+func pgConstraintError(err error) *ConstraintError {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+	kind, ok := pgConstraintKinds[pgErr.Code]
+	if !ok {
+		return nil
+	}
+	column := pgErr.ColumnName
+	if column == "" {
+		column = detailColumn(pgErr.Detail)
+	}
+	return &ConstraintError{
+		Kind:       kind,
+		Constraint: pgErr.ConstraintName,
+		Table:      pgErr.TableName,
+		Column:     column,
+		Err:        err,
+	}
+}
+
+// detailColumn returns the last column of the key described by the detail of a unique or foreign key violation,
+// such as "Key (tenant_id, email)=(default, a@test.com) already exists.".
+func detailColumn(detail string) string {
+	columns, ok := strings.CutPrefix(detail, "Key (")
+	if !ok {
+		return ""
+	}
+	columns, _, ok = strings.Cut(columns, ")=(")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(columns, ",")
+	return strings.Trim(strings.TrimSpace(parts[len(parts)-1]), `"`)
+}
+
+// translateError converts the GORM errors into the errors of this package.
+func translateError(err error) error {
+	var constraintErr *ConstraintError
+	switch {
+	case errors.As(err, &constraintErr):
+		return constraintErr
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicateKey
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrRecordNotFound
+	}
+	return err
+}
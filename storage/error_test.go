@@ -0,0 +1,59 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestConstraintError(t *testing.T) {
+	dialector := storage.NewPostgresDialector(postgres.Config{DSN: "host=localhost"})
+
+	err := dialector.Translate(&pgconn.PgError{
+		Code:           "23505",
+		ConstraintName: "users_tenant_id_email_key",
+		TableName:      "users",
+		Detail:         "Key (tenant_id, email)=(default, a@test.com) already exists.",
+	})
+	var ce *storage.ConstraintError
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, storage.ConstraintUnique, ce.Kind)
+	assert.Equal(t, "users_tenant_id_email_key", ce.Constraint)
+	assert.Equal(t, "users", ce.Table)
+	assert.Equal(t, "email", ce.Column)
+	assert.ErrorIs(t, err, storage.ErrDuplicateKey)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	assert.Equal(t, "unique constraint users_tenant_id_email_key violated by users.email", err.Error())
+
+	err = dialector.Translate(&pgconn.PgError{
+		Code:           "23503",
+		ConstraintName: "fk_product",
+		TableName:      "order_items",
+		Detail:         `Key (product_id)=(4c1a1b2e-5f2c-4c47-a1a5-4f5e2b1c9d7e) is not present in table "products".`,
+	})
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, storage.ConstraintForeignKey, ce.Kind)
+	assert.Equal(t, "product_id", ce.Column)
+	assert.ErrorIs(t, err, gorm.ErrForeignKeyViolated)
+	assert.NotErrorIs(t, err, storage.ErrDuplicateKey)
+
+	err = dialector.Translate(&pgconn.PgError{Code: "23502", TableName: "products", ColumnName: "name"})
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, storage.ConstraintNotNull, ce.Kind)
+	assert.Equal(t, "name", ce.Column)
+	assert.Equal(t, "not null constraint violated by products.name", err.Error())
+
+	err = dialector.Translate(&pgconn.PgError{Code: "23514", ConstraintName: "products_quantity_check", TableName: "products"})
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, storage.ConstraintCheck, ce.Kind)
+	assert.Equal(t, "check constraint products_quantity_check violated", err.Error())
+
+	// the other errors are left to GORM
+	other := &pgconn.PgError{Code: "40001"}
+	assert.False(t, errors.As(dialector.Translate(other), &ce))
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"gorm.io/gorm"
+)
+
+const (
+	instrumentationName     = "storage:instrumentation"
+	instrumentationStartKey = "storage:instrumentation_start"
+)
+
+// InstrumentationConfig configures the query instrumentation plugin.
+type InstrumentationConfig struct {
+	// SlowThreshold is the duration above which a query is logged as slow, zero disables the slow query log.
+	SlowThreshold time.Duration
+	// RepeatThreshold is the number of times the same query can run for a tracked context, usually an HTTP
+	// request, before it is reported as a possible N+1 problem. Zero disables the detection.
+	RepeatThreshold int
+	// Logger receives the slow and repeated query warnings, slog.Default is used when nil.
+	Logger *slog.Logger
+}
+
+// QueryStats are the aggregated metrics of the queries running one operation on one table.
+type QueryStats struct {
+	Table     string  `json:"table"`
+	Operation string  `json:"operation"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	Slow      int64   `json:"slow"`
+	Rows      int64   `json:"rows"`
+	TotalMs   float64 `json:"totalMs"`
+	MaxMs     float64 `json:"maxMs"`
+}
+
+type statsKey struct {
+	table, operation string
+}
+
+// Instrumentation is a GORM plugin measuring every statement: its duration, table, operation and number of rows.
+// It logs the slow queries with the method that issued them and warns when a tracked context, see TrackQueries,
+// runs the same query more than the configured number of times.
+// It is registered by NewPostgreStorage and can be retrieved with InstrumentationOf.
+type Instrumentation struct {
+	cfg InstrumentationConfig
+
+	mu    sync.Mutex
+	stats map[statsKey]*QueryStats
+}
+
+var _ gorm.Plugin = &Instrumentation{}
+
+// NewInstrumentation creates the query instrumentation plugin, it must be registered with gorm.DB.Use.
+func NewInstrumentation(cfg InstrumentationConfig) *Instrumentation {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Instrumentation{
+		cfg:   cfg,
+		stats: make(map[statsKey]*QueryStats),
+	}
+}
+
+// InstrumentationOf returns the instrumentation plugin registered on the db.
+func InstrumentationOf(db *gorm.DB) (*Instrumentation, bool) {
+	p, ok := db.Config.Plugins[instrumentationName]
+	if !ok {
+		return nil, false
+	}
+	i, ok := p.(*Instrumentation)
+	return i, ok
+}
+
+// Name implements gorm.Plugin.
+func (i *Instrumentation) Name() string {
+	return instrumentationName
+}
+
+// Initialize implements gorm.Plugin, it registers the callbacks around every kind of statement.
+func (i *Instrumentation) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	before, after := instrumentationName+"_before", instrumentationName+"_after"
+	return errors.Join(
+		cb.Create().Before("*").Register(before, i.before),
+		cb.Create().After("*").Register(after, i.after("create")),
+		cb.Query().Before("*").Register(before, i.before),
+		cb.Query().After("*").Register(after, i.after("query")),
+		cb.Update().Before("*").Register(before, i.before),
+		cb.Update().After("*").Register(after, i.after("update")),
+		cb.Delete().Before("*").Register(before, i.before),
+		cb.Delete().After("*").Register(after, i.after("delete")),
+		cb.Row().Before("*").Register(before, i.before),
+		cb.Row().After("*").Register(after, i.after("row")),
+		cb.Raw().Before("*").Register(before, i.before),
+		cb.Raw().After("*").Register(after, i.after("raw")),
+	)
+}
+
+// Stats returns a snapshot of the metrics, sorted by table and operation.
+func (i *Instrumentation) Stats() []QueryStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats := make([]QueryStats, 0, len(i.stats))
+	for _, s := range i.stats {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b QueryStats) int {
+		return cmp.Or(cmp.Compare(a.Table, b.Table), cmp.Compare(a.Operation, b.Operation))
+	})
+	return stats
+}
+
+// Reset clears the metrics.
+func (i *Instrumentation) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	clear(i.stats)
+}
+
+func (i *Instrumentation) before(db *gorm.DB) {
+	db.InstanceSet(instrumentationStartKey, time.Now())
+}
+
+func (i *Instrumentation) after(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(instrumentationStartKey)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(v.(time.Time))
+		stmt := db.Statement
+		slow := i.cfg.SlowThreshold > 0 && elapsed > i.cfg.SlowThreshold
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		i.record(stmt.Table, op, elapsed, db.RowsAffected, failed, slow)
+
+		if slow {
+			i.cfg.Logger.Warn("slow query",
+				"duration", elapsed,
+				"table", stmt.Table,
+				"operation", op,
+				"rows", db.RowsAffected,
+				"caller", caller(),
+				"sql", stmt.SQL.String(),
+			)
+		}
+		if t, ok := stmt.Context.Value(queryTrackerKey{}).(*queryTracker); ok && i.cfg.RepeatThreshold > 0 {
+			if n := t.add(stmt.SQL.String()); n == i.cfg.RepeatThreshold+1 {
+				i.cfg.Logger.Warn("repeated query, possible N+1",
+					"scope", t.scope,
+					"count", n,
+					"table", stmt.Table,
+					"caller", caller(),
+					"sql", stmt.SQL.String(),
+				)
+			}
+		}
+	}
+}
+
+func (i *Instrumentation) record(table, op string, elapsed time.Duration, rows int64, failed, slow bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	key := statsKey{table: table, operation: op}
+	s, ok := i.stats[key]
+	if !ok {
+		s = &QueryStats{Table: table, Operation: op}
+		i.stats[key] = s
+	}
+	ms := float64(elapsed) / float64(time.Millisecond)
+	s.Count++
+	s.Rows += max(rows, 0)
+	s.TotalMs += ms
+	s.MaxMs = max(s.MaxMs, ms)
+	if failed {
+		s.Errors++
+	}
+	if slow {
+		s.Slow++
+	}
+}
+
+// storagePackage is the import path of this package, its frames are skipped when looking for the caller.
+var storagePackage = reflect.TypeOf(Config{}).PkgPath() + "."
+
+// caller returns the first function of the call stack outside of GORM and of this package, which is the
+// repository method or the handler that issued the query.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "gorm.io/") && !strings.HasPrefix(f.Function, storagePackage) {
+			name := f.Function
+			if i := strings.LastIndex(name, "/"); i >= 0 {
+				name = name[i+1:]
+			}
+			return fmt.Sprintf("%s (%s:%d)", name, filepath.Base(f.File), f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+type queryTrackerKey struct{}
+
+// queryTracker counts the queries run with a context by SQL shape, the SQL with placeholders for the values.
+type queryTracker struct {
+	scope  string
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (t *queryTracker) add(sql string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts[sql]++
+	return t.counts[sql]
+}
+
+// TrackQueries returns a context counting the queries run with it, so the instrumentation can report the ones
+// repeated more than its RepeatThreshold. The scope names the unit of work in the warnings, like an HTTP route.
+func TrackQueries(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, queryTrackerKey{}, &queryTracker{scope: scope, counts: make(map[string]int)})
+}
@@ -0,0 +1,84 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zechao158/ecomm/storage"
+	"golang.org/x/exp/slog"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestInstrumentation(t *testing.T) {
+	ctx := context.Background()
+	newDB := func(cfg storage.InstrumentationConfig) (*gorm.DB, *storage.Instrumentation, *bytes.Buffer) {
+		db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+			DryRun:                 true,
+			DisableAutomaticPing:   true,
+			SkipDefaultTransaction: true,
+		})
+		assert.NoError(t, err)
+		var logs bytes.Buffer
+		cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+		assert.NoError(t, db.Use(storage.NewInstrumentation(cfg)))
+		inst, ok := storage.InstrumentationOf(db)
+		assert.True(t, ok)
+		return db, inst, &logs
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		db, inst, logs := newDB(storage.InstrumentationConfig{})
+		store := storage.New[TestModel](db)
+		assert.NoError(t, store.Create(ctx, &TestModel{ID: uuid.New()}))
+		_, err := store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		_, err = store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+
+		stats := inst.Stats()
+		assert.Len(t, stats, 2)
+		assert.Equal(t, "test_models", stats[0].Table)
+		assert.Equal(t, "create", stats[0].Operation)
+		assert.Equal(t, int64(1), stats[0].Count)
+		assert.Equal(t, "query", stats[1].Operation)
+		assert.Equal(t, int64(2), stats[1].Count)
+		assert.Empty(t, logs.String())
+
+		inst.Reset()
+		assert.Empty(t, inst.Stats())
+	})
+
+	t.Run("slow query", func(t *testing.T) {
+		db, inst, logs := newDB(storage.InstrumentationConfig{SlowThreshold: 1})
+		_, err := storage.New[TestModel](db).GetAll(ctx, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(1), inst.Stats()[0].Slow)
+		assert.Contains(t, logs.String(), "slow query")
+		assert.Contains(t, logs.String(), "storage_test.TestInstrumentation")
+		assert.Contains(t, logs.String(), "test_models")
+	})
+
+	t.Run("repeated query", func(t *testing.T) {
+		db, _, logs := newDB(storage.InstrumentationConfig{RepeatThreshold: 2})
+		store := storage.New[TestModel](db)
+
+		// the dry run doesn't return any row, only the SQL matters.
+		// untracked contexts are never reported
+		for range 3 {
+			_, _ = store.GetByID(ctx, uuid.New(), false)
+		}
+		assert.Empty(t, logs.String())
+
+		tracked := storage.TrackQueries(ctx, "GET /products")
+		for range 4 {
+			_, _ = store.GetByID(tracked, uuid.New(), false)
+		}
+		assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("possible N+1")))
+		assert.Contains(t, logs.String(), "GET /products")
+	})
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLockNotAcquired is returned by the try variants of the advisory locks when the lock is held by another session.
+var ErrLockNotAcquired = errors.New("lock not acquired")
+
+// ErrLockLost is the cause of the cancellation of the context of a Lock whose connection was lost, the database
+// released the lock with the session.
+var ErrLockLost = errors.New("lock lost")
+
+// errLockReleased is the cause of the cancellation of the context of a released Lock.
+var errLockReleased = errors.New("lock released")
+
+// lockKeepAlive is the interval at which a Lock checks that its connection is still alive.
+const lockKeepAlive = time.Second
+
+// LockKey returns the key of the postgres advisory lock named name. Advisory locks are keyed by a bigint, so
+// every instance must derive it from the name the same way.
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Lock is a session-level postgres advisory lock, it is held by a dedicated connection until it is released or
+// the connection is lost. The instances of the API use it to coordinate, for instance to run a scheduled job on
+// one of them only.
+type Lock struct {
+	name    string
+	key     int64
+	conn    *sql.Conn
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	done    chan struct{}
+	release sync.Once
+	err     error
+}
+
+// AcquireLock takes the advisory lock named name, waiting until it is released by the session holding it or
+// until ctx is done.
+func AcquireLock(ctx context.Context, db *gorm.DB, name string) (*Lock, error) {
+	return acquireLock(ctx, db, name, "SELECT true FROM pg_advisory_lock($1)")
+}
+
+// TryAcquireLock takes the advisory lock named name if it is free, it returns ErrLockNotAcquired otherwise.
+func TryAcquireLock(ctx context.Context, db *gorm.DB, name string) (*Lock, error) {
+	return acquireLock(ctx, db, name, "SELECT pg_try_advisory_lock($1)")
+}
+
+func acquireLock(ctx context.Context, db *gorm.DB, name, query string) (*Lock, error) {
+	if err := requirePostgres(db); err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// session locks belong to the connection, which must not go back to the pool while the lock is held
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection for lock %s: %w", name, err)
+	}
+	key := LockKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, query, key).Scan(&acquired); err != nil {
+		// the lock may have been granted right before the cancellation, discarding the connection releases it
+		discard(conn)
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, errors.Join(err, ctx.Err()))
+	}
+	if !acquired {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s", ErrLockNotAcquired, name)
+	}
+
+	l := &Lock{name: name, key: key, conn: conn, done: make(chan struct{})}
+	l.ctx, l.cancel = context.WithCancelCause(context.WithoutCancel(ctx))
+	go l.keepAlive()
+	return l, nil
+}
+
+// Name returns the name of the lock.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Context returns a context that is canceled when the lock is released or lost, the work guarded by the lock
+// should run with it. context.Cause returns ErrLockLost when the connection holding the lock was lost.
+func (l *Lock) Context() context.Context {
+	return l.ctx
+}
+
+// keepAlive pings the connection of the lock until it is released, and cancels the context of the lock when
+// the connection is lost.
+func (l *Lock) keepAlive() {
+	ticker := time.NewTicker(lockKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			if err := l.conn.PingContext(l.ctx); err != nil && l.ctx.Err() == nil {
+				l.cancel(fmt.Errorf("%w: %s: %w", ErrLockLost, l.name, err))
+				return
+			}
+		}
+	}
+}
+
+// Release releases the lock and returns its connection to the pool, it is safe to call several times.
+func (l *Lock) Release(ctx context.Context) error {
+	l.release.Do(func() {
+		l.cancel(errLockReleased)
+		close(l.done)
+		if err := context.Cause(l.ctx); errors.Is(err, ErrLockLost) {
+			// the database released the lock when the session ended
+			discard(l.conn)
+			l.err = err
+			return
+		}
+		var released bool
+		err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&released)
+		if err == nil && !released {
+			err = fmt.Errorf("lock %s was not held", l.name)
+		}
+		if err != nil {
+			discard(l.conn)
+			l.err = fmt.Errorf("failed to release lock %s: %w", l.name, err)
+			return
+		}
+		l.err = l.conn.Close()
+	})
+	return l.err
+}
+
+// RunLocked runs fn while holding the advisory lock named name, it returns ErrLockNotAcquired without running
+// fn when another session holds the lock. fn gets the context of the Lock, canceled if the lock is lost.
+func RunLocked(ctx context.Context, db *gorm.DB, name string, fn func(ctx context.Context) error) error {
+	l, err := TryAcquireLock(ctx, db, name)
+	if err != nil {
+		return err
+	}
+	lockCtx, cancel := context.WithCancel(l.Context())
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	err = fn(lockCtx)
+	return errors.Join(err, l.Release(context.WithoutCancel(ctx)))
+}
+
+// LockTx takes the transaction-level advisory lock named name, waiting until it is released by the session
+// holding it or until ctx is done. The lock is released when tx commits or rolls back.
+func LockTx(ctx context.Context, tx *gorm.DB, name string) error {
+	if err := requireLockTx(tx); err != nil {
+		return err
+	}
+	if err := tx.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(?)", LockKey(name)).Error; err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// TryLockTx takes the transaction-level advisory lock named name if it is free, it returns ErrLockNotAcquired
+// otherwise. The lock is released when tx commits or rolls back.
+func TryLockTx(ctx context.Context, tx *gorm.DB, name string) error {
+	if err := requireLockTx(tx); err != nil {
+		return err
+	}
+	var acquired bool
+	if err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", LockKey(name)).Scan(&acquired).Error; err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !acquired {
+		return fmt.Errorf("%w: %s", ErrLockNotAcquired, name)
+	}
+	return nil
+}
+
+func requireLockTx(tx *gorm.DB) error {
+	if err := requirePostgres(tx); err != nil {
+		return err
+	}
+	if !inTransaction(tx) {
+		return errors.New("transaction-level locks need a transaction")
+	}
+	return nil
+}
+
+func requirePostgres(db *gorm.DB) error {
+	if name := db.Dialector.Name(); name != DriverPostgres {
+		return fmt.Errorf("advisory locks are not supported by %s", name)
+	}
+	return nil
+}
+
+// discard closes conn instead of returning it to the pool.
+func discard(conn *sql.Conn) {
+	conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
@@ -0,0 +1,159 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+	dbURL := runPostgres(t)
+	// every instance of the API has its own pool
+	open := func() *gorm.DB {
+		db, err := gorm.Open(storage.NewPostgresDialector(postgres.Config{DSN: dbURL}), &gorm.Config{})
+		require.NoError(t, err)
+		return db
+	}
+	instance1, instance2 := open(), open()
+
+	t.Run("TryAcquireLock", func(t *testing.T) {
+		l, err := storage.TryAcquireLock(ctx, instance1, "try")
+		require.NoError(t, err)
+
+		_, err = storage.TryAcquireLock(ctx, instance2, "try")
+		assert.ErrorIs(t, err, storage.ErrLockNotAcquired)
+		// the locks are independent
+		other, err := storage.TryAcquireLock(ctx, instance2, "other")
+		assert.NoError(t, err)
+		assert.NoError(t, other.Release(ctx))
+
+		assert.NoError(t, l.Release(ctx))
+		assert.NoError(t, l.Release(ctx))
+		assert.ErrorIs(t, context.Cause(l.Context()), context.Canceled)
+		l, err = storage.TryAcquireLock(ctx, instance2, "try")
+		assert.NoError(t, err)
+		assert.NoError(t, l.Release(ctx))
+	})
+
+	t.Run("AcquireLock waits for the release", func(t *testing.T) {
+		l, err := storage.AcquireLock(ctx, instance1, "wait")
+		require.NoError(t, err)
+
+		acquired := make(chan *storage.Lock)
+		go func() {
+			l, err := storage.AcquireLock(ctx, instance2, "wait")
+			assert.NoError(t, err)
+			acquired <- l
+		}()
+		select {
+		case <-acquired:
+			t.Fatal("the lock was acquired twice")
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		assert.NoError(t, l.Release(ctx))
+		select {
+		case l := <-acquired:
+			assert.NoError(t, l.Release(ctx))
+		case <-time.After(5 * time.Second):
+			t.Fatal("the lock wasn't acquired after its release")
+		}
+	})
+
+	t.Run("AcquireLock stops waiting when the context is done", func(t *testing.T) {
+		l, err := storage.AcquireLock(ctx, instance1, "canceled")
+		require.NoError(t, err)
+		defer l.Release(ctx)
+
+		ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		_, err = storage.AcquireLock(ctx, instance2, "canceled")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("the lock is released when the connection is lost", func(t *testing.T) {
+		l, err := storage.AcquireLock(ctx, instance1, "lost")
+		require.NoError(t, err)
+
+		assert.NoError(t, instance2.Exec(
+			"SELECT pg_terminate_backend(pid) FROM pg_locks WHERE locktype = 'advisory' AND pid <> pg_backend_pid()",
+		).Error)
+		select {
+		case <-l.Context().Done():
+			assert.ErrorIs(t, context.Cause(l.Context()), storage.ErrLockLost)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the loss of the connection wasn't detected")
+		}
+		assert.ErrorIs(t, l.Release(ctx), storage.ErrLockLost)
+
+		l, err = storage.TryAcquireLock(ctx, instance2, "lost")
+		assert.NoError(t, err)
+		assert.NoError(t, l.Release(ctx))
+		// the pool of the instance is still usable
+		assert.NoError(t, instance1.Exec("SELECT 1").Error)
+	})
+
+	t.Run("RunLocked", func(t *testing.T) {
+		runs := 0
+		err := storage.RunLocked(ctx, instance1, "job", func(ctx context.Context) error {
+			runs++
+			return storage.RunLocked(ctx, instance2, "job", func(context.Context) error {
+				runs++
+				return nil
+			})
+		})
+		assert.ErrorIs(t, err, storage.ErrLockNotAcquired)
+		assert.Equal(t, 1, runs)
+
+		failure := errors.New("failure")
+		err = storage.RunLocked(ctx, instance2, "job", func(context.Context) error {
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+	})
+
+	t.Run("LockTx and TryLockTx", func(t *testing.T) {
+		tx := instance1.Begin()
+		require.NoError(t, storage.LockTx(ctx, tx, "tx"))
+		assert.NoError(t, storage.TryLockTx(ctx, tx, "tx"), "the locks are reentrant")
+
+		other := instance2.Begin()
+		assert.ErrorIs(t, storage.TryLockTx(ctx, other, "tx"), storage.ErrLockNotAcquired)
+		// the failed try doesn't abort the transaction
+		assert.NoError(t, other.Exec("SELECT 1").Error)
+		_, err := storage.TryAcquireLock(ctx, instance2, "tx")
+		assert.ErrorIs(t, err, storage.ErrLockNotAcquired)
+
+		assert.NoError(t, tx.Commit().Error)
+		assert.NoError(t, storage.LockTx(ctx, other, "tx"))
+		assert.NoError(t, other.Rollback().Error)
+
+		assert.Error(t, storage.LockTx(ctx, instance1, "tx"), "a transaction is required")
+	})
+}
+
+func TestAdvisoryLockRequiresPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewStorage(storage.Config{
+		Driver: storage.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+
+	_, err = storage.TryAcquireLock(ctx, db, "job")
+	assert.Error(t, err)
+	assert.Error(t, db.Transaction(func(tx *gorm.DB) error {
+		return storage.LockTx(ctx, tx, "job")
+	}))
+	assert.Equal(t, storage.LockKey("job"), storage.LockKey("job"))
+	assert.NotEqual(t, storage.LockKey("job"), storage.LockKey("other"))
+}
@@ -0,0 +1,944 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrNotSupported is returned by the in-memory store for the operations that only make sense against a
+// database, such as raw SQLModifier closures or SQL expressions.
+var ErrNotSupported = errors.New("operation not supported by the in-memory store")
+
+// MemoryStore is an in-memory implementation of CRUDStorer, it is meant to be used in unit tests.
+//
+// Rows are keyed by the primary key of T, columns and fields are resolved with the GORM naming
+// conventions so Query, Predicate and PageRequest behave as they do against the database.
+// Stored values are copied on every read and write so callers can't mutate them by accident.
+// Changes are not audited, the audit log only exists in the database.
+// Tenant aware entities are isolated like they are by CRUDStore, and their unique columns are unique per tenant.
+// It is safe for concurrent use.
+type MemoryStore[T any] struct {
+	mu     sync.RWMutex
+	schema *schema.Schema
+	pk     *schema.Field
+	unique []*schema.Field
+	// deletedAt is the soft delete field of T, nil if T is not soft deletable.
+	deletedAt *schema.Field
+	// tenant is the tenant field of T, nil if T is not tenant aware.
+	tenant *schema.Field
+	rows   map[any]T
+	// keys keeps the insertion order so reads are deterministic.
+	keys []any
+}
+
+var _ CRUDStorer[struct{ ID uuid.UUID }] = &MemoryStore[struct{ ID uuid.UUID }]{}
+
+// NewMemory creates an empty in-memory CRUDStorer for the given type T. Rows are keyed by the primary key of T,
+// usually its ID field, and unique lists the extra columns that must be unique, like a unique index would.
+// It panics if T can't be parsed as a GORM model or has no primary key.
+func NewMemory[T any](unique ...string) *MemoryStore[T] {
+	s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("storage: invalid memory store model: %v", err))
+	}
+	if s.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("storage: memory store model %s has no primary key", s.Name))
+	}
+	m := &MemoryStore[T]{
+		schema:    s,
+		pk:        s.PrioritizedPrimaryField,
+		deletedAt: deletedAtField(s),
+		tenant:    tenantField(s),
+		rows:      make(map[any]T),
+	}
+	for _, col := range unique {
+		f := s.LookUpField(col)
+		if f == nil {
+			panic(fmt.Sprintf("storage: memory store model %s has no field %s", s.Name, col))
+		}
+		m.unique = append(m.unique, f)
+	}
+	return m
+}
+
+// GetAll implements CRUDStorer, raw SQLModifier closures can't be evaluated in memory so only a nil modifier is supported.
+func (m *MemoryStore[T]) GetAll(ctx context.Context, mod SQLModifier) ([]T, error) {
+	if mod != nil {
+		return nil, ErrNotSupported
+	}
+	return m.Find(ctx, Query{})
+}
+
+// Stream implements CRUDStorer, like GetAll only a nil modifier is supported. The records are read from a snapshot
+// taken when the iteration starts, in primary key order.
+func (m *MemoryStore[T]) Stream(ctx context.Context, mod SQLModifier, batchSize int) iter.Seq2[T, error] {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		if mod != nil {
+			yield(zero, ErrNotSupported)
+			return
+		}
+		results, err := m.Find(ctx, Query{Order: []Order{{Field: m.pk.DBName}}})
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for i, t := range results {
+			if i%batchSize == 0 && ctx.Err() != nil {
+				yield(zero, ctx.Err())
+				return
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Find implements CRUDStorer, the query is evaluated in memory. Locking is ignored.
+func (m *MemoryStore[T]) Find(ctx context.Context, q Query) ([]T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results, err := m.filter(ctx, q.Where)
+	if err != nil {
+		return nil, err
+	}
+	if len(q.Order) > 0 {
+		fields := make([]*schema.Field, len(q.Order))
+		for i, o := range q.Order {
+			if fields[i] = m.schema.LookUpField(o.Field); fields[i] == nil {
+				return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, o.Field)
+			}
+		}
+		slices.SortStableFunc(results, func(a, b T) int {
+			for i, o := range q.Order {
+				c := compareValues(m.value(ctx, fields[i], a), m.value(ctx, fields[i], b))
+				if o.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+	if q.Offset > 0 {
+		results = results[min(q.Offset, len(results)):]
+	}
+	if q.Limit > 0 && q.Limit < len(results) {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+// GetPage implements CRUDStorer.
+func (m *MemoryStore[T]) GetPage(ctx context.Context, p PageRequest, filter Predicate) (*Page[T], error) {
+	plan, err := newPagePlan(m.schema, p)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	results, err := m.filter(ctx, filter)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	compareKeys := func(av, aid, bv, bid any) int {
+		c := compareValues(av, bv)
+		if c == 0 {
+			c = compareValues(aid, bid)
+		}
+		if plan.desc {
+			c = -c
+		}
+		return c
+	}
+	slices.SortFunc(results, func(a, b T) int {
+		return compareKeys(m.value(ctx, plan.sortField, a), m.value(ctx, plan.pk, a), m.value(ctx, plan.sortField, b), m.value(ctx, plan.pk, b))
+	})
+	if plan.after != nil {
+		i := slices.IndexFunc(results, func(t T) bool {
+			return compareKeys(m.value(ctx, plan.sortField, t), m.value(ctx, plan.pk, t), plan.after.value, plan.after.id) > 0
+		})
+		if i < 0 {
+			i = len(results)
+		}
+		results = results[i:]
+	}
+	if len(results) > plan.limit+1 {
+		results = results[:plan.limit+1]
+	}
+	return buildPage(ctx, plan, results)
+}
+
+// GetByID implements CRUDStorer, forUpdate is ignored.
+func (m *MemoryStore[T]) GetByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.rows[id]
+	if !ok || !m.visible(ctx, t) {
+		return nil, ErrRecordNotFound
+	}
+	t = clone(t)
+	return &t, nil
+}
+
+// GetByFields implements CRUDStorer, the values are compared with the string representation of the fields.
+// forUpdate is ignored.
+func (m *MemoryStore[T]) GetByFields(ctx context.Context, fields map[string]string, forUpdate bool) (*T, error) {
+	ps := make([]Predicate, 0, len(fields))
+	for k, v := range fields {
+		ps = append(ps, Eq(k, v))
+	}
+	results, err := m.Find(ctx, Query{Where: And(ps...), Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return &results[0], nil
+}
+
+// Delete implements CRUDStorer, soft deletable rows are only marked as deleted.
+func (m *MemoryStore[T]) Delete(ctx context.Context, t *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := m.key(ctx, *t)
+	stored, ok := m.rows[key]
+	if !ok || !m.inTenant(ctx, stored) {
+		return nil
+	}
+	if m.deletedAt != nil {
+		if m.isDeleted(ctx, stored) {
+			return nil
+		}
+		return m.setDeletedAt(ctx, key, gorm.DeletedAt{Time: time.Now(), Valid: true})
+	}
+	m.remove(key)
+	return nil
+}
+
+// Restore implements CRUDStorer.
+func (m *MemoryStore[T]) Restore(ctx context.Context, id uuid.UUID) error {
+	if m.deletedAt == nil {
+		return ErrNotSoftDeletable
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.rows[id]
+	if !ok || !m.inTenant(ctx, stored) || !m.isDeleted(ctx, stored) {
+		return ErrRecordNotFound
+	}
+	return m.setDeletedAt(ctx, id, gorm.DeletedAt{})
+}
+
+// Purge implements CRUDStorer.
+func (m *MemoryStore[T]) Purge(ctx context.Context, t *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := m.key(ctx, *t)
+	if stored, ok := m.rows[key]; ok && m.inTenant(ctx, stored) {
+		m.remove(key)
+	}
+	return nil
+}
+
+// Create implements CRUDStorer, it returns ErrDuplicateKey if the primary key or a unique column is already used.
+func (m *MemoryStore[T]) Create(ctx context.Context, t *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.prepareCreate(ctx, t); err != nil {
+		return err
+	}
+	if err := m.checkUnique(ctx, *t, nil); err != nil {
+		return err
+	}
+	m.insert(ctx, *t)
+	return nil
+}
+
+// Update implements CRUDStorer, like CRUDStore it creates the row if it doesn't exist unless T is versioned.
+func (m *MemoryStore[T]) Update(ctx context.Context, t *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.stampTenant(ctx, t); err != nil {
+		return err
+	}
+	key := m.key(ctx, *t)
+	stored, exists := m.rows[key]
+	vf := versionField(m.schema)
+	if vf != nil && (!exists || !m.inTenant(ctx, stored)) {
+		return ErrRecordNotFound
+	}
+	if exists && !m.inTenant(ctx, stored) {
+		// the primary key is used by another tenant
+		return ErrDuplicateKey
+	}
+	if err := m.checkUnique(ctx, *t, key); err != nil {
+		return err
+	}
+	if vf != nil {
+		current := vf.ReflectValueOf(ctx, reflect.ValueOf(t).Elem()).Int()
+		if stored := vf.ReflectValueOf(ctx, reflect.ValueOf(&stored).Elem()).Int(); stored != current {
+			return fmt.Errorf("%w: version %d is outdated", ErrStaleObject, current)
+		}
+		if err := vf.Set(ctx, reflect.ValueOf(t).Elem(), current+1); err != nil {
+			return err
+		}
+	}
+	if err := m.touch(ctx, t); err != nil {
+		return err
+	}
+	if exists {
+		m.rows[key] = clone(*t)
+		return nil
+	}
+	m.insert(ctx, *t)
+	return nil
+}
+
+// CreateMany implements CRUDStorer, either all the records are created or none.
+func (m *MemoryStore[T]) CreateMany(ctx context.Context, ts []T, batchSize int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range ts {
+		if err := m.prepareCreate(ctx, &ts[i]); err != nil {
+			return 0, err
+		}
+		if err := m.checkUnique(ctx, ts[i], nil); err != nil {
+			return 0, err
+		}
+		for j := range i {
+			if m.key(ctx, ts[i]) == m.key(ctx, ts[j]) {
+				return 0, ErrDuplicateKey
+			}
+			for _, f := range m.unique {
+				if m.conflicts(ctx, m.uniqueKey(f), ts[i], ts[j]) {
+					return 0, ErrDuplicateKey
+				}
+			}
+		}
+	}
+	for _, t := range ts {
+		m.insert(ctx, t)
+	}
+	return int64(len(ts)), nil
+}
+
+// UpsertMany implements CRUDStorer.
+func (m *MemoryStore[T]) UpsertMany(ctx context.Context, ts []T, opts UpsertOptions) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conflict := []*schema.Field{m.pk}
+	if len(opts.ConflictColumns) > 0 {
+		conflict = conflict[:0]
+		for _, col := range opts.ConflictColumns {
+			f := m.schema.LookUpField(col)
+			if f == nil {
+				return 0, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, col)
+			}
+			conflict = append(conflict, f)
+		}
+	}
+	updates := make([]*schema.Field, len(opts.UpdateColumns))
+	for i, col := range opts.UpdateColumns {
+		if updates[i] = m.schema.LookUpField(col); updates[i] == nil {
+			return 0, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, col)
+		}
+	}
+
+	var affected int64
+	for i := range ts {
+		if err := m.prepareCreate(ctx, &ts[i]); err != nil {
+			return affected, err
+		}
+		key := slices.IndexFunc(m.keys, func(key any) bool {
+			return m.conflicts(ctx, conflict, m.rows[key], ts[i])
+		})
+		if key < 0 {
+			if err := m.checkUnique(ctx, ts[i], nil); err != nil {
+				return affected, err
+			}
+			m.insert(ctx, ts[i])
+			affected++
+			continue
+		}
+		stored := m.rows[m.keys[key]]
+		if len(updates) == 0 || !m.inTenant(ctx, stored) {
+			continue
+		}
+		rv, src := reflect.ValueOf(&stored).Elem(), reflect.ValueOf(ts[i])
+		for _, f := range updates {
+			if err := f.Set(ctx, rv, f.ReflectValueOf(ctx, src).Interface()); err != nil {
+				return affected, err
+			}
+		}
+		m.rows[m.keys[key]] = clone(stored)
+		affected++
+	}
+	return affected, nil
+}
+
+// UpdateWhere implements CRUDStorer, only plain values are supported, SQL expressions return ErrNotSupported.
+func (m *MemoryStore[T]) UpdateWhere(ctx context.Context, filter Predicate, values map[string]any) (int64, error) {
+	if _, err := filterExpression(filter); err != nil {
+		return 0, err
+	}
+	fields := make(map[*schema.Field]any, len(values))
+	for col, v := range values {
+		if _, ok := v.(clause.Expression); ok {
+			return 0, ErrNotSupported
+		}
+		f := m.schema.LookUpField(col)
+		if f == nil {
+			return 0, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, col)
+		}
+		fields[f] = v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var affected int64
+	for _, key := range m.keys {
+		t := m.rows[key]
+		if m.isDeleted(ctx, t) || !m.inTenant(ctx, t) {
+			continue
+		}
+		ok, err := m.match(ctx, filter, t)
+		if err != nil {
+			return affected, err
+		}
+		if !ok {
+			continue
+		}
+		rv := reflect.ValueOf(&t).Elem()
+		for f, v := range fields {
+			if err := f.Set(ctx, rv, v); err != nil {
+				return affected, err
+			}
+		}
+		if vf := versionField(m.schema); vf != nil {
+			if err := vf.Set(ctx, rv, vf.ReflectValueOf(ctx, rv).Int()+1); err != nil {
+				return affected, err
+			}
+		}
+		if err := m.touch(ctx, &t); err != nil {
+			return affected, err
+		}
+		m.rows[key] = clone(t)
+		affected++
+	}
+	return affected, nil
+}
+
+// DeleteWhere implements CRUDStorer.
+func (m *MemoryStore[T]) DeleteWhere(ctx context.Context, filter Predicate) (int64, error) {
+	if _, err := filterExpression(filter); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted []any
+	for _, key := range m.keys {
+		if m.isDeleted(ctx, m.rows[key]) || !m.inTenant(ctx, m.rows[key]) {
+			continue
+		}
+		ok, err := m.match(ctx, filter, m.rows[key])
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			deleted = append(deleted, key)
+		}
+	}
+	now := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	for _, key := range deleted {
+		if m.deletedAt == nil {
+			m.remove(key)
+			continue
+		}
+		if err := m.setDeletedAt(ctx, key, now); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(deleted)), nil
+}
+
+// Count implements CRUDStorer.
+func (m *MemoryStore[T]) Count(ctx context.Context, filter Predicate) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results, err := m.filter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(results)), nil
+}
+
+// Exists implements CRUDStorer.
+func (m *MemoryStore[T]) Exists(ctx context.Context, filter Predicate) (bool, error) {
+	n, err := m.Count(ctx, filter)
+	return n > 0, err
+}
+
+// Sum implements CRUDStorer.
+func (m *MemoryStore[T]) Sum(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return m.aggregate(ctx, aggregateSum, column, filter)
+}
+
+// Min implements CRUDStorer.
+func (m *MemoryStore[T]) Min(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return m.aggregate(ctx, aggregateMin, column, filter)
+}
+
+// Max implements CRUDStorer.
+func (m *MemoryStore[T]) Max(ctx context.Context, column string, filter Predicate) (float64, error) {
+	return m.aggregate(ctx, aggregateMax, column, filter)
+}
+
+// CountBy implements CRUDStorer.
+func (m *MemoryStore[T]) CountBy(ctx context.Context, column string, filter Predicate) (map[string]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, err := aggregateField(m.schema, column, false)
+	if err != nil {
+		return nil, err
+	}
+	results, err := m.filter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, t := range results {
+		key := ""
+		if v := indirect(m.value(ctx, f, t)); v != nil {
+			key = fmt.Sprint(v)
+		}
+		counts[key]++
+	}
+	return counts, nil
+}
+
+// aggregate applies fn to the column of the rows matching filter, NULL values are skipped like SQL does.
+func (m *MemoryStore[T]) aggregate(ctx context.Context, fn aggregate, column string, filter Predicate) (float64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, err := aggregateField(m.schema, column, true)
+	if err != nil {
+		return 0, err
+	}
+	results, err := m.filter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		acc   float64
+		found bool
+	)
+	for _, t := range results {
+		v, ok := toFloat(indirect(m.value(ctx, f, t)))
+		if !ok {
+			continue
+		}
+		switch {
+		case !found:
+			acc = v
+		case fn == aggregateSum:
+			acc += v
+		case fn == aggregateMin:
+			acc = min(acc, v)
+		case fn == aggregateMax:
+			acc = max(acc, v)
+		}
+		found = true
+	}
+	if !found && fn != aggregateSum {
+		return 0, ErrRecordNotFound
+	}
+	return acc, nil
+}
+
+// filter returns a copy of the rows matching the predicate, the caller must hold the lock.
+func (m *MemoryStore[T]) filter(ctx context.Context, p Predicate) ([]T, error) {
+	results := make([]T, 0, len(m.keys))
+	for _, key := range m.keys {
+		t := m.rows[key]
+		if !m.visible(ctx, t) {
+			continue
+		}
+		ok, err := m.match(ctx, p, t)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			results = append(results, clone(t))
+		}
+	}
+	return results, nil
+}
+
+// match evaluates the predicate against a row, following the SQL semantics for NULL values.
+func (m *MemoryStore[T]) match(ctx context.Context, p Predicate, t T) (bool, error) {
+	switch p := p.(type) {
+	case nil:
+		return true, nil
+	case logicalPredicate:
+		conditions := false
+		for _, pred := range p.Predicates {
+			if pred == nil {
+				continue
+			}
+			conditions = true
+			ok, err := m.match(ctx, pred, t)
+			if err != nil {
+				return false, err
+			}
+			if ok == p.Or {
+				return ok, nil
+			}
+		}
+		// an empty OR is no condition at all, like for the database.
+		return !p.Or || !conditions, nil
+	case fieldPredicate:
+		f := m.schema.LookUpField(p.Field)
+		if f == nil {
+			return false, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, p.Field)
+		}
+		v := m.value(ctx, f, t)
+		if p.Value == nil {
+			switch p.Op {
+			case OpEq:
+				return v == nil, nil
+			case OpNe:
+				return v != nil, nil
+			}
+			return false, nil
+		}
+		if v == nil {
+			return false, nil
+		}
+		switch p.Op {
+		case OpIn:
+			return slices.ContainsFunc(p.Value.([]any), func(x any) bool { return compareValues(v, x) == 0 }), nil
+		case OpLike:
+			return likeToRegexp(fmt.Sprint(p.Value)).MatchString(fmt.Sprint(v)), nil
+		}
+		c := compareValues(v, p.Value)
+		switch p.Op {
+		case OpNe:
+			return c != 0, nil
+		case OpGt:
+			return c > 0, nil
+		case OpGte:
+			return c >= 0, nil
+		case OpLt:
+			return c < 0, nil
+		case OpLte:
+			return c <= 0, nil
+		}
+		return c == 0, nil
+	}
+	return false, fmt.Errorf("%w: predicate %T", ErrNotSupported, p)
+}
+
+// value returns the value of a field of a row, nil pointers and unset deletion times are returned as nil.
+func (m *MemoryStore[T]) value(ctx context.Context, f *schema.Field, t T) any {
+	v := reflect.Indirect(f.ReflectValueOf(ctx, reflect.ValueOf(&t).Elem()))
+	if !v.IsValid() {
+		return nil
+	}
+	if d, ok := v.Interface().(gorm.DeletedAt); ok {
+		if !d.Valid {
+			return nil
+		}
+		return d.Time
+	}
+	return v.Interface()
+}
+
+// isDeleted reports whether the row is soft deleted.
+func (m *MemoryStore[T]) isDeleted(ctx context.Context, t T) bool {
+	return m.deletedAt != nil && m.value(ctx, m.deletedAt, t) != nil
+}
+
+// visible reports whether the row can be read with the given context.
+func (m *MemoryStore[T]) visible(ctx context.Context, t T) bool {
+	return m.inTenant(ctx, t) && (includeDeleted(ctx) || !m.isDeleted(ctx, t))
+}
+
+// inTenant reports whether the row belongs to the tenant of the context, rows are not scoped when T is not
+// tenant aware or the context has no tenant.
+func (m *MemoryStore[T]) inTenant(ctx context.Context, t T) bool {
+	if m.tenant == nil {
+		return true
+	}
+	tenant, ok := TenantFromContext(ctx)
+	return !ok || m.value(ctx, m.tenant, t) == tenant
+}
+
+// stampTenant sets the tenant of the context on t.
+func (m *MemoryStore[T]) stampTenant(ctx context.Context, t *T) error {
+	return stampTenant(ctx, m.schema, t)
+}
+
+// setDeletedAt sets the deletion time of a stored row, the caller must hold the lock.
+func (m *MemoryStore[T]) setDeletedAt(ctx context.Context, key any, d gorm.DeletedAt) error {
+	t := m.rows[key]
+	if err := m.deletedAt.Set(ctx, reflect.ValueOf(&t).Elem(), d); err != nil {
+		return err
+	}
+	m.rows[key] = t
+	return nil
+}
+
+func (m *MemoryStore[T]) key(ctx context.Context, t T) any {
+	return m.pk.ReflectValueOf(ctx, reflect.ValueOf(&t).Elem()).Interface()
+}
+
+// conflicts reports whether a and b have the same non null values for all the fields.
+func (m *MemoryStore[T]) conflicts(ctx context.Context, fields []*schema.Field, a, b T) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	for _, f := range fields {
+		av, bv := m.value(ctx, f, a), m.value(ctx, f, b)
+		if av == nil || bv == nil || compareValues(av, bv) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueKey returns the fields of the unique index of a unique column, which includes the tenant for the tenant
+// aware entities.
+func (m *MemoryStore[T]) uniqueKey(f *schema.Field) []*schema.Field {
+	if m.tenant == nil {
+		return []*schema.Field{f}
+	}
+	return []*schema.Field{m.tenant, f}
+}
+
+// checkUnique returns ErrDuplicateKey if t uses the primary key or a unique value of a stored row,
+// the row with the self key is ignored.
+func (m *MemoryStore[T]) checkUnique(ctx context.Context, t T, self any) error {
+	key := m.key(ctx, t)
+	if _, exists := m.rows[key]; exists && key != self {
+		return ErrDuplicateKey
+	}
+	for _, k := range m.keys {
+		if k == self {
+			continue
+		}
+		for _, f := range m.unique {
+			if m.conflicts(ctx, m.uniqueKey(f), m.rows[k], t) {
+				return ErrDuplicateKey
+			}
+		}
+	}
+	return nil
+}
+
+// prepareCreate sets the version, the tenant and the timestamps of a new row.
+func (m *MemoryStore[T]) prepareCreate(ctx context.Context, t *T) error {
+	if err := initVersion(ctx, m.schema, t); err != nil {
+		return err
+	}
+	if err := m.stampTenant(ctx, t); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(t).Elem()
+	now := time.Now()
+	for _, f := range m.schema.Fields {
+		if f.AutoCreateTime == 0 || !isTimeField(f) {
+			continue
+		}
+		if _, zero := f.ValueOf(ctx, rv); zero {
+			if err := f.Set(ctx, rv, now); err != nil {
+				return err
+			}
+		}
+	}
+	return m.touch(ctx, t)
+}
+
+// touch sets the update timestamps of a row.
+func (m *MemoryStore[T]) touch(ctx context.Context, t *T) error {
+	rv := reflect.ValueOf(t).Elem()
+	now := time.Now()
+	for _, f := range m.schema.Fields {
+		if f.AutoUpdateTime == 0 || !isTimeField(f) {
+			continue
+		}
+		if err := f.Set(ctx, rv, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore[T]) insert(ctx context.Context, t T) {
+	key := m.key(ctx, t)
+	m.rows[key] = clone(t)
+	m.keys = append(m.keys, key)
+}
+
+func (m *MemoryStore[T]) remove(key any) {
+	if _, ok := m.rows[key]; !ok {
+		return
+	}
+	delete(m.rows, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k any) bool { return k == key })
+}
+
+func isTimeField(f *schema.Field) bool {
+	t := f.FieldType
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t == reflect.TypeOf(time.Time{})
+}
+
+// compareValues compares two values of the same kind: numbers by value, times chronologically
+// and anything else by its string representation.
+func compareValues(a, b any) int {
+	a, b = indirect(a), indirect(b)
+	if an, ok := toFloat(a); ok {
+		if bn, ok := toFloat(b); ok {
+			return cmp.Compare(an, bn)
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func indirect(v any) any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		return v
+	}
+	if rv.IsNil() {
+		return nil
+	}
+	return rv.Elem().Interface()
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// likeToRegexp converts a SQL LIKE pattern into an anchored regular expression.
+func likeToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(")$")
+	return regexp.MustCompile(b.String())
+}
+
+// clone returns a deep copy of the exported fields of t, so the copy doesn't share pointers,
+// slices or maps with the original.
+func clone[T any](t T) T {
+	cloneValue(reflect.ValueOf(&t).Elem())
+	return t
+}
+
+func cloneValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		n := reflect.New(v.Elem().Type())
+		n.Elem().Set(v.Elem())
+		cloneValue(n.Elem())
+		v.Set(n)
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(n, v)
+		for i := range n.Len() {
+			cloneValue(n.Index(i))
+		}
+		v.Set(n)
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		n := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(iter.Value())
+			cloneValue(e)
+			n.SetMapIndex(iter.Key(), e)
+		}
+		v.Set(n)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if f := v.Field(i); f.CanSet() {
+				cloneValue(f)
+			}
+		}
+	}
+}
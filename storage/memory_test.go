@@ -0,0 +1,356 @@
+package storage_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/gorm"
+)
+
+type MemoryTestModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name      string
+	Email     string
+	Price     float64
+	Note      *string
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+type MemorySoftDeleteTestModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+type MemoryTenantTestModel struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TenantID string
+	Email    string
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Create and GetByID", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]("email")
+		note := "a"
+		model := MemoryTestModel{ID: uuid.New(), Name: "Test", Email: "a@test.com", Note: &note}
+		assert.NoError(t, store.Create(ctx, &model))
+		assert.Equal(t, 1, model.Version)
+		assert.False(t, model.CreatedAt.IsZero())
+		assert.NotNil(t, model.UpdatedAt)
+
+		// the stored value is a copy
+		note = "changed"
+		result, err := store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", *result.Note)
+		*result.Note = "changed"
+		result, err = store.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", *result.Note)
+
+		_, err = store.GetByID(ctx, uuid.New(), false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+	})
+
+	t.Run("Create already exist", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]("email")
+		model := MemoryTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.NoError(t, store.Create(ctx, &model))
+		assert.ErrorIs(t, store.Create(ctx, &model), storage.ErrDuplicateKey)
+
+		other := MemoryTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.ErrorIs(t, store.Create(ctx, &other), storage.ErrDuplicateKey)
+	})
+
+	t.Run("GetByFields", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		model := MemoryTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.NoError(t, store.Create(ctx, &model))
+
+		result, err := store.GetByFields(ctx, map[string]string{"email": "a@test.com"}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, model.ID, result.ID)
+
+		_, err = store.GetByFields(ctx, map[string]string{"email": "b@test.com"}, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+	})
+
+	t.Run("Update versioned", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		model := MemoryTestModel{ID: uuid.New(), Name: "Test"}
+		assert.NoError(t, store.Create(ctx, &model))
+
+		stale := model
+		model.Name = "Updated"
+		assert.NoError(t, store.Update(ctx, &model))
+		assert.Equal(t, 2, model.Version)
+		assert.ErrorIs(t, store.Update(ctx, &stale), storage.ErrStaleObject)
+
+		missing := MemoryTestModel{ID: uuid.New(), Version: 1}
+		assert.ErrorIs(t, store.Update(ctx, &missing), storage.ErrRecordNotFound)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		_, err := store.CreateMany(ctx, []MemoryTestModel{
+			{ID: uuid.New(), Name: "shoe", Price: 10},
+			{ID: uuid.New(), Name: "red shoe", Price: 30},
+			{ID: uuid.New(), Name: "hat", Price: 20},
+		}, 0)
+		assert.NoError(t, err)
+
+		results, err := store.Find(ctx, storage.Query{
+			Where: storage.Or(storage.Like("name", "%shoe"), storage.Gte("price", 20)),
+			Order: []storage.Order{{Field: "price", Desc: true}},
+			Limit: 2,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "red shoe", results[0].Name)
+		assert.Equal(t, "hat", results[1].Name)
+
+		_, err = store.GetAll(ctx, storage.Query{}.Apply)
+		assert.ErrorIs(t, err, storage.ErrNotSupported)
+	})
+
+	t.Run("GetPage", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		for _, name := range []string{"e", "b", "d", "a", "c"} {
+			assert.NoError(t, store.Create(ctx, &MemoryTestModel{ID: uuid.New(), Name: name}))
+		}
+
+		page, err := store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, memoryModelNames(page.Items))
+
+		page, err = store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2, Cursor: page.NextCursor}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c", "d"}, memoryModelNames(page.Items))
+
+		prev, err := store.GetPage(ctx, storage.PageRequest{SortKey: "name", Limit: 2, Cursor: page.PrevCursor}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, memoryModelNames(prev.Items))
+		assert.Empty(t, prev.PrevCursor)
+
+		// the rows without value would be skipped
+		_, err = store.GetPage(ctx, storage.PageRequest{SortKey: "updated_at"}, nil)
+		assert.ErrorIs(t, err, storage.ErrInvalidSortKey)
+		_, err = store.GetPage(ctx, storage.PageRequest{SortKey: "note"}, nil)
+		assert.ErrorIs(t, err, storage.ErrInvalidSortKey)
+	})
+
+	t.Run("bulk operations", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		models := []MemoryTestModel{
+			{ID: uuid.New(), Name: "a"},
+			{ID: uuid.New(), Name: "a"},
+		}
+		n, err := store.CreateMany(ctx, models, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		models[0].Name = "b"
+		n, err = store.UpsertMany(ctx, []MemoryTestModel{models[0], {ID: uuid.New(), Name: "c"}}, storage.UpsertOptions{
+			UpdateColumns: []string{"name"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		n, err = store.UpdateWhere(ctx, storage.Eq("name", "a"), map[string]any{"price": 5.0})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		result, err := store.GetByID(ctx, models[1].ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 5.0, result.Price)
+		assert.Equal(t, 2, result.Version)
+
+		n, err = store.DeleteWhere(ctx, storage.In("name", []string{"a", "b"}))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		all, err := store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c"}, memoryModelNames(all))
+	})
+
+	t.Run("Aggregates", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		_, err := store.CreateMany(ctx, []MemoryTestModel{
+			{ID: uuid.New(), Name: "a", Price: 10},
+			{ID: uuid.New(), Name: "a", Price: 30},
+			{ID: uuid.New(), Name: "b", Price: 20},
+		}, 0)
+		assert.NoError(t, err)
+
+		n, err := store.Count(ctx, storage.Eq("name", "a"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		ok, err := store.Exists(ctx, storage.Eq("name", "c"))
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		sum, err := store.Sum(ctx, "price", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 60.0, sum)
+		low, err := store.Min(ctx, "price", storage.Eq("name", "a"))
+		assert.NoError(t, err)
+		assert.Equal(t, 10.0, low)
+		high, err := store.Max(ctx, "price", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 30.0, high)
+		counts, err := store.CountBy(ctx, "name", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"a": 2, "b": 1}, counts)
+
+		sum, err = store.Sum(ctx, "price", storage.Eq("name", "c"))
+		assert.NoError(t, err)
+		assert.Zero(t, sum)
+		_, err = store.Max(ctx, "price", storage.Eq("name", "c"))
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		_, err = store.Sum(ctx, "name", nil)
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+		_, err = store.CountBy(ctx, "unknown", nil)
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		for _, name := range []string{"a", "b", "c"} {
+			assert.NoError(t, store.Create(ctx, &MemoryTestModel{ID: uuid.New(), Name: name}))
+		}
+		var names []string
+		for model, err := range store.Stream(ctx, nil, 2) {
+			assert.NoError(t, err)
+			names = append(names, model.Name)
+			if len(names) == 2 {
+				break
+			}
+		}
+		assert.Len(t, names, 2)
+
+		for _, err := range store.Stream(ctx, func(db *gorm.DB) *gorm.DB { return db }, 0) {
+			assert.ErrorIs(t, err, storage.ErrNotSupported)
+		}
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		for _, err := range store.Stream(canceled, nil, 0) {
+			assert.ErrorIs(t, err, context.Canceled)
+		}
+	})
+
+	t.Run("Soft delete, Restore and Purge", func(t *testing.T) {
+		store := storage.NewMemory[MemorySoftDeleteTestModel]()
+		models := []MemorySoftDeleteTestModel{
+			{ID: uuid.New(), Name: "a"},
+			{ID: uuid.New(), Name: "b"},
+			{ID: uuid.New(), Name: "c"},
+		}
+		_, err := store.CreateMany(ctx, models, 0)
+		assert.NoError(t, err)
+
+		assert.NoError(t, store.Delete(ctx, &models[0]))
+		n, err := store.DeleteWhere(ctx, storage.In("name", []string{"a", "b"}))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = store.GetByID(ctx, models[0].ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		all, err := store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+		deleted, err := store.Find(storage.IncludeDeleted(ctx), storage.Query{Where: storage.Ne(storage.DeletedAtColumn, nil)})
+		assert.NoError(t, err)
+		assert.Len(t, deleted, 2)
+		assert.True(t, deleted[0].DeletedAt.Valid)
+
+		assert.NoError(t, store.Restore(ctx, models[0].ID))
+		assert.ErrorIs(t, store.Restore(ctx, models[0].ID), storage.ErrRecordNotFound)
+		restored, err := store.GetByID(ctx, models[0].ID, false)
+		assert.NoError(t, err)
+		assert.False(t, restored.DeletedAt.Valid)
+
+		assert.NoError(t, store.Purge(ctx, &models[1]))
+		_, err = store.GetByID(storage.IncludeDeleted(ctx), models[1].ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+
+		assert.ErrorIs(t, storage.NewMemory[MemoryTestModel]().Restore(ctx, uuid.New()), storage.ErrNotSoftDeletable)
+	})
+
+	t.Run("Tenant isolation", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTenantTestModel]("email")
+		acme, globex := storage.WithTenant(ctx, "acme"), storage.WithTenant(ctx, "globex")
+		a := MemoryTenantTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.NoError(t, store.Create(acme, &a))
+		assert.Equal(t, "acme", a.TenantID)
+		// the unique columns are unique per tenant
+		b := MemoryTenantTestModel{ID: uuid.New(), Email: "a@test.com"}
+		assert.NoError(t, store.Create(globex, &b))
+		assert.ErrorIs(t, store.Create(globex, &MemoryTenantTestModel{ID: uuid.New(), Email: "a@test.com"}), storage.ErrDuplicateKey)
+
+		_, err := store.GetByID(globex, a.ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		found, err := store.GetByFields(globex, map[string]string{"email": "a@test.com"}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, b.ID, found.ID)
+		all, err := store.GetAll(acme, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []MemoryTenantTestModel{a}, all)
+		// a context without tenant sees every tenant
+		all, err = store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Len(t, all, 2)
+
+		// the rows of another tenant can't be written
+		stolen := a
+		stolen.Email = "stolen@test.com"
+		assert.ErrorIs(t, store.Update(globex, &stolen), storage.ErrDuplicateKey)
+		assert.NoError(t, store.Delete(globex, &a))
+		assert.NoError(t, store.Purge(globex, &a))
+		n, err := store.UpdateWhere(globex, storage.Eq("id", a.ID), map[string]any{"email": "stolen@test.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+		n, err = store.DeleteWhere(globex, storage.Eq("id", a.ID))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+		n, err = store.UpsertMany(globex, []MemoryTenantTestModel{stolen}, storage.UpsertOptions{UpdateColumns: []string{"email"}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+		stored, err := store.GetByID(acme, a.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, a, *stored)
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		store := storage.NewMemory[MemoryTestModel]()
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				model := MemoryTestModel{ID: uuid.New()}
+				assert.NoError(t, store.Create(ctx, &model))
+				_, err := store.GetAll(ctx, nil)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		all, err := store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Len(t, all, 50)
+	})
+}
+
+func memoryModelNames(models []MemoryTestModel) []string {
+	names := make([]string, len(models))
+	for i := range models {
+		names[i] = models[i].Name
+	}
+	return names
+}
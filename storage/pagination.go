@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultPageLimit is used when a PageRequest doesn't specify a limit.
+	DefaultPageLimit = 50
+	// MaxPageLimit is the biggest page size a PageRequest can ask for.
+	MaxPageLimit = 500
+)
+
+var (
+	// ErrInvalidCursor is returned when the given cursor can't be decoded or doesn't belong to the requested sort key.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrInvalidSortKey is returned when the sort key is not a column of the entity.
+	ErrInvalidSortKey = errors.New("invalid sort key")
+)
+
+// PageRequest describes a page to be read with keyset pagination.
+//
+// SortKey is the column (or field name) used to sort the rows, it defaults to the primary key. Nullable columns,
+// mapped to pointers or to types like sql.NullTime, can't be used: the keyset comparison would skip the NULL rows.
+// The primary key is always added as a tie breaker so rows with the same sort value are never skipped.
+// Cursor is an opaque value taken from a previous Page, empty means the first page.
+// Limit is the page size, it defaults to DefaultPageLimit and is capped by MaxPageLimit.
+type PageRequest struct {
+	SortKey string
+	Desc    bool
+	Cursor  string
+	Limit   int
+}
+
+// Page is the result of a paginated read. NextCursor and PrevCursor are empty when there is
+// no page in that direction.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// cursor is the decoded form of the opaque cursor string handed to the clients.
+type cursor struct {
+	SortKey  string          `json:"k"`
+	Value    json.RawMessage `json:"v"`
+	ID       json.RawMessage `json:"id"`
+	Backward bool            `json:"b,omitempty"`
+}
+
+func (c cursor) encode() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// keyset is the sort value and primary key of the row a cursor points at.
+type keyset struct {
+	value any
+	id    any
+}
+
+// pagePlan is a PageRequest resolved against the schema of an entity, it's shared by the stores
+// so they all paginate the same way.
+type pagePlan struct {
+	sortField *schema.Field
+	pk        *schema.Field
+	limit     int
+	// after is set when reading from a cursor, the page starts right after this row.
+	after *keyset
+	// backward means reading the rows in the opposite order and reversing them afterwards.
+	backward bool
+	// desc is the order the rows are read in, taking backward into account.
+	desc bool
+}
+
+func newPagePlan(s *schema.Schema, p PageRequest) (*pagePlan, error) {
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, fmt.Errorf("%w: %s has no primary key", ErrInvalidSortKey, s.Name)
+	}
+	plan := &pagePlan{pk: pk, sortField: pk, desc: p.Desc}
+	if p.SortKey != "" {
+		plan.sortField = s.LookUpField(p.SortKey)
+		if plan.sortField == nil || plan.sortField.DBName == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSortKey, p.SortKey)
+		}
+		if nullable(plan.sortField) {
+			return nil, fmt.Errorf("%w: %s is nullable", ErrInvalidSortKey, p.SortKey)
+		}
+	}
+
+	plan.limit = p.Limit
+	if plan.limit <= 0 {
+		plan.limit = DefaultPageLimit
+	}
+	plan.limit = min(plan.limit, MaxPageLimit)
+
+	if p.Cursor == "" {
+		return plan, nil
+	}
+	cur, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cur.SortKey != plan.sortField.DBName {
+		return nil, ErrInvalidCursor
+	}
+	value, err := decodeCursorValue(cur.Value, plan.sortField)
+	if err != nil {
+		return nil, err
+	}
+	id, err := decodeCursorValue(cur.ID, pk)
+	if err != nil {
+		return nil, err
+	}
+	plan.after = &keyset{value: value, id: id}
+	plan.backward = cur.Backward
+	plan.desc = p.Desc != cur.Backward
+	return plan, nil
+}
+
+// nullable reports whether the field can hold NULL: a pointer, or a struct with a Valid flag such as sql.NullTime
+// or gorm.DeletedAt.
+func nullable(f *schema.Field) bool {
+	t := f.FieldType
+	if t.Kind() == reflect.Pointer {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	valid, ok := t.FieldByName("Valid")
+	return ok && valid.Type.Kind() == reflect.Bool
+}
+
+// buildPage builds the page from the rows read following the plan, at most limit+1 rows in the plan order.
+func buildPage[T any](ctx context.Context, plan *pagePlan, results []T) (*Page[T], error) {
+	hasMore := len(results) > plan.limit
+	if hasMore {
+		results = results[:plan.limit]
+	}
+	if plan.backward {
+		slices.Reverse(results)
+	}
+
+	page := &Page[T]{Items: results}
+	if len(results) == 0 {
+		return page, nil
+	}
+	// going forward there is a previous page only if we came from one, going backward the
+	// page we came from is always there.
+	hasNext, hasPrev := hasMore, plan.after != nil
+	if plan.backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	var err error
+	if hasNext {
+		page.NextCursor, err = newCursor(ctx, plan.sortField, plan.pk, results[len(results)-1], false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		page.PrevCursor, err = newCursor(ctx, plan.sortField, plan.pk, results[0], true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// GetPage implements CRUDStorer, it reads a single page of rows matching filter using keyset pagination.
+// A nil filter matches every row.
+func (c CRUDStore[T]) GetPage(ctx context.Context, p PageRequest, filter Predicate) (*Page[T], error) {
+	return timedResult(ctx, c, true, func(ctx context.Context, c CRUDStore[T]) (*Page[T], error) {
+		return c.getPage(ctx, p, filter)
+	})
+}
+
+// getPage reads the page of GetPage.
+func (c CRUDStore[T]) getPage(ctx context.Context, p PageRequest, filter Predicate) (*Page[T], error) {
+	db := c.read(ctx)
+	s, err := parseSchema[T](db)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := newPagePlan(s, p)
+	if err != nil {
+		return nil, err
+	}
+	sortField, pk := plan.sortField, plan.pk
+
+	db = Query{Where: filter}.Apply(db)
+	if plan.after != nil {
+		op := ">"
+		if plan.desc {
+			op = "<"
+		}
+		if sortField == pk {
+			db = db.Where(clause.Expr{
+				SQL:  fmt.Sprintf("? %s ?", op),
+				Vars: []any{clause.Column{Name: pk.DBName}, plan.after.id},
+			})
+		} else {
+			db = db.Where(clause.Expr{
+				SQL:  fmt.Sprintf("(?, ?) %s (?, ?)", op),
+				Vars: []any{clause.Column{Name: sortField.DBName}, clause.Column{Name: pk.DBName}, plan.after.value, plan.after.id},
+			})
+		}
+	}
+
+	columns := []clause.OrderByColumn{{Column: clause.Column{Name: sortField.DBName}, Desc: plan.desc}}
+	if sortField != pk {
+		columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: pk.DBName}, Desc: plan.desc})
+	}
+
+	var results []T
+	if r := db.Order(clause.OrderBy{Columns: columns}).Limit(plan.limit + 1).Find(&results); r.Error != nil {
+		return nil, r.Error
+	}
+	return buildPage(ctx, plan, results)
+}
+
+func newCursor[T any](ctx context.Context, sortField, pk *schema.Field, row T, backward bool) (string, error) {
+	rv := reflect.ValueOf(&row).Elem()
+	value, _ := sortField.ValueOf(ctx, rv)
+	id, _ := pk.ValueOf(ctx, rv)
+	v, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	i, err := json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+	return cursor{SortKey: sortField.DBName, Value: v, ID: i, Backward: backward}.encode()
+}
+
+// decodeCursorValue decodes a cursor value into the Go type of the given field, so the
+// database driver receives the same type it would get from the entity itself.
+func decodeCursorValue(raw json.RawMessage, f *schema.Field) (any, error) {
+	v := reflect.New(f.FieldType)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return v.Elem().Interface(), nil
+}
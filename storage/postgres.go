@@ -1,14 +1,22 @@
 package storage
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
 type Config struct {
+	// Driver is the database driver, DriverPostgres or DriverSQLite, see NewStorage.
+	Driver string
+	// DBPath is the file of the SQLite database.
+	DBPath     string
 	DBUser     string
 	DBHost     string
 	DBName     string
@@ -16,19 +24,99 @@ type Config struct {
 	DBPort     string
 	DBSSLMode  string
 	DebugMode  bool
+	// Replicas are the read replicas of the database, when set the reads that don't lock rows and don't run
+	// inside a transaction are sent to one of them. Writes always go to the primary.
+	Replicas []Replica
+	// SlowQueryThreshold is the duration above which the queries are logged as slow, zero disables the log.
+	SlowQueryThreshold time.Duration
+	// RepeatedQueryThreshold is the number of times a request can run the same query before it is reported
+	// as a possible N+1 problem, zero disables the detection.
+	RepeatedQueryThreshold int
+	// StatementTimeout is the default statement timeout of the operations of the CRUDStores, zero disables it.
+	// WithStatementTimeout overrides it for a single call.
+	StatementTimeout time.Duration
+}
+
+// Replica is a read replica of the primary database, the empty fields default to the ones of the primary.
+type Replica struct {
+	DBHost     string
+	DBPort     string
+	DBUser     string
+	DBPassword string
+}
+
+// PostgresDialector is the GORM postgres dialector translating the integrity constraint violations into
+// ConstraintError, the other errors are translated by GORM.
+type PostgresDialector struct {
+	*postgres.Dialector
+}
+
+// NewPostgresDialector creates a PostgresDialector with the given configuration.
+func NewPostgresDialector(cfg postgres.Config) PostgresDialector {
+	return PostgresDialector{postgres.New(cfg).(*postgres.Dialector)}
+}
+
+// Translate implements gorm.ErrorTranslator.
+func (d PostgresDialector) Translate(err error) error {
+	if ce := pgConstraintError(err); ce != nil {
+		return ce
+	}
+	return d.Dialector.Translate(err)
 }
 
 func NewPostgreStorage(cfg Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s", cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMode, "UTC")
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(NewPostgresDialector(postgres.Config{DSN: cfg.dsn(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword)}), &gorm.Config{
 		TranslateError: true,
+		NamingStrategy: NamingStrategy{Schema: DefaultSchema},
 	})
+	if err != nil {
+		log.Fatalf("Failed to get sql.DB: %v", err)
+	}
+
+	db, err = setup(db, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Replicas) > 0 {
+		replicas := make([]gorm.Dialector, len(cfg.Replicas))
+		for i, r := range cfg.Replicas {
+			replicas[i] = NewPostgresDialector(postgres.Config{DSN: cfg.dsn(
+				cmp.Or(r.DBHost, cfg.DBHost),
+				cmp.Or(r.DBPort, cfg.DBPort),
+				cmp.Or(r.DBUser, cfg.DBUser),
+				cmp.Or(r.DBPassword, cfg.DBPassword),
+			)})
+		}
+		err := db.Use(dbresolver.Register(dbresolver.Config{
+			Replicas:          replicas,
+			Policy:            dbresolver.RandomPolicy{},
+			TraceResolverMode: cfg.DebugMode,
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to register the read replicas: %w", err)
+		}
+	}
 
 	if cfg.DebugMode {
 		db = db.Debug()
 	}
-	if err != nil {
-		log.Fatalf("Failed to get sql.DB: %v", err)
-	}
 	return db, nil
 }
+
+func (cfg Config) dsn(host, port, user, password string) string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s", host, user, password, cfg.DBName, port, cfg.DBSSLMode, "UTC")
+}
+
+type usePrimaryKey struct{}
+
+// UsePrimary returns a context that makes the reads of the stores go to the primary database instead of a
+// read replica, it is meant to read your own writes right after them, before the replicas caught up.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(usePrimaryKey{}).(bool)
+	return v
+}
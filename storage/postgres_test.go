@@ -0,0 +1,129 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	pgContainer "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/gorm"
+)
+
+func TestReadReplicas(t *testing.T) {
+	ctx := context.Background()
+	dbname := "yourdb"
+	user := "youruser"
+	password := "yourpassword"
+	// The replica is an independent database, so the rows written to the primary are never visible on it
+	// and a read tells which database served it.
+	runPostgres := func() (host, port string) {
+		ctr, err := pgContainer.Run(
+			ctx,
+			"postgres:17-alpine",
+			pgContainer.WithDatabase(dbname),
+			pgContainer.WithUsername(user),
+			pgContainer.WithPassword(password),
+			pgContainer.BasicWaitStrategies(),
+		)
+		testcontainers.CleanupContainer(t, ctr)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		host, err = ctr.Host(ctx)
+		assert.NoError(t, err)
+		mapped, err := ctr.MappedPort(ctx, "5432/tcp")
+		assert.NoError(t, err)
+		return host, mapped.Port()
+	}
+	primaryHost, primaryPort := runPostgres()
+	replicaHost, replicaPort := runPostgres()
+
+	db, err := storage.NewPostgreStorage(storage.Config{
+		DBUser:     user,
+		DBHost:     primaryHost,
+		DBName:     dbname,
+		DBPassword: password,
+		DBPort:     primaryPort,
+		DBSSLMode:  "disable",
+		Replicas:   []storage.Replica{{DBHost: replicaHost, DBPort: replicaPort}},
+	})
+	assert.NoError(t, err)
+	// AutoMigrate runs on the primary, the replica needs its own table. The writes are audited on the primary.
+	assert.NoError(t, db.Exec("CREATE SCHEMA IF NOT EXISTS ecom").Error)
+	assert.NoError(t, db.AutoMigrate(&TestModel{}, &storage.AuditEntry{}))
+	replica, err := storage.NewPostgreStorage(storage.Config{
+		DBUser:     user,
+		DBHost:     replicaHost,
+		DBName:     dbname,
+		DBPassword: password,
+		DBPort:     replicaPort,
+		DBSSLMode:  "disable",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, replica.AutoMigrate(&TestModel{}))
+
+	store := storage.New[TestModel](db)
+	model := TestModel{ID: uuid.New(), Name: "Test"}
+	assert.NoError(t, store.Create(ctx, &model))
+
+	t.Run("reads go to the replica", func(t *testing.T) {
+		_, err := store.GetByID(ctx, model.ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		_, err = store.GetByFields(ctx, map[string]string{"name": "Test"}, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		all, err := store.GetAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("locking reads go to the primary", func(t *testing.T) {
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			result, err := storage.New[TestModel](tx).GetByID(ctx, model.ID, true)
+			assert.NoError(t, err)
+			assert.Equal(t, model.ID, result.ID)
+			return nil
+		}))
+		result, err := store.GetByFields(ctx, map[string]string{"name": "Test"}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, model.ID, result.ID)
+	})
+
+	t.Run("reads inside a transaction go to the primary", func(t *testing.T) {
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			all, err := storage.New[TestModel](tx).GetAll(ctx, nil)
+			assert.NoError(t, err)
+			assert.Len(t, all, 1)
+			return nil
+		}))
+	})
+
+	t.Run("reads with a statement timeout go to the replica", func(t *testing.T) {
+		ctx := storage.WithStatementTimeout(ctx, time.Second)
+		_, err := store.GetByID(ctx, model.ID, false)
+		assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+		page, err := store.GetPage(ctx, storage.PageRequest{}, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Items)
+		result, err := store.GetByID(ctx, model.ID, true)
+		assert.NoError(t, err)
+		assert.Equal(t, model.ID, result.ID)
+
+		_, err = store.GetAll(storage.WithStatementTimeout(ctx, 50*time.Millisecond), func(db *gorm.DB) *gorm.DB {
+			return db.Where("pg_sleep(1) IS NOT NULL")
+		})
+		assert.ErrorIs(t, err, storage.ErrTimeout)
+	})
+
+	t.Run("UsePrimary", func(t *testing.T) {
+		result, err := store.GetByID(storage.UsePrimary(ctx), model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, model.ID, result.ID)
+		results, err := store.Find(storage.UsePrimary(ctx), storage.Query{Where: storage.Eq("name", "Test")})
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+	})
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DeletedAtColumn is the column of the soft deletable entities. Entities with a gorm.DeletedAt field
+// mapped to this column are soft deleted: Delete sets the deletion time, reads skip the deleted rows
+// unless the context comes from IncludeDeleted, Restore brings them back and Purge removes them for good.
+const DeletedAtColumn = "deleted_at"
+
+// ErrNotSoftDeletable is returned when restoring an entity that doesn't support soft delete.
+var ErrNotSoftDeletable = errors.New("entity doesn't support soft delete")
+
+type includeDeletedKey struct{}
+
+// IncludeDeleted returns a context that makes the reads of the stores return the soft deleted rows too.
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+func includeDeleted(ctx context.Context) bool {
+	v, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return v
+}
+
+// deletedAtField returns the soft delete field of the schema, or nil if the entity is not soft deletable.
+func deletedAtField(s *schema.Schema) *schema.Field {
+	f := s.LookUpField(DeletedAtColumn)
+	if f == nil || f.FieldType != reflect.TypeOf(gorm.DeletedAt{}) {
+		return nil
+	}
+	return f
+}
+
+// Restore implements CRUDStorer, it clears the deletion time of a soft deleted record.
+func (c CRUDStore[T]) Restore(ctx context.Context, id uuid.UUID) error {
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return err
+	}
+	f := deletedAtField(s)
+	if f == nil {
+		return ErrNotSoftDeletable
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return ErrRecordNotFound
+	}
+	return c.timed(ctx, false, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.audited(ctx, s, AuditRestore, func() any { return id }, func(tx CRUDStore[T]) error {
+			r := tenantScoped(ctx, tx.db.WithContext(ctx), s).Unscoped().Model(new(T)).
+				Where(clause.Eq{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Value: id}).
+				Where(clause.Neq{Column: clause.Column{Name: f.DBName}, Value: nil}).
+				Update(f.DBName, nil)
+			if r.Error != nil {
+				return translateError(r.Error)
+			}
+			if r.RowsAffected == 0 {
+				return ErrRecordNotFound
+			}
+			return nil
+		})
+	})
+}
+
+// Purge implements CRUDStorer, it permanently deletes the record, even if it is soft deletable.
+func (c CRUDStore[T]) Purge(ctx context.Context, t *T) error {
+	s, err := parseSchema[T](c.db)
+	if err != nil {
+		return err
+	}
+	return c.timed(ctx, false, func(ctx context.Context, c CRUDStore[T]) error {
+		return c.audited(ctx, s, AuditPurge, func() any { return auditID(ctx, s, t) }, func(tx CRUDStore[T]) error {
+			return translateError(tenantScoped(ctx, tx.db.WithContext(ctx), s).Unscoped().Delete(t).Error)
+		})
+	})
+}
@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidFilter is returned when a filter parsed from user input uses an unknown field,
+// an unsupported operator or a value that can't be converted to the field type.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Operator is a comparison operator used by a field predicate.
+type Operator string
+
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpIn   Operator = "in"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpLike Operator = "like"
+)
+
+// Predicate is a typed condition that can be translated into a GORM where clause.
+// Predicates are plain values, they can be composed with And and Or, compared in tests
+// and built from user input without touching the database.
+type Predicate interface {
+	Expression() clause.Expression
+}
+
+// fieldPredicate compares a single column against a value.
+type fieldPredicate struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+// Expression implements Predicate, the field is always quoted as a column so it can't be used to inject SQL.
+func (p fieldPredicate) Expression() clause.Expression {
+	column := clause.Column{Name: p.Field}
+	switch p.Op {
+	case OpNe:
+		return clause.Neq{Column: column, Value: p.Value}
+	case OpIn:
+		return clause.IN{Column: column, Values: p.Value.([]any)}
+	case OpGt:
+		return clause.Gt{Column: column, Value: p.Value}
+	case OpGte:
+		return clause.Gte{Column: column, Value: p.Value}
+	case OpLt:
+		return clause.Lt{Column: column, Value: p.Value}
+	case OpLte:
+		return clause.Lte{Column: column, Value: p.Value}
+	case OpLike:
+		return clause.Like{Column: column, Value: p.Value}
+	default:
+		return clause.Eq{Column: column, Value: p.Value}
+	}
+}
+
+// logicalPredicate combines several predicates with AND or OR.
+type logicalPredicate struct {
+	Or         bool
+	Predicates []Predicate
+}
+
+// Expression implements Predicate.
+func (p logicalPredicate) Expression() clause.Expression {
+	exprs := make([]clause.Expression, 0, len(p.Predicates))
+	for _, pred := range p.Predicates {
+		if pred == nil {
+			continue
+		}
+		if expr := pred.Expression(); expr != nil {
+			exprs = append(exprs, expr)
+		}
+	}
+	if p.Or {
+		return clause.Or(exprs...)
+	}
+	return clause.And(exprs...)
+}
+
+// Eq matches rows where field equals value.
+func Eq(field string, value any) Predicate {
+	return fieldPredicate{Field: field, Op: OpEq, Value: value}
+}
+
+// Ne matches rows where field is different from value.
+func Ne(field string, value any) Predicate {
+	return fieldPredicate{Field: field, Op: OpNe, Value: value}
+}
+
+// In matches rows where field is one of the given values.
+func In[V any](field string, values []V) Predicate {
+	vs := make([]any, len(values))
+	for i := range values {
+		vs[i] = values[i]
+	}
+	return fieldPredicate{Field: field, Op: OpIn, Value: vs}
+}
+
+// Gt matches rows where field is greater than value.
+func Gt(field string, value any) Predicate {
+	return fieldPredicate{Field: field, Op: OpGt, Value: value}
+}
+
+// Gte matches rows where field is greater than or equal to value.
+func Gte(field string, value any) Predicate {
+	return fieldPredicate{Field: field, Op: OpGte, Value: value}
+}
+
+// Lt matches rows where field is less than value.
+func Lt(field string, value any) Predicate {
+	return fieldPredicate{Field: field, Op: OpLt, Value: value}
+}
+
+// Lte matches rows where field is less than or equal to value.
+func Lte(field string, value any) Predicate {
+	return fieldPredicate{Field: field, Op: OpLte, Value: value}
+}
+
+// Range matches rows where field is between from and to, both inclusive. A nil bound leaves that side open.
+func Range(field string, from, to any) Predicate {
+	var ps []Predicate
+	if from != nil {
+		ps = append(ps, Gte(field, from))
+	}
+	if to != nil {
+		ps = append(ps, Lte(field, to))
+	}
+	return And(ps...)
+}
+
+// Like matches rows where field matches the SQL LIKE pattern.
+func Like(field string, pattern string) Predicate {
+	return fieldPredicate{Field: field, Op: OpLike, Value: pattern}
+}
+
+// And matches rows matching all the given predicates, nil predicates are ignored.
+func And(ps ...Predicate) Predicate {
+	return logicalPredicate{Predicates: ps}
+}
+
+// Or matches rows matching at least one of the given predicates, nil predicates are ignored.
+func Or(ps ...Predicate) Predicate {
+	return logicalPredicate{Or: true, Predicates: ps}
+}
+
+// Order sorts the result by a field.
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Query is a typed query specification: a filter, an ordering and an optional limit and offset.
+// ForUpdate locks the selected rows with SELECT ... FOR UPDATE, it is only supported by postgresql.
+// SkipLocked adds SKIP LOCKED to the lock, so the rows already locked by another transaction are left
+// out instead of waiting for them, which lets concurrent workers claim distinct rows.
+// The zero value matches every row.
+type Query struct {
+	Where      Predicate
+	Order      []Order
+	Limit      int
+	Offset     int
+	ForUpdate  bool
+	SkipLocked bool
+}
+
+// Apply translates the query into GORM conditions, it has the SQLModifier signature so a
+// Query can be used anywhere a SQLModifier is expected.
+func (q Query) Apply(db *gorm.DB) *gorm.DB {
+	if q.Where != nil {
+		if expr := q.Where.Expression(); expr != nil {
+			db = db.Where(expr)
+		}
+	}
+	if len(q.Order) > 0 {
+		columns := make([]clause.OrderByColumn, len(q.Order))
+		for i, o := range q.Order {
+			columns[i] = clause.OrderByColumn{Column: clause.Column{Name: o.Field}, Desc: o.Desc}
+		}
+		db = db.Order(clause.OrderBy{Columns: columns})
+	}
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+	if q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+	if q.ForUpdate {
+		locking := clause.Locking{Strength: "UPDATE"}
+		if q.SkipLocked {
+			locking.Options = "SKIP LOCKED"
+		}
+		db = db.Clauses(locking)
+	}
+	return db
+}
+
+// FieldParser converts the raw value of a URL query parameter into the Go type of a field.
+type FieldParser func(string) (any, error)
+
+var (
+	StringField FieldParser = func(s string) (any, error) { return s, nil }
+	IntField    FieldParser = func(s string) (any, error) { return strconv.Atoi(s) }
+	FloatField  FieldParser = func(s string) (any, error) { return strconv.ParseFloat(s, 64) }
+	BoolField   FieldParser = func(s string) (any, error) { return strconv.ParseBool(s) }
+	UUIDField   FieldParser = func(s string) (any, error) { return uuid.Parse(s) }
+	TimeField   FieldParser = func(s string) (any, error) { return time.Parse(time.RFC3339, s) }
+)
+
+// AllowedFields is the allow-list of the columns of an entity that can be filtered from user input,
+// with the parser used to convert their values.
+type AllowedFields map[string]FieldParser
+
+// ParseFilter builds a predicate from URL query parameters, every parameter must be an allowed field.
+// Parameters have the form "field=value" for equality or "field[op]=value" for any other operator,
+// the values of the "in" operator are comma separated. Repeating an equality turns it into an "in".
+// All the parameters are combined with AND, it returns nil when there are no parameters.
+func ParseFilter(values url.Values, allowed AllowedFields) (Predicate, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// keep the predicates order stable so the same URL always builds the same query
+	sort.Strings(keys)
+
+	var ps []Predicate
+	for _, key := range keys {
+		field, op, err := parseFilterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		parse, ok := allowed[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, field)
+		}
+		raws := values[key]
+		if op == OpEq && len(raws) > 1 {
+			op = OpIn
+		}
+		if op == OpIn {
+			var vs []any
+			for _, raw := range raws {
+				for _, r := range strings.Split(raw, ",") {
+					v, err := parse(r)
+					if err != nil {
+						return nil, fmt.Errorf("%w: invalid value %q for %s", ErrInvalidFilter, r, field)
+					}
+					vs = append(vs, v)
+				}
+			}
+			ps = append(ps, In(field, vs))
+			continue
+		}
+		for _, raw := range raws {
+			if op == OpLike {
+				ps = append(ps, Like(field, raw))
+				continue
+			}
+			v, err := parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid value %q for %s", ErrInvalidFilter, raw, field)
+			}
+			ps = append(ps, fieldPredicate{Field: field, Op: op, Value: v})
+		}
+	}
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	return And(ps...), nil
+}
+
+func parseFilterKey(key string) (string, Operator, error) {
+	field, rest, found := strings.Cut(key, "[")
+	if !found {
+		return key, OpEq, nil
+	}
+	op, ok := strings.CutSuffix(rest, "]")
+	if !ok {
+		return "", "", fmt.Errorf("%w: malformed parameter %s", ErrInvalidFilter, key)
+	}
+	switch o := Operator(op); o {
+	case OpEq, OpNe, OpIn, OpGt, OpGte, OpLt, OpLte, OpLike:
+		return field, o, nil
+	}
+	return "", "", fmt.Errorf("%w: unknown operator %s", ErrInvalidFilter, op)
+}
@@ -0,0 +1,114 @@
+package storage_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestQuery(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	assert.NoError(t, err)
+
+	toSQL := func(q storage.Query) string {
+		return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return q.Apply(tx).Find(&[]TestModel{})
+		})
+	}
+
+	id1 := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	id2 := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	tests := []struct {
+		name     string
+		query    storage.Query
+		expected string
+	}{
+		{
+			name:     "empty",
+			query:    storage.Query{},
+			expected: `SELECT * FROM "test_models"`,
+		},
+		{
+			name:     "in",
+			query:    storage.Query{Where: storage.In("id", []uuid.UUID{id1, id2})},
+			expected: `SELECT * FROM "test_models" WHERE "id" IN ('11111111-1111-1111-1111-111111111111','22222222-2222-2222-2222-222222222222')`,
+		},
+		{
+			name: "and or",
+			query: storage.Query{
+				Where: storage.And(
+					storage.Range("price", 10, 20),
+					storage.Or(storage.Eq("name", "a"), storage.Like("name", "b%")),
+				),
+			},
+			expected: `SELECT * FROM "test_models" WHERE ("price" >= 10 AND "price" <= 20) AND ("name" = 'a' OR "name" LIKE 'b%')`,
+		},
+		{
+			name:     "order and limit",
+			query:    storage.Query{Order: []storage.Order{{Field: "name", Desc: true}}, Limit: 10, Offset: 5},
+			expected: `SELECT * FROM "test_models" ORDER BY "name" DESC LIMIT 10 OFFSET 5`,
+		},
+		{
+			name:     "for update skip locked",
+			query:    storage.Query{Where: storage.Eq("name", "a"), Limit: 10, ForUpdate: true, SkipLocked: true},
+			expected: `SELECT * FROM "test_models" WHERE "name" = 'a' LIMIT 10 FOR UPDATE SKIP LOCKED`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, toSQL(tt.query))
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	allowed := storage.AllowedFields{
+		"name":  storage.StringField,
+		"price": storage.FloatField,
+	}
+
+	t.Run("operators", func(t *testing.T) {
+		p, err := storage.ParseFilter(url.Values{
+			"name":       {"a", "b"},
+			"price[gte]": {"10"},
+			"price[lt]":  {"20.5"},
+			"name[like]": {"%shoe%"},
+		}, allowed)
+		assert.NoError(t, err)
+		assert.Equal(t, storage.And(
+			storage.In("name", []any{"a", "b"}),
+			storage.Like("name", "%shoe%"),
+			storage.Gte("price", 10.0),
+			storage.Lt("price", 20.5),
+		), p)
+	})
+
+	t.Run("no parameters", func(t *testing.T) {
+		p, err := storage.ParseFilter(url.Values{}, allowed)
+		assert.NoError(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := storage.ParseFilter(url.Values{"password": {"x"}}, allowed)
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+	})
+
+	t.Run("unknown operator", func(t *testing.T) {
+		_, err := storage.ParseFilter(url.Values{"price[between]": {"x"}}, allowed)
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		_, err := storage.ParseFilter(url.Values{"price": {"cheap"}}, allowed)
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+	})
+}
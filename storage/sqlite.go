@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSQLiteStorage opens the SQLite database stored in the DBPath file, it is meant for local development and
+// tests. The tables are not qualified with a schema, and the locking reads, like GetByID with forUpdate, are
+// plain reads: SQLite has no row locks, instead the transactions take the write lock of the whole database when
+// they begin, so they are serialized. Read replicas are not supported.
+func NewSQLiteStorage(cfg Config) (*gorm.DB, error) {
+	if len(cfg.Replicas) > 0 {
+		return nil, fmt.Errorf("read replicas are not supported by SQLite")
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", cfg.DBPath)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		NamingStrategy: NamingStrategy{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the SQLite database: %w", err)
+	}
+
+	db, err = setup(db, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DebugMode {
+		db = db.Debug()
+	}
+	return db, nil
+}
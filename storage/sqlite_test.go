@@ -0,0 +1,157 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type AggregateTestModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Status    string
+	Quantity  int
+	DeletedAt gorm.DeletedAt
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewStorage(storage.Config{
+		Driver: storage.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TestModel{}, &AggregateTestModel{}, &storage.AuditEntry{}))
+
+	// SQLite has no schemas
+	assert.True(t, db.Migrator().HasTable("audit_log"))
+	assert.Equal(t, "ecom.audit_log", storage.TableName(schema.NamingStrategy{}, "audit_log"))
+	assert.Equal(t, "ecom.audit_log", storage.TableName(storage.NamingStrategy{Schema: storage.DefaultSchema}, "audit_log"))
+
+	store := storage.New[TestModel](db)
+	model := TestModel{ID: uuid.New(), Name: "Test"}
+	require.NoError(t, store.Create(ctx, &model))
+	// the locking reads are plain reads
+	result, err := store.GetByID(ctx, model.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Test", result.Name)
+	assert.ErrorIs(t, store.Create(ctx, &model), storage.ErrDuplicateKey)
+
+	var created int64
+	assert.NoError(t, db.Model(&storage.AuditEntry{}).Count(&created).Error)
+	assert.Equal(t, int64(1), created)
+
+	// the stream reads the rows in batches, stops when the loop breaks and when the context is canceled
+	models := []TestModel{model}
+	for i := range 4 {
+		models = append(models, TestModel{ID: uuid.New(), Name: fmt.Sprintf("Stream %d", i)})
+	}
+	_, err = store.CreateMany(ctx, models[1:], 0)
+	require.NoError(t, err)
+	var names []string
+	for m, err := range store.Stream(ctx, nil, 2) {
+		assert.NoError(t, err)
+		names = append(names, m.Name)
+	}
+	assert.ElementsMatch(t, testModelNames(models), names)
+	names = nil
+	for m, err := range store.Stream(ctx, func(db *gorm.DB) *gorm.DB { return db.Where("name <> ?", "Test") }, 3) {
+		assert.NoError(t, err)
+		if names = append(names, m.Name); len(names) == 2 {
+			break
+		}
+	}
+	assert.Len(t, names, 2)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	var errs []error
+	for _, err := range store.Stream(canceled, nil, 2) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+
+	// the aggregates are computed by the database, soft deleted rows excluded
+	aggregates := storage.New[AggregateTestModel](db)
+	rows := []AggregateTestModel{
+		{ID: uuid.New(), Status: "pending", Quantity: 2},
+		{ID: uuid.New(), Status: "pending", Quantity: 5},
+		{ID: uuid.New(), Status: "paid", Quantity: 1},
+		{ID: uuid.New(), Status: "paid", Quantity: 100},
+	}
+	_, err = aggregates.CreateMany(ctx, rows, 0)
+	require.NoError(t, err)
+	require.NoError(t, aggregates.Delete(ctx, &rows[3]))
+	n, err := aggregates.Count(ctx, storage.Eq("status", "pending"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	exists, err := aggregates.Exists(ctx, storage.Gt("quantity", 50))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	sum, err := aggregates.Sum(ctx, "quantity", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 8.0, sum)
+	low, err := aggregates.Min(ctx, "quantity", storage.Eq("status", "pending"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, low)
+	_, err = aggregates.Max(ctx, "quantity", storage.Eq("status", "refunded"))
+	assert.ErrorIs(t, err, storage.ErrRecordNotFound)
+	counts, err := aggregates.CountBy(ctx, "status", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"pending": 2, "paid": 1}, counts)
+
+	// the set-based writes record an audit entry per changed row
+	_, err = aggregates.UpdateWhere(ctx, storage.Eq("status", "pending"), map[string]any{"quantity": gorm.Expr("quantity + 1")})
+	require.NoError(t, err)
+	rows[2].Quantity = 3
+	_, err = aggregates.UpsertMany(ctx, []AggregateTestModel{rows[2], {ID: uuid.New(), Status: "paid", Quantity: 7}}, storage.UpsertOptions{
+		UpdateColumns: []string{"quantity"},
+	})
+	require.NoError(t, err)
+	_, err = aggregates.DeleteWhere(ctx, storage.Eq("status", "paid"))
+	require.NoError(t, err)
+	audit := storage.New[storage.AuditEntry](db)
+	counts, err = audit.CountBy(ctx, "action", storage.Eq("entity_type", "AggregateTestModel"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{storage.AuditCreate: 5, storage.AuditUpdate: 3, storage.AuditDelete: 3}, counts)
+	entries, err := audit.Find(ctx, storage.Query{Where: storage.And(
+		storage.Eq("entity_id", rows[0].ID.String()),
+		storage.Eq("action", storage.AuditUpdate),
+	)})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.JSONEq(t, `{"quantity":{"old":2,"new":3}}`, string(entries[0].Changes))
+
+	// the entries belong to the tenant of the change
+	acme := storage.WithTenant(ctx, "acme")
+	require.NoError(t, store.Create(acme, &TestModel{ID: uuid.New(), Name: "acme"}))
+	entries, err = audit.GetAll(acme, nil)
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "acme", entries[0].TenantID)
+	}
+	n, err = audit.Count(storage.WithTenant(ctx, "globex"), nil)
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	// SQLite has no statement timeout, the operations get a context deadline instead
+	_, err = store.GetAll(storage.WithStatementTimeout(ctx, time.Nanosecond), nil)
+	assert.ErrorIs(t, err, storage.ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = store.GetAll(storage.WithStatementTimeout(ctx, time.Minute), nil)
+	assert.NoError(t, err)
+	_, err = store.GetAll(storage.WithStatementTimeout(canceled, time.Minute), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, storage.ErrTimeout, "the cancellations of the caller aren't timeouts")
+
+	_, err = storage.NewStorage(storage.Config{Driver: "oracle"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"gorm.io/gorm"
+)
+
+// errStopStream stops FindInBatches when the consumer of a stream stops iterating.
+var errStopStream = errors.New("stream stopped")
+
+// Stream implements CRUDStorer, it reads the records matching the SQLModifier in batches of batchSize rows,
+// DefaultBatchSize is used when batchSize is not positive. The records are read in primary key order, using the
+// last key of a batch to read the next one, so m must not sort them or limit them.
+// The statement timeout doesn't apply to the stream, the deadline of the context does.
+func (c CRUDStore[T]) Stream(ctx context.Context, m SQLModifier, batchSize int) iter.Seq2[T, error] {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	c = c.joined(ctx)
+	return func(yield func(T, error) bool) {
+		db := c.read(ctx)
+		if m != nil {
+			db = m(db)
+		}
+		stopped := false
+		var batch []T
+		r := db.FindInBatches(&batch, batchSize, func(*gorm.DB, int) error {
+			for _, t := range batch {
+				if !yield(t, nil) {
+					stopped = true
+					return errStopStream
+				}
+			}
+			return ctx.Err()
+		})
+		if !stopped && r.Error != nil {
+			var zero T
+			yield(zero, timeoutError(ctx, r.Error, false))
+		}
+	}
+}
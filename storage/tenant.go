@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TenantColumn is the column of the tenant aware entities. Entities with a string field mapped to this column
+// are isolated per tenant: when the context carries a tenant, see WithTenant, the stores only read, update and
+// delete the rows of that tenant and stamp it on the rows they create.
+const TenantColumn = "tenant_id"
+
+type tenantKey struct{}
+
+// WithTenant returns a context scoping the operations of the stores to the given tenant.
+// A context without tenant is not scoped, it is meant for the system tasks that work across tenants.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant the operations made with the context are scoped to.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// tenantField returns the tenant field of the schema, or nil if the entity is not tenant aware.
+func tenantField(s *schema.Schema) *schema.Field {
+	f := s.LookUpField(TenantColumn)
+	if f == nil || f.FieldType.Kind() != reflect.String {
+		return nil
+	}
+	return f
+}
+
+// tenantCondition returns the condition restricting the rows to the tenant of the context, or nil if the
+// entity is not tenant aware or the context has no tenant.
+func tenantCondition(ctx context.Context, s *schema.Schema) clause.Expression {
+	f := tenantField(s)
+	if f == nil {
+		return nil
+	}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: tenant}
+}
+
+// tenantScoped adds the tenant condition of the context to db.
+func tenantScoped(ctx context.Context, db *gorm.DB, s *schema.Schema) *gorm.DB {
+	if cond := tenantCondition(ctx, s); cond != nil {
+		return db.Where(cond)
+	}
+	return db
+}
+
+// stampTenant sets the tenant of the context on t.
+func stampTenant[T any](ctx context.Context, s *schema.Schema, t *T) error {
+	f := tenantField(s)
+	if f == nil {
+		return nil
+	}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return f.Set(ctx, reflect.ValueOf(t).Elem(), tenant)
+}
+
+// updateInTenant saves t like gorm.DB.Save does, but only overwrites a row of the tenant of the context: Save
+// falls back to an upsert that would overwrite the row of another tenant using the same primary key.
+func updateInTenant[T any](ctx context.Context, db *gorm.DB, s *schema.Schema, t *T) error {
+	if pk := s.PrioritizedPrimaryField; pk != nil {
+		if _, zero := pk.ValueOf(ctx, reflect.ValueOf(t).Elem()); !zero {
+			r := tenantScoped(ctx, db.WithContext(ctx), s).Model(t).Select("*").Updates(t)
+			if r.Error != nil || r.RowsAffected > 0 {
+				return translateError(r.Error)
+			}
+		}
+	}
+	return translateError(db.WithContext(ctx).Create(t).Error)
+}
@@ -0,0 +1,91 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type TenantTestModel struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TenantID string    `gorm:"uniqueIndex:idx_tenant_test_models_email"`
+	Email    string    `gorm:"uniqueIndex:idx_tenant_test_models_email"`
+}
+
+func TestTenantScope(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	assert.NoError(t, err)
+	var statements []string
+	capture := func(db *gorm.DB) {
+		statements = append(statements, strings.TrimSpace(db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)))
+	}
+	cb := db.Callback()
+	assert.NoError(t, cb.Create().After("*").Register("test:capture", capture))
+	assert.NoError(t, cb.Query().After("*").Register("test:capture", capture))
+	assert.NoError(t, cb.Update().After("*").Register("test:capture", capture))
+	assert.NoError(t, cb.Delete().After("*").Register("test:capture", capture))
+	run := func(f func() error) []string {
+		statements = nil
+		assert.NoError(t, f())
+		return statements
+	}
+
+	store := storage.New[TenantTestModel](db)
+	ctx := storage.WithTenant(context.Background(), "acme")
+	id := uuid.New()
+	model := TenantTestModel{ID: id, TenantID: "globex", Email: "a@test.com"}
+
+	sql := run(func() error { return store.Create(ctx, &model) })
+	assert.Equal(t, "acme", model.TenantID)
+	assert.Equal(t, []string{`INSERT INTO "tenant_test_models" ("id","tenant_id","email") VALUES ('` + id.String() + `','acme','a@test.com')`}, sql)
+
+	sql = run(func() error { _, err := store.GetByID(ctx, id, true); return err })
+	assert.Equal(t, []string{`SELECT * FROM "tenant_test_models" WHERE "tenant_test_models"."tenant_id" = 'acme' AND id = '` + id.String() + `' ORDER BY "tenant_test_models"."id" LIMIT 1 FOR UPDATE`}, sql)
+
+	sql = run(func() error {
+		_, err := store.Find(ctx, storage.Query{Where: storage.Eq("email", "a@test.com")})
+		return err
+	})
+	assert.Equal(t, []string{`SELECT * FROM "tenant_test_models" WHERE "tenant_test_models"."tenant_id" = 'acme' AND "email" = 'a@test.com'`}, sql)
+
+	// the update doesn't upsert over the row of another tenant, it inserts when no row of the tenant was updated
+	model.TenantID = "globex"
+	sql = run(func() error { return store.Update(ctx, &model) })
+	assert.Equal(t, "acme", model.TenantID)
+	assert.Equal(t, []string{
+		`UPDATE "tenant_test_models" SET "tenant_id"='acme',"email"='a@test.com' WHERE "tenant_test_models"."tenant_id" = 'acme' AND "id" = '` + id.String() + `'`,
+		`INSERT INTO "tenant_test_models" ("id","tenant_id","email") VALUES ('` + id.String() + `','acme','a@test.com')`,
+	}, sql)
+
+	sql = run(func() error { return store.Delete(ctx, &model) })
+	assert.Equal(t, []string{`DELETE FROM "tenant_test_models" WHERE "tenant_test_models"."tenant_id" = 'acme' AND "tenant_test_models"."id" = '` + id.String() + `'`}, sql)
+
+	sql = run(func() error {
+		_, err := store.UpdateWhere(ctx, storage.Eq("email", "a@test.com"), map[string]any{"email": "b@test.com"})
+		return err
+	})
+	assert.Equal(t, []string{`UPDATE "tenant_test_models" SET "email"='b@test.com' WHERE "tenant_test_models"."tenant_id" = 'acme' AND "email" = 'a@test.com'`}, sql)
+
+	sql = run(func() error {
+		_, err := store.UpsertMany(ctx, []TenantTestModel{model}, storage.UpsertOptions{UpdateColumns: []string{"email"}})
+		return err
+	})
+	assert.Equal(t, []string{`INSERT INTO "tenant_test_models" ("id","tenant_id","email") VALUES ('` + id.String() + `','acme','a@test.com') ON CONFLICT ("id") DO UPDATE SET "email"="excluded"."email" WHERE "tenant_test_models"."tenant_id" = 'acme'`}, sql)
+
+	sql = run(func() error { _, err := store.Count(ctx, storage.Ne("email", "")); return err })
+	assert.Equal(t, []string{`SELECT count(*) FROM "tenant_test_models" WHERE "tenant_test_models"."tenant_id" = 'acme' AND "email" <> ''`}, sql)
+
+	// a context without tenant is not scoped
+	sql = run(func() error { _, err := store.GetAll(context.Background(), nil); return err })
+	assert.Equal(t, []string{`SELECT * FROM "tenant_test_models"`}, sql)
+}
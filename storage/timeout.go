@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const statementTimeoutName = "storage:statement_timeout"
+
+// pgQueryCanceled is the SQLSTATE of the statements canceled by the statement timeout.
+const pgQueryCanceled = "57014"
+
+// ErrTimeout is returned when an operation of a store is canceled by its statement timeout or by the deadline
+// of its context.
+var ErrTimeout = errors.New("operation timed out")
+
+// StatementTimeout is a GORM plugin setting the default statement timeout of the operations of the CRUDStores,
+// WithStatementTimeout overrides it for a single call.
+type StatementTimeout struct {
+	Timeout time.Duration
+}
+
+var _ gorm.Plugin = StatementTimeout{}
+
+// Name implements gorm.Plugin.
+func (StatementTimeout) Name() string {
+	return statementTimeoutName
+}
+
+// Initialize implements gorm.Plugin.
+func (StatementTimeout) Initialize(*gorm.DB) error {
+	return nil
+}
+
+type statementTimeoutKey struct{}
+
+// WithStatementTimeout returns a context that makes the operations of the stores time out after d instead of the
+// default statement timeout, zero disables the timeout.
+func WithStatementTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutKey{}, d)
+}
+
+// statementTimeout returns the statement timeout of the operations run with ctx on db.
+func statementTimeout(ctx context.Context, db *gorm.DB) time.Duration {
+	if d, ok := ctx.Value(statementTimeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	if p, ok := db.Config.Plugins[statementTimeoutName].(StatementTimeout); ok {
+		return p.Timeout
+	}
+	return 0
+}
+
+// timed runs op with the statement timeout of the context, in the transaction carried by the context if any.
+// On postgres the timeout is set with SET LOCAL in the transaction of the store, and restored after op, unless
+// ApplyStatementTimeout already set it for the transaction. A store that isn't bound to a transaction runs the
+// writes and the locking reads in a new transaction on the primary. The other reads get a deadline on their
+// context instead, like on the databases without statement timeout: dbresolver doesn't route the transactions,
+// so a transaction would move them from the replicas to the primary.
+//
+// Stream isn't timed since it would hold the transaction open while the caller iterates.
+func (c CRUDStore[T]) timed(ctx context.Context, read bool, op func(context.Context, CRUDStore[T]) error) error {
+	c = c.joined(ctx)
+	d := statementTimeout(ctx, c.db)
+	switch {
+	case d <= 0:
+		return timeoutError(ctx, op(ctx, c), false)
+	case c.db.Dialector.Name() != DriverPostgres:
+		return c.deadline(ctx, d, op)
+	case inTransaction(c.db):
+		var previous any
+		if applied, ok := appliedStatementTimeout(ctx, c.db); ok {
+			if applied == d {
+				return timeoutError(ctx, op(ctx, c), true)
+			}
+			previous = applied.Milliseconds()
+		} else {
+			var setting string
+			if err := c.db.WithContext(ctx).Raw("SELECT current_setting('statement_timeout')").Scan(&setting).Error; err != nil {
+				return err
+			}
+			previous = setting
+		}
+		if err := setStatementTimeout(ctx, c.db, d.Milliseconds()); err != nil {
+			return err
+		}
+		err := op(ctx, c)
+		// restoring fails when err aborted the transaction, its rollback reverts the setting anyway
+		if restoreErr := setStatementTimeout(context.WithoutCancel(ctx), c.db, previous); err == nil {
+			err = restoreErr
+		}
+		return timeoutError(ctx, err, true)
+	case read && !usePrimary(ctx):
+		return c.deadline(ctx, d, op)
+	}
+	return timeoutError(ctx, c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := setStatementTimeout(ctx, tx, d.Milliseconds()); err != nil {
+			return err
+		}
+		return op(ctx, CRUDStore[T]{db: tx})
+	}), true)
+}
+
+// ApplyStatementTimeout sets the statement timeout of the operations run with ctx for the rest of the
+// transaction carried by ctx, see WithTx, and returns ctx recording it. The stores joining the transaction
+// with the same timeout then don't set it around each of their operations. It does nothing without
+// transaction, without timeout or on the databases without statement timeout.
+func ApplyStatementTimeout(ctx context.Context) (context.Context, error) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	if !ok || tc.db == nil || tc.db.Dialector.Name() != DriverPostgres {
+		return ctx, nil
+	}
+	d := statementTimeout(ctx, tc.db)
+	if d <= 0 {
+		return ctx, nil
+	}
+	if err := setStatementTimeout(ctx, tc.db, d.Milliseconds()); err != nil {
+		return ctx, err
+	}
+	tc.timeout = &d
+	return context.WithValue(ctx, txKey{}, tc), nil
+}
+
+// appliedStatementTimeout returns the statement timeout set by ApplyStatementTimeout for the transaction of
+// db, false when it isn't known.
+func appliedStatementTimeout(ctx context.Context, db *gorm.DB) (time.Duration, bool) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	if !ok || tc.timeout == nil || !sameTransaction(tc.db, db) {
+		return 0, false
+	}
+	return *tc.timeout, true
+}
+
+// deadline runs op with a context canceled after d.
+func (c CRUDStore[T]) deadline(ctx context.Context, d time.Duration, op func(context.Context, CRUDStore[T]) error) error {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	return timeoutError(ctx, op(ctx, c), false)
+}
+
+// timedResult is timed for the operations returning a result.
+func timedResult[T, R any](ctx context.Context, c CRUDStore[T], read bool, op func(context.Context, CRUDStore[T]) (R, error)) (R, error) {
+	var result R
+	err := c.timed(ctx, read, func(ctx context.Context, c CRUDStore[T]) error {
+		var err error
+		result, err = op(ctx, c)
+		return err
+	})
+	return result, err
+}
+
+// setStatementTimeout sets the statement timeout until the end of the transaction, like SET LOCAL does.
+// set_config is used since SET doesn't accept parameters.
+func setStatementTimeout(ctx context.Context, tx *gorm.DB, timeout any) error {
+	return tx.WithContext(ctx).Exec("SELECT set_config('statement_timeout', ?, true)", fmt.Sprint(timeout)).Error
+}
+
+// locks reports whether the SQLModifier locks the rows it reads.
+func locks(db *gorm.DB, m SQLModifier) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m(db.Session(&gorm.Session{NewDB: true})).Statement.Clauses[clause.Locking{}.Name()]
+	return ok
+}
+
+// timeoutError wraps into ErrTimeout the errors of the statements canceled by the deadline of their context, or
+// by the statement timeout when the store set one. The other cancellations, by the context of the caller or by
+// pg_cancel_backend, are returned unchanged.
+func timeoutError(ctx context.Context, err error, timed bool) error {
+	if err == nil || errors.Is(err, ErrTimeout) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	var pgErr *pgconn.PgError
+	canceled := errors.As(err, &pgErr) && pgErr.Code == pgQueryCanceled
+	if errors.Is(err, context.DeadlineExceeded) || canceled && (timed && ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/exp/slog"
+	"gorm.io/gorm"
+)
+
+// The SQLSTATE of the postgres errors that abort a transaction which can succeed when run again.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// IsRetryable reports whether err is a serialization failure or a deadlock, the transaction that failed with it
+// can be run again from the start.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
+
+// RetryPolicy configures how the transactions failing with a retryable error are run again.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times the transaction is run, the first one included. Zero means 1.
+	MaxAttempts int
+	// BaseDelay is the upper bound of the random wait before the first retry, it doubles after every attempt
+	// without exceeding MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy runs a transaction up to 5 times, waiting up to 10ms before the first retry.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// backoff returns the jittered wait before the retry following the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+// Retry runs fn until it succeeds, fails with an error that isn't retryable or the policy runs out of attempts.
+// The last error is returned, the wait between the attempts is interrupted when the context is done.
+func Retry(ctx context.Context, p RetryPolicy, fn func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+		delay := p.backoff(attempt)
+		slog.Warn("retrying transaction", "attempt", attempt, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// inTransaction reports whether db is bound to a transaction.
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// sameTransaction reports whether a and b are bound to the same transaction.
+func sameTransaction(a, b *gorm.DB) bool {
+	return inTransaction(a) && a.Statement.ConnPool == b.Statement.ConnPool
+}
+
+// ParseIsolationLevel parses the name of an isolation level, such as "serializable" or "repeatable read".
+// The empty string is sql.LevelDefault.
+func ParseIsolationLevel(s string) (sql.IsolationLevel, error) {
+	name := strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-'
+	}), " ")
+	for _, level := range []sql.IsolationLevel{
+		sql.LevelDefault,
+		sql.LevelReadUncommitted,
+		sql.LevelReadCommitted,
+		sql.LevelRepeatableRead,
+		sql.LevelSerializable,
+	} {
+		if name == strings.ToLower(level.String()) || name == "" && level == sql.LevelDefault {
+			return level, nil
+		}
+	}
+	return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", s)
+}
+
+type txKey struct{}
+
+// txContext is the transaction carried by a context.
+type txContext struct {
+	db    *gorm.DB
+	hooks *txHooks
+	// timeout is the statement timeout set for the rest of the transaction by ApplyStatementTimeout, nil when
+	// it isn't known.
+	timeout *time.Duration
+}
+
+// txHooks are the functions to run once the changes made in a transaction, or in a transaction nested in it,
+// are committed or rolled back.
+type txHooks struct {
+	mu sync.Mutex
+	// parent are the hooks of the enclosing transaction, nil for the outermost one.
+	parent        *txHooks
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+// take returns the hooks and forgets them, so they run once.
+func (h *txHooks) take() (afterCommit, afterRollback []func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	afterCommit, afterRollback = h.afterCommit, h.afterRollback
+	h.afterCommit, h.afterRollback = nil, nil
+	return afterCommit, afterRollback
+}
+
+// WithTx returns a context carrying the transaction tx. The CRUDStores that aren't bound to a transaction run
+// their operations in tx when they are called with that context, so the repositories built on them join the
+// transaction of their caller. The session package uses it to propagate its transactions.
+//
+// When ctx already carries a transaction, tx is nested in it: the hooks registered with AfterCommit and
+// AfterRollback on the returned context move to the enclosing transaction when the nested one commits.
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	tc := txContext{db: tx, hooks: &txHooks{}}
+	if parent, ok := ctx.Value(txKey{}).(txContext); ok && parent.db != nil {
+		tc.hooks.parent = parent.hooks
+		if sameTransaction(parent.db, tx) {
+			tc.timeout = parent.timeout
+		}
+	}
+	return context.WithValue(ctx, txKey{}, tc)
+}
+
+// WithTxFrom returns ctx carrying the transaction carried by from, along with its hooks, so a transaction
+// nested in it with ctx bubbles its hooks up to it.
+func WithTxFrom(ctx, from context.Context) context.Context {
+	tc, ok := from.Value(txKey{}).(txContext)
+	if !ok || tc.db == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, txKey{}, tc)
+}
+
+// TxFromContext returns the transaction carried by ctx, see WithTx.
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	return tc.db, ok && tc.db != nil
+}
+
+// AfterCommit registers fn to run once the outermost transaction carried by ctx committed the changes made in
+// the transaction of ctx, fn never runs when they are rolled back. fn runs right away when ctx carries no
+// transaction.
+//
+// fn gets ctx without its transaction and its cancellation, so it can use the stores.
+func AfterCommit(ctx context.Context, fn func(context.Context)) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	if !ok || tc.db == nil {
+		fn(ctx)
+		return
+	}
+	tc.hooks.mu.Lock()
+	defer tc.hooks.mu.Unlock()
+	tc.hooks.afterCommit = append(tc.hooks.afterCommit, bindHook(ctx, fn))
+}
+
+// AfterRollback registers fn to run once the changes made in the transaction carried by ctx are rolled back,
+// by the rollback of that transaction or of an enclosing one. fn never runs when ctx carries no transaction.
+//
+// fn gets ctx without its transaction and its cancellation, so it can use the stores.
+func AfterRollback(ctx context.Context, fn func(context.Context)) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	if !ok || tc.db == nil {
+		return
+	}
+	tc.hooks.mu.Lock()
+	defer tc.hooks.mu.Unlock()
+	tc.hooks.afterRollback = append(tc.hooks.afterRollback, bindHook(ctx, fn))
+}
+
+// bindHook binds fn to ctx, without its transaction and its cancellation.
+func bindHook(ctx context.Context, fn func(context.Context)) func() {
+	ctx = context.WithValue(context.WithoutCancel(ctx), txKey{}, txContext{})
+	return func() {
+		fn(ctx)
+	}
+}
+
+// Committed is called by the owner of the transaction carried by ctx once it committed. The hooks of a nested
+// transaction move to the enclosing one, the AfterCommit functions of the outermost transaction run.
+func Committed(ctx context.Context) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	if !ok || tc.db == nil {
+		return
+	}
+	afterCommit, afterRollback := tc.hooks.take()
+	if parent := tc.hooks.parent; parent != nil {
+		parent.mu.Lock()
+		defer parent.mu.Unlock()
+		parent.afterCommit = append(parent.afterCommit, afterCommit...)
+		parent.afterRollback = append(parent.afterRollback, afterRollback...)
+		return
+	}
+	for _, fn := range afterCommit {
+		fn()
+	}
+}
+
+// RolledBack is called by the owner of the transaction carried by ctx once it rolled back, or failed to
+// commit. The AfterRollback functions of the transaction and of the transactions committed in it run.
+func RolledBack(ctx context.Context) {
+	tc, ok := ctx.Value(txKey{}).(txContext)
+	if !ok || tc.db == nil {
+		return
+	}
+	_, afterRollback := tc.hooks.take()
+	for _, fn := range afterRollback {
+		fn()
+	}
+}
+
+// joined returns the store running its operations in the transaction carried by ctx, unless the store is
+// already bound to a transaction.
+func (c CRUDStore[T]) joined(ctx context.Context) CRUDStore[T] {
+	if tx, ok := TxFromContext(ctx); ok && !inTransaction(c.db) {
+		return CRUDStore[T]{db: tx}
+	}
+	return c
+}
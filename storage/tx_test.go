@@ -0,0 +1,171 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zechao158/ecomm/storage"
+	"gorm.io/gorm"
+)
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	serialization := &pgconn.PgError{Code: "40001", Message: "could not serialize access"}
+
+	t.Run("IsRetryable", func(t *testing.T) {
+		assert.True(t, storage.IsRetryable(serialization))
+		assert.True(t, storage.IsRetryable(fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40P01"})))
+		assert.False(t, storage.IsRetryable(&pgconn.PgError{Code: "23505"}))
+		assert.False(t, storage.IsRetryable(errors.New("failure")))
+	})
+
+	t.Run("stops waiting when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		attempts := 0
+		err := storage.Retry(ctx, storage.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}, func(context.Context) error {
+			attempts++
+			cancel()
+			return serialization
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, err, serialization)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestParseIsolationLevel(t *testing.T) {
+	for s, expected := range map[string]sql.IsolationLevel{
+		"":                sql.LevelDefault,
+		"serializable":    sql.LevelSerializable,
+		"REPEATABLE READ": sql.LevelRepeatableRead,
+		"read_committed":  sql.LevelReadCommitted,
+	} {
+		level, err := storage.ParseIsolationLevel(s)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, level, s)
+	}
+	_, err := storage.ParseIsolationLevel("snapshot")
+	assert.Error(t, err)
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewStorage(storage.Config{
+		Driver: storage.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TestModel{}, &storage.AuditEntry{}))
+	store := storage.New[TestModel](db)
+	rollback := errors.New("rollback")
+
+	t.Run("the stores join the transaction of the context", func(t *testing.T) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			ctx := storage.WithTx(ctx, tx)
+			joined, ok := storage.TxFromContext(ctx)
+			assert.True(t, ok)
+			assert.Same(t, tx, joined)
+			if err := store.Create(ctx, &TestModel{ID: uuid.New(), Name: "joined"}); err != nil {
+				return err
+			}
+			n, err := store.Count(ctx, storage.Eq("name", "joined"))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+			return rollback
+		})
+		assert.ErrorIs(t, err, rollback)
+		n, err := store.Count(ctx, storage.Eq("name", "joined"))
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		_, ok := storage.TxFromContext(ctx)
+		assert.False(t, ok)
+	})
+
+	t.Run("AfterCommit and AfterRollback", func(t *testing.T) {
+		var ran []string
+		hook := func(name string) func(context.Context) {
+			return func(ctx context.Context) {
+				_, inTx := storage.TxFromContext(ctx)
+				assert.False(t, inTx, "the hooks run without the transaction")
+				ran = append(ran, name)
+			}
+		}
+		storage.AfterCommit(ctx, hook("no transaction"))
+		storage.AfterRollback(ctx, hook("never"))
+		assert.Equal(t, []string{"no transaction"}, ran, "runs right away without transaction")
+		ran = nil
+
+		outer := storage.WithTx(ctx, db)
+		storage.AfterCommit(outer, hook("outer"))
+		committed := storage.WithTx(outer, db)
+		storage.AfterCommit(committed, hook("committed"))
+		storage.AfterRollback(committed, hook("committed rolled back"))
+		rolledBack := storage.WithTx(outer, db)
+		storage.AfterCommit(rolledBack, hook("never"))
+		storage.AfterRollback(rolledBack, hook("rolled back"))
+
+		// the hooks of a committed nested transaction bubble up to the enclosing one
+		storage.Committed(committed)
+		assert.Empty(t, ran)
+		storage.RolledBack(rolledBack)
+		assert.Equal(t, []string{"rolled back"}, ran)
+
+		ran = nil
+		storage.Committed(outer)
+		assert.Equal(t, []string{"outer", "committed"}, ran)
+		storage.Committed(outer)
+		storage.RolledBack(outer)
+		assert.Equal(t, []string{"outer", "committed"}, ran, "the hooks run once")
+
+		ran = nil
+		outer = storage.WithTx(ctx, db)
+		committed = storage.WithTx(outer, db)
+		storage.AfterCommit(committed, hook("never"))
+		storage.AfterRollback(committed, hook("committed rolled back"))
+		storage.Committed(committed)
+		storage.RolledBack(outer)
+		assert.Equal(t, []string{"committed rolled back"}, ran)
+	})
+
+	t.Run("CachedStore", func(t *testing.T) {
+		cached := storage.NewCached[TestModel](store, storage.NewLRUCache(10, 0))
+		model := TestModel{ID: uuid.New(), Name: "cached"}
+		require.NoError(t, store.Create(ctx, &model))
+		_, err := cached.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			ctx := storage.WithTx(ctx, tx)
+			model.Name = "renamed"
+			if err := cached.Update(ctx, &model); err != nil {
+				return err
+			}
+			// the reads of the transaction see its changes
+			read, err := cached.GetByID(ctx, model.ID, false)
+			assert.NoError(t, err)
+			assert.Equal(t, "renamed", read.Name)
+			// the cache isn't invalidated before the commit
+			assert.Zero(t, cached.Stats().Invalidations)
+			return rollback
+		})
+		assert.ErrorIs(t, err, rollback)
+		assert.Zero(t, cached.Stats().Invalidations)
+		read, err := cached.GetByID(ctx, model.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "cached", read.Name)
+
+		txCtx := storage.WithTx(ctx, db)
+		assert.NoError(t, cached.Delete(txCtx, &model))
+		storage.Committed(txCtx)
+		assert.Equal(t, uint64(1), cached.Stats().Invalidations)
+	})
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// VersionColumn is the column used for optimistic locking. Entities with an integer field
+// mapped to this column are versioned: every Update checks that the row still has the version
+// held by the entity and increments it, a mismatch returns ErrStaleObject.
+const VersionColumn = "version"
+
+// parseSchema returns the GORM schema of T, schemas are cached by GORM so it's cheap to call on every operation.
+func parseSchema[T any](db *gorm.DB) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// versionField returns the version field of the schema, or nil if the entity is not versioned.
+func versionField(s *schema.Schema) *schema.Field {
+	f := s.LookUpField(VersionColumn)
+	if f == nil {
+		return nil
+	}
+	switch f.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f
+	}
+	return nil
+}
+
+// initVersion sets the version of a new versioned entity to 1 when it's not set.
+func initVersion[T any](ctx context.Context, s *schema.Schema, t *T) error {
+	vf := versionField(s)
+	if vf == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(t).Elem()
+	if _, zero := vf.ValueOf(ctx, rv); zero {
+		return vf.Set(ctx, rv, 1)
+	}
+	return nil
+}
+
+// updateVersioned updates all the fields of t only if the stored row has the same version as t,
+// on success the version of t is incremented.
+func updateVersioned[T any](ctx context.Context, db *gorm.DB, s *schema.Schema, vf *schema.Field, t *T) error {
+	rv := reflect.ValueOf(t).Elem()
+	current := vf.ReflectValueOf(ctx, rv).Int()
+	if err := vf.Set(ctx, rv, current+1); err != nil {
+		return err
+	}
+
+	r := db.WithContext(ctx).Model(t).
+		Where(clause.Eq{Column: clause.Column{Name: vf.DBName}, Value: current}).
+		Select("*").
+		Updates(t)
+	if r.Error == nil && r.RowsAffected == 1 {
+		return nil
+	}
+	// the update didn't happen, so the entity keeps the version it had.
+	if err := vf.Set(ctx, rv, current); err != nil {
+		return err
+	}
+	if r.Error != nil {
+		return r.Error
+	}
+
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return ErrStaleObject
+	}
+	id, _ := pk.ValueOf(ctx, rv)
+	var count int64
+	if err := db.WithContext(ctx).Model(new(T)).Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: id}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrRecordNotFound
+	}
+	return fmt.Errorf("%w: version %d is outdated", ErrStaleObject, current)
+}
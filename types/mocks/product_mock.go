@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/zechao158/ecomm/storage"
 	"github.com/zechao158/ecomm/types"
+	"iter"
 	"sync"
 )
 
@@ -21,12 +22,30 @@ var _ types.ProductRepository = &MockProductRepository{}
 //
 //		// make and configure a mocked types.ProductRepository
 //		mockedProductRepository := &MockProductRepository{
+//			CountFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the Count method")
+//			},
+//			CountByFunc: func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+//				panic("mock out the CountBy method")
+//			},
 //			CreateFunc: func(contextMoqParam context.Context, product *types.Product) error {
 //				panic("mock out the Create method")
 //			},
+//			CreateManyFunc: func(ctx context.Context, ts []types.Product, batchSize int) (int64, error) {
+//				panic("mock out the CreateMany method")
+//			},
 //			DeleteFunc: func(contextMoqParam context.Context, product *types.Product) error {
 //				panic("mock out the Delete method")
 //			},
+//			DeleteWhereFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the DeleteWhere method")
+//			},
+//			ExistsFunc: func(ctx context.Context, filter storage.Predicate) (bool, error) {
+//				panic("mock out the Exists method")
+//			},
+//			FindFunc: func(contextMoqParam context.Context, query storage.Query) ([]types.Product, error) {
+//				panic("mock out the Find method")
+//			},
 //			GetAllFunc: func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.Product, error) {
 //				panic("mock out the GetAll method")
 //			},
@@ -36,12 +55,42 @@ var _ types.ProductRepository = &MockProductRepository{}
 //			GetByIDFunc: func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.Product, error) {
 //				panic("mock out the GetByID method")
 //			},
-//			GetProductsByIDsFunc: func(contextMoqParam context.Context, uUIDs []uuid.UUID) ([]types.Product, error) {
+//			GetPageFunc: func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.Product], error) {
+//				panic("mock out the GetPage method")
+//			},
+//			GetProductsByIDsFunc: func(ctx context.Context, ids []uuid.UUID, forUpdate bool) ([]types.Product, error) {
 //				panic("mock out the GetProductsByIDs method")
 //			},
+//			GetTotalInStockFunc: func(ctx context.Context) (int64, error) {
+//				panic("mock out the GetTotalInStock method")
+//			},
+//			MaxFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Max method")
+//			},
+//			MinFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Min method")
+//			},
+//			PurgeFunc: func(contextMoqParam context.Context, product *types.Product) error {
+//				panic("mock out the Purge method")
+//			},
+//			RestoreFunc: func(ctx context.Context, id uuid.UUID) error {
+//				panic("mock out the Restore method")
+//			},
+//			StreamFunc: func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.Product, error] {
+//				panic("mock out the Stream method")
+//			},
+//			SumFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Sum method")
+//			},
 //			UpdateFunc: func(contextMoqParam context.Context, product *types.Product) error {
 //				panic("mock out the Update method")
 //			},
+//			UpdateWhereFunc: func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+//				panic("mock out the UpdateWhere method")
+//			},
+//			UpsertManyFunc: func(ctx context.Context, ts []types.Product, opts storage.UpsertOptions) (int64, error) {
+//				panic("mock out the UpsertMany method")
+//			},
 //		}
 //
 //		// use mockedProductRepository in code that requires types.ProductRepository
@@ -49,12 +98,30 @@ var _ types.ProductRepository = &MockProductRepository{}
 //
 //	}
 type MockProductRepository struct {
+	// CountFunc mocks the Count method.
+	CountFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// CountByFunc mocks the CountBy method.
+	CountByFunc func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error)
+
 	// CreateFunc mocks the Create method.
 	CreateFunc func(contextMoqParam context.Context, product *types.Product) error
 
+	// CreateManyFunc mocks the CreateMany method.
+	CreateManyFunc func(ctx context.Context, ts []types.Product, batchSize int) (int64, error)
+
 	// DeleteFunc mocks the Delete method.
 	DeleteFunc func(contextMoqParam context.Context, product *types.Product) error
 
+	// DeleteWhereFunc mocks the DeleteWhere method.
+	DeleteWhereFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// ExistsFunc mocks the Exists method.
+	ExistsFunc func(ctx context.Context, filter storage.Predicate) (bool, error)
+
+	// FindFunc mocks the Find method.
+	FindFunc func(contextMoqParam context.Context, query storage.Query) ([]types.Product, error)
+
 	// GetAllFunc mocks the GetAll method.
 	GetAllFunc func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.Product, error)
 
@@ -64,14 +131,60 @@ type MockProductRepository struct {
 	// GetByIDFunc mocks the GetByID method.
 	GetByIDFunc func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.Product, error)
 
+	// GetPageFunc mocks the GetPage method.
+	GetPageFunc func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.Product], error)
+
 	// GetProductsByIDsFunc mocks the GetProductsByIDs method.
-	GetProductsByIDsFunc func(contextMoqParam context.Context, uUIDs []uuid.UUID) ([]types.Product, error)
+	GetProductsByIDsFunc func(ctx context.Context, ids []uuid.UUID, forUpdate bool) ([]types.Product, error)
+
+	// GetTotalInStockFunc mocks the GetTotalInStock method.
+	GetTotalInStockFunc func(ctx context.Context) (int64, error)
+
+	// MaxFunc mocks the Max method.
+	MaxFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// MinFunc mocks the Min method.
+	MinFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// PurgeFunc mocks the Purge method.
+	PurgeFunc func(contextMoqParam context.Context, product *types.Product) error
+
+	// RestoreFunc mocks the Restore method.
+	RestoreFunc func(ctx context.Context, id uuid.UUID) error
+
+	// StreamFunc mocks the Stream method.
+	StreamFunc func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.Product, error]
+
+	// SumFunc mocks the Sum method.
+	SumFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
 
 	// UpdateFunc mocks the Update method.
 	UpdateFunc func(contextMoqParam context.Context, product *types.Product) error
 
+	// UpdateWhereFunc mocks the UpdateWhere method.
+	UpdateWhereFunc func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error)
+
+	// UpsertManyFunc mocks the UpsertMany method.
+	UpsertManyFunc func(ctx context.Context, ts []types.Product, opts storage.UpsertOptions) (int64, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// Count holds details about calls to the Count method.
+		Count []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// CountBy holds details about calls to the CountBy method.
+		CountBy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
 		// Create holds details about calls to the Create method.
 		Create []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -79,6 +192,15 @@ type MockProductRepository struct {
 			// Product is the product argument value.
 			Product *types.Product
 		}
+		// CreateMany holds details about calls to the CreateMany method.
+		CreateMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.Product
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
 		// Delete holds details about calls to the Delete method.
 		Delete []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -86,6 +208,27 @@ type MockProductRepository struct {
 			// Product is the product argument value.
 			Product *types.Product
 		}
+		// DeleteWhere holds details about calls to the DeleteWhere method.
+		DeleteWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Exists holds details about calls to the Exists method.
+		Exists []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Find holds details about calls to the Find method.
+		Find []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Query is the query argument value.
+			Query storage.Query
+		}
 		// GetAll holds details about calls to the GetAll method.
 		GetAll []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -111,12 +254,78 @@ type MockProductRepository struct {
 			// ForUpdate is the forUpdate argument value.
 			ForUpdate bool
 		}
+		// GetPage holds details about calls to the GetPage method.
+		GetPage []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// PageRequest is the pageRequest argument value.
+			PageRequest storage.PageRequest
+			// Predicate is the predicate argument value.
+			Predicate storage.Predicate
+		}
 		// GetProductsByIDs holds details about calls to the GetProductsByIDs method.
 		GetProductsByIDs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ids is the ids argument value.
+			Ids []uuid.UUID
+			// ForUpdate is the forUpdate argument value.
+			ForUpdate bool
+		}
+		// GetTotalInStock holds details about calls to the GetTotalInStock method.
+		GetTotalInStock []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Max holds details about calls to the Max method.
+		Max []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Min holds details about calls to the Min method.
+		Min []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Purge holds details about calls to the Purge method.
+		Purge []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
 			ContextMoqParam context.Context
-			// UUIDs is the uUIDs argument value.
-			UUIDs []uuid.UUID
+			// Product is the product argument value.
+			Product *types.Product
+		}
+		// Restore holds details about calls to the Restore method.
+		Restore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// Stream holds details about calls to the Stream method.
+		Stream []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// M is the m argument value.
+			M storage.SQLModifier
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
+		// Sum holds details about calls to the Sum method.
+		Sum []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
 		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
@@ -125,14 +334,124 @@ type MockProductRepository struct {
 			// Product is the product argument value.
 			Product *types.Product
 		}
+		// UpdateWhere holds details about calls to the UpdateWhere method.
+		UpdateWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+			// Values is the values argument value.
+			Values map[string]any
+		}
+		// UpsertMany holds details about calls to the UpsertMany method.
+		UpsertMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.Product
+			// Opts is the opts argument value.
+			Opts storage.UpsertOptions
+		}
 	}
+	lockCount            sync.RWMutex
+	lockCountBy          sync.RWMutex
 	lockCreate           sync.RWMutex
+	lockCreateMany       sync.RWMutex
 	lockDelete           sync.RWMutex
+	lockDeleteWhere      sync.RWMutex
+	lockExists           sync.RWMutex
+	lockFind             sync.RWMutex
 	lockGetAll           sync.RWMutex
 	lockGetByFields      sync.RWMutex
 	lockGetByID          sync.RWMutex
+	lockGetPage          sync.RWMutex
 	lockGetProductsByIDs sync.RWMutex
+	lockGetTotalInStock  sync.RWMutex
+	lockMax              sync.RWMutex
+	lockMin              sync.RWMutex
+	lockPurge            sync.RWMutex
+	lockRestore          sync.RWMutex
+	lockStream           sync.RWMutex
+	lockSum              sync.RWMutex
 	lockUpdate           sync.RWMutex
+	lockUpdateWhere      sync.RWMutex
+	lockUpsertMany       sync.RWMutex
+}
+
+// Count calls CountFunc.
+func (mock *MockProductRepository) Count(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.CountFunc == nil {
+		panic("MockProductRepository.CountFunc: method is nil but ProductRepository.Count was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockCount.Lock()
+	mock.calls.Count = append(mock.calls.Count, callInfo)
+	mock.lockCount.Unlock()
+	return mock.CountFunc(ctx, filter)
+}
+
+// CountCalls gets all the calls that were made to Count.
+// Check the length with:
+//
+//	len(mockedProductRepository.CountCalls())
+func (mock *MockProductRepository) CountCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockCount.RLock()
+	calls = mock.calls.Count
+	mock.lockCount.RUnlock()
+	return calls
+}
+
+// CountBy calls CountByFunc.
+func (mock *MockProductRepository) CountBy(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+	if mock.CountByFunc == nil {
+		panic("MockProductRepository.CountByFunc: method is nil but ProductRepository.CountBy was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockCountBy.Lock()
+	mock.calls.CountBy = append(mock.calls.CountBy, callInfo)
+	mock.lockCountBy.Unlock()
+	return mock.CountByFunc(ctx, column, filter)
+}
+
+// CountByCalls gets all the calls that were made to CountBy.
+// Check the length with:
+//
+//	len(mockedProductRepository.CountByCalls())
+func (mock *MockProductRepository) CountByCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockCountBy.RLock()
+	calls = mock.calls.CountBy
+	mock.lockCountBy.RUnlock()
+	return calls
 }
 
 // Create calls CreateFunc.
@@ -171,6 +490,46 @@ func (mock *MockProductRepository) CreateCalls() []struct {
 	return calls
 }
 
+// CreateMany calls CreateManyFunc.
+func (mock *MockProductRepository) CreateMany(ctx context.Context, ts []types.Product, batchSize int) (int64, error) {
+	if mock.CreateManyFunc == nil {
+		panic("MockProductRepository.CreateManyFunc: method is nil but ProductRepository.CreateMany was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Ts        []types.Product
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		Ts:        ts,
+		BatchSize: batchSize,
+	}
+	mock.lockCreateMany.Lock()
+	mock.calls.CreateMany = append(mock.calls.CreateMany, callInfo)
+	mock.lockCreateMany.Unlock()
+	return mock.CreateManyFunc(ctx, ts, batchSize)
+}
+
+// CreateManyCalls gets all the calls that were made to CreateMany.
+// Check the length with:
+//
+//	len(mockedProductRepository.CreateManyCalls())
+func (mock *MockProductRepository) CreateManyCalls() []struct {
+	Ctx       context.Context
+	Ts        []types.Product
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Ts        []types.Product
+		BatchSize int
+	}
+	mock.lockCreateMany.RLock()
+	calls = mock.calls.CreateMany
+	mock.lockCreateMany.RUnlock()
+	return calls
+}
+
 // Delete calls DeleteFunc.
 func (mock *MockProductRepository) Delete(contextMoqParam context.Context, product *types.Product) error {
 	if mock.DeleteFunc == nil {
@@ -207,6 +566,114 @@ func (mock *MockProductRepository) DeleteCalls() []struct {
 	return calls
 }
 
+// DeleteWhere calls DeleteWhereFunc.
+func (mock *MockProductRepository) DeleteWhere(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.DeleteWhereFunc == nil {
+		panic("MockProductRepository.DeleteWhereFunc: method is nil but ProductRepository.DeleteWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockDeleteWhere.Lock()
+	mock.calls.DeleteWhere = append(mock.calls.DeleteWhere, callInfo)
+	mock.lockDeleteWhere.Unlock()
+	return mock.DeleteWhereFunc(ctx, filter)
+}
+
+// DeleteWhereCalls gets all the calls that were made to DeleteWhere.
+// Check the length with:
+//
+//	len(mockedProductRepository.DeleteWhereCalls())
+func (mock *MockProductRepository) DeleteWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockDeleteWhere.RLock()
+	calls = mock.calls.DeleteWhere
+	mock.lockDeleteWhere.RUnlock()
+	return calls
+}
+
+// Exists calls ExistsFunc.
+func (mock *MockProductRepository) Exists(ctx context.Context, filter storage.Predicate) (bool, error) {
+	if mock.ExistsFunc == nil {
+		panic("MockProductRepository.ExistsFunc: method is nil but ProductRepository.Exists was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockExists.Lock()
+	mock.calls.Exists = append(mock.calls.Exists, callInfo)
+	mock.lockExists.Unlock()
+	return mock.ExistsFunc(ctx, filter)
+}
+
+// ExistsCalls gets all the calls that were made to Exists.
+// Check the length with:
+//
+//	len(mockedProductRepository.ExistsCalls())
+func (mock *MockProductRepository) ExistsCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockExists.RLock()
+	calls = mock.calls.Exists
+	mock.lockExists.RUnlock()
+	return calls
+}
+
+// Find calls FindFunc.
+func (mock *MockProductRepository) Find(contextMoqParam context.Context, query storage.Query) ([]types.Product, error) {
+	if mock.FindFunc == nil {
+		panic("MockProductRepository.FindFunc: method is nil but ProductRepository.Find was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}{
+		ContextMoqParam: contextMoqParam,
+		Query:           query,
+	}
+	mock.lockFind.Lock()
+	mock.calls.Find = append(mock.calls.Find, callInfo)
+	mock.lockFind.Unlock()
+	return mock.FindFunc(contextMoqParam, query)
+}
+
+// FindCalls gets all the calls that were made to Find.
+// Check the length with:
+//
+//	len(mockedProductRepository.FindCalls())
+func (mock *MockProductRepository) FindCalls() []struct {
+	ContextMoqParam context.Context
+	Query           storage.Query
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}
+	mock.lockFind.RLock()
+	calls = mock.calls.Find
+	mock.lockFind.RUnlock()
+	return calls
+}
+
 // GetAll calls GetAllFunc.
 func (mock *MockProductRepository) GetAll(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.Product, error) {
 	if mock.GetAllFunc == nil {
@@ -323,22 +790,64 @@ func (mock *MockProductRepository) GetByIDCalls() []struct {
 	return calls
 }
 
+// GetPage calls GetPageFunc.
+func (mock *MockProductRepository) GetPage(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.Product], error) {
+	if mock.GetPageFunc == nil {
+		panic("MockProductRepository.GetPageFunc: method is nil but ProductRepository.GetPage was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}{
+		ContextMoqParam: contextMoqParam,
+		PageRequest:     pageRequest,
+		Predicate:       predicate,
+	}
+	mock.lockGetPage.Lock()
+	mock.calls.GetPage = append(mock.calls.GetPage, callInfo)
+	mock.lockGetPage.Unlock()
+	return mock.GetPageFunc(contextMoqParam, pageRequest, predicate)
+}
+
+// GetPageCalls gets all the calls that were made to GetPage.
+// Check the length with:
+//
+//	len(mockedProductRepository.GetPageCalls())
+func (mock *MockProductRepository) GetPageCalls() []struct {
+	ContextMoqParam context.Context
+	PageRequest     storage.PageRequest
+	Predicate       storage.Predicate
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}
+	mock.lockGetPage.RLock()
+	calls = mock.calls.GetPage
+	mock.lockGetPage.RUnlock()
+	return calls
+}
+
 // GetProductsByIDs calls GetProductsByIDsFunc.
-func (mock *MockProductRepository) GetProductsByIDs(contextMoqParam context.Context, uUIDs []uuid.UUID) ([]types.Product, error) {
+func (mock *MockProductRepository) GetProductsByIDs(ctx context.Context, ids []uuid.UUID, forUpdate bool) ([]types.Product, error) {
 	if mock.GetProductsByIDsFunc == nil {
 		panic("MockProductRepository.GetProductsByIDsFunc: method is nil but ProductRepository.GetProductsByIDs was just called")
 	}
 	callInfo := struct {
-		ContextMoqParam context.Context
-		UUIDs           []uuid.UUID
+		Ctx       context.Context
+		Ids       []uuid.UUID
+		ForUpdate bool
 	}{
-		ContextMoqParam: contextMoqParam,
-		UUIDs:           uUIDs,
+		Ctx:       ctx,
+		Ids:       ids,
+		ForUpdate: forUpdate,
 	}
 	mock.lockGetProductsByIDs.Lock()
 	mock.calls.GetProductsByIDs = append(mock.calls.GetProductsByIDs, callInfo)
 	mock.lockGetProductsByIDs.Unlock()
-	return mock.GetProductsByIDsFunc(contextMoqParam, uUIDs)
+	return mock.GetProductsByIDsFunc(ctx, ids, forUpdate)
 }
 
 // GetProductsByIDsCalls gets all the calls that were made to GetProductsByIDs.
@@ -346,12 +855,14 @@ func (mock *MockProductRepository) GetProductsByIDs(contextMoqParam context.Cont
 //
 //	len(mockedProductRepository.GetProductsByIDsCalls())
 func (mock *MockProductRepository) GetProductsByIDsCalls() []struct {
-	ContextMoqParam context.Context
-	UUIDs           []uuid.UUID
+	Ctx       context.Context
+	Ids       []uuid.UUID
+	ForUpdate bool
 } {
 	var calls []struct {
-		ContextMoqParam context.Context
-		UUIDs           []uuid.UUID
+		Ctx       context.Context
+		Ids       []uuid.UUID
+		ForUpdate bool
 	}
 	mock.lockGetProductsByIDs.RLock()
 	calls = mock.calls.GetProductsByIDs
@@ -359,6 +870,270 @@ func (mock *MockProductRepository) GetProductsByIDsCalls() []struct {
 	return calls
 }
 
+// GetTotalInStock calls GetTotalInStockFunc.
+func (mock *MockProductRepository) GetTotalInStock(ctx context.Context) (int64, error) {
+	if mock.GetTotalInStockFunc == nil {
+		panic("MockProductRepository.GetTotalInStockFunc: method is nil but ProductRepository.GetTotalInStock was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetTotalInStock.Lock()
+	mock.calls.GetTotalInStock = append(mock.calls.GetTotalInStock, callInfo)
+	mock.lockGetTotalInStock.Unlock()
+	return mock.GetTotalInStockFunc(ctx)
+}
+
+// GetTotalInStockCalls gets all the calls that were made to GetTotalInStock.
+// Check the length with:
+//
+//	len(mockedProductRepository.GetTotalInStockCalls())
+func (mock *MockProductRepository) GetTotalInStockCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetTotalInStock.RLock()
+	calls = mock.calls.GetTotalInStock
+	mock.lockGetTotalInStock.RUnlock()
+	return calls
+}
+
+// Max calls MaxFunc.
+func (mock *MockProductRepository) Max(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MaxFunc == nil {
+		panic("MockProductRepository.MaxFunc: method is nil but ProductRepository.Max was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMax.Lock()
+	mock.calls.Max = append(mock.calls.Max, callInfo)
+	mock.lockMax.Unlock()
+	return mock.MaxFunc(ctx, column, filter)
+}
+
+// MaxCalls gets all the calls that were made to Max.
+// Check the length with:
+//
+//	len(mockedProductRepository.MaxCalls())
+func (mock *MockProductRepository) MaxCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMax.RLock()
+	calls = mock.calls.Max
+	mock.lockMax.RUnlock()
+	return calls
+}
+
+// Min calls MinFunc.
+func (mock *MockProductRepository) Min(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MinFunc == nil {
+		panic("MockProductRepository.MinFunc: method is nil but ProductRepository.Min was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMin.Lock()
+	mock.calls.Min = append(mock.calls.Min, callInfo)
+	mock.lockMin.Unlock()
+	return mock.MinFunc(ctx, column, filter)
+}
+
+// MinCalls gets all the calls that were made to Min.
+// Check the length with:
+//
+//	len(mockedProductRepository.MinCalls())
+func (mock *MockProductRepository) MinCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMin.RLock()
+	calls = mock.calls.Min
+	mock.lockMin.RUnlock()
+	return calls
+}
+
+// Purge calls PurgeFunc.
+func (mock *MockProductRepository) Purge(contextMoqParam context.Context, product *types.Product) error {
+	if mock.PurgeFunc == nil {
+		panic("MockProductRepository.PurgeFunc: method is nil but ProductRepository.Purge was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Product         *types.Product
+	}{
+		ContextMoqParam: contextMoqParam,
+		Product:         product,
+	}
+	mock.lockPurge.Lock()
+	mock.calls.Purge = append(mock.calls.Purge, callInfo)
+	mock.lockPurge.Unlock()
+	return mock.PurgeFunc(contextMoqParam, product)
+}
+
+// PurgeCalls gets all the calls that were made to Purge.
+// Check the length with:
+//
+//	len(mockedProductRepository.PurgeCalls())
+func (mock *MockProductRepository) PurgeCalls() []struct {
+	ContextMoqParam context.Context
+	Product         *types.Product
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Product         *types.Product
+	}
+	mock.lockPurge.RLock()
+	calls = mock.calls.Purge
+	mock.lockPurge.RUnlock()
+	return calls
+}
+
+// Restore calls RestoreFunc.
+func (mock *MockProductRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	if mock.RestoreFunc == nil {
+		panic("MockProductRepository.RestoreFunc: method is nil but ProductRepository.Restore was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestore.Lock()
+	mock.calls.Restore = append(mock.calls.Restore, callInfo)
+	mock.lockRestore.Unlock()
+	return mock.RestoreFunc(ctx, id)
+}
+
+// RestoreCalls gets all the calls that were made to Restore.
+// Check the length with:
+//
+//	len(mockedProductRepository.RestoreCalls())
+func (mock *MockProductRepository) RestoreCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockRestore.RLock()
+	calls = mock.calls.Restore
+	mock.lockRestore.RUnlock()
+	return calls
+}
+
+// Stream calls StreamFunc.
+func (mock *MockProductRepository) Stream(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.Product, error] {
+	if mock.StreamFunc == nil {
+		panic("MockProductRepository.StreamFunc: method is nil but ProductRepository.Stream was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		M:         m,
+		BatchSize: batchSize,
+	}
+	mock.lockStream.Lock()
+	mock.calls.Stream = append(mock.calls.Stream, callInfo)
+	mock.lockStream.Unlock()
+	return mock.StreamFunc(ctx, m, batchSize)
+}
+
+// StreamCalls gets all the calls that were made to Stream.
+// Check the length with:
+//
+//	len(mockedProductRepository.StreamCalls())
+func (mock *MockProductRepository) StreamCalls() []struct {
+	Ctx       context.Context
+	M         storage.SQLModifier
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}
+	mock.lockStream.RLock()
+	calls = mock.calls.Stream
+	mock.lockStream.RUnlock()
+	return calls
+}
+
+// Sum calls SumFunc.
+func (mock *MockProductRepository) Sum(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.SumFunc == nil {
+		panic("MockProductRepository.SumFunc: method is nil but ProductRepository.Sum was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockSum.Lock()
+	mock.calls.Sum = append(mock.calls.Sum, callInfo)
+	mock.lockSum.Unlock()
+	return mock.SumFunc(ctx, column, filter)
+}
+
+// SumCalls gets all the calls that were made to Sum.
+// Check the length with:
+//
+//	len(mockedProductRepository.SumCalls())
+func (mock *MockProductRepository) SumCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockSum.RLock()
+	calls = mock.calls.Sum
+	mock.lockSum.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
 func (mock *MockProductRepository) Update(contextMoqParam context.Context, product *types.Product) error {
 	if mock.UpdateFunc == nil {
@@ -394,3 +1169,83 @@ func (mock *MockProductRepository) UpdateCalls() []struct {
 	mock.lockUpdate.RUnlock()
 	return calls
 }
+
+// UpdateWhere calls UpdateWhereFunc.
+func (mock *MockProductRepository) UpdateWhere(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+	if mock.UpdateWhereFunc == nil {
+		panic("MockProductRepository.UpdateWhereFunc: method is nil but ProductRepository.UpdateWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+		Values: values,
+	}
+	mock.lockUpdateWhere.Lock()
+	mock.calls.UpdateWhere = append(mock.calls.UpdateWhere, callInfo)
+	mock.lockUpdateWhere.Unlock()
+	return mock.UpdateWhereFunc(ctx, filter, values)
+}
+
+// UpdateWhereCalls gets all the calls that were made to UpdateWhere.
+// Check the length with:
+//
+//	len(mockedProductRepository.UpdateWhereCalls())
+func (mock *MockProductRepository) UpdateWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+	Values map[string]any
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}
+	mock.lockUpdateWhere.RLock()
+	calls = mock.calls.UpdateWhere
+	mock.lockUpdateWhere.RUnlock()
+	return calls
+}
+
+// UpsertMany calls UpsertManyFunc.
+func (mock *MockProductRepository) UpsertMany(ctx context.Context, ts []types.Product, opts storage.UpsertOptions) (int64, error) {
+	if mock.UpsertManyFunc == nil {
+		panic("MockProductRepository.UpsertManyFunc: method is nil but ProductRepository.UpsertMany was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Ts   []types.Product
+		Opts storage.UpsertOptions
+	}{
+		Ctx:  ctx,
+		Ts:   ts,
+		Opts: opts,
+	}
+	mock.lockUpsertMany.Lock()
+	mock.calls.UpsertMany = append(mock.calls.UpsertMany, callInfo)
+	mock.lockUpsertMany.Unlock()
+	return mock.UpsertManyFunc(ctx, ts, opts)
+}
+
+// UpsertManyCalls gets all the calls that were made to UpsertMany.
+// Check the length with:
+//
+//	len(mockedProductRepository.UpsertManyCalls())
+func (mock *MockProductRepository) UpsertManyCalls() []struct {
+	Ctx  context.Context
+	Ts   []types.Product
+	Opts storage.UpsertOptions
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Ts   []types.Product
+		Opts storage.UpsertOptions
+	}
+	mock.lockUpsertMany.RLock()
+	calls = mock.calls.UpsertMany
+	mock.lockUpsertMany.RUnlock()
+	return calls
+}
@@ -0,0 +1,1307 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mocks
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+	"iter"
+	"sync"
+)
+
+// Ensure, that MockRefreshTokenRepository does implement types.RefreshTokenRepository.
+// If this is not the case, regenerate this file with moq.
+var _ types.RefreshTokenRepository = &MockRefreshTokenRepository{}
+
+// MockRefreshTokenRepository is a mock implementation of types.RefreshTokenRepository.
+//
+//	func TestSomethingThatUsesRefreshTokenRepository(t *testing.T) {
+//
+//		// make and configure a mocked types.RefreshTokenRepository
+//		mockedRefreshTokenRepository := &MockRefreshTokenRepository{
+//			CountFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the Count method")
+//			},
+//			CountByFunc: func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+//				panic("mock out the CountBy method")
+//			},
+//			CreateFunc: func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+//				panic("mock out the Create method")
+//			},
+//			CreateManyFunc: func(ctx context.Context, ts []types.RefreshToken, batchSize int) (int64, error) {
+//				panic("mock out the CreateMany method")
+//			},
+//			DeleteFunc: func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+//				panic("mock out the Delete method")
+//			},
+//			DeleteWhereFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the DeleteWhere method")
+//			},
+//			ExistsFunc: func(ctx context.Context, filter storage.Predicate) (bool, error) {
+//				panic("mock out the Exists method")
+//			},
+//			FindFunc: func(contextMoqParam context.Context, query storage.Query) ([]types.RefreshToken, error) {
+//				panic("mock out the Find method")
+//			},
+//			GetAllFunc: func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.RefreshToken, error) {
+//				panic("mock out the GetAll method")
+//			},
+//			GetByFieldsFunc: func(ctx context.Context, fields map[string]string, forUpdate bool) (*types.RefreshToken, error) {
+//				panic("mock out the GetByFields method")
+//			},
+//			GetByHashFunc: func(ctx context.Context, hash string, forUpdate bool) (*types.RefreshToken, error) {
+//				panic("mock out the GetByHash method")
+//			},
+//			GetByIDFunc: func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.RefreshToken, error) {
+//				panic("mock out the GetByID method")
+//			},
+//			GetPageFunc: func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.RefreshToken], error) {
+//				panic("mock out the GetPage method")
+//			},
+//			MaxFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Max method")
+//			},
+//			MinFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Min method")
+//			},
+//			PurgeFunc: func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+//				panic("mock out the Purge method")
+//			},
+//			RestoreFunc: func(ctx context.Context, id uuid.UUID) error {
+//				panic("mock out the Restore method")
+//			},
+//			RevokeFamilyFunc: func(ctx context.Context, familyID uuid.UUID) error {
+//				panic("mock out the RevokeFamily method")
+//			},
+//			RevokeUserFunc: func(ctx context.Context, userID uuid.UUID) error {
+//				panic("mock out the RevokeUser method")
+//			},
+//			StreamFunc: func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.RefreshToken, error] {
+//				panic("mock out the Stream method")
+//			},
+//			SumFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Sum method")
+//			},
+//			UpdateFunc: func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+//				panic("mock out the Update method")
+//			},
+//			UpdateWhereFunc: func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+//				panic("mock out the UpdateWhere method")
+//			},
+//			UpsertManyFunc: func(ctx context.Context, ts []types.RefreshToken, opts storage.UpsertOptions) (int64, error) {
+//				panic("mock out the UpsertMany method")
+//			},
+//		}
+//
+//		// use mockedRefreshTokenRepository in code that requires types.RefreshTokenRepository
+//		// and then make assertions.
+//
+//	}
+type MockRefreshTokenRepository struct {
+	// CountFunc mocks the Count method.
+	CountFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// CountByFunc mocks the CountBy method.
+	CountByFunc func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error)
+
+	// CreateFunc mocks the Create method.
+	CreateFunc func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error
+
+	// CreateManyFunc mocks the CreateMany method.
+	CreateManyFunc func(ctx context.Context, ts []types.RefreshToken, batchSize int) (int64, error)
+
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error
+
+	// DeleteWhereFunc mocks the DeleteWhere method.
+	DeleteWhereFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// ExistsFunc mocks the Exists method.
+	ExistsFunc func(ctx context.Context, filter storage.Predicate) (bool, error)
+
+	// FindFunc mocks the Find method.
+	FindFunc func(contextMoqParam context.Context, query storage.Query) ([]types.RefreshToken, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.RefreshToken, error)
+
+	// GetByFieldsFunc mocks the GetByFields method.
+	GetByFieldsFunc func(ctx context.Context, fields map[string]string, forUpdate bool) (*types.RefreshToken, error)
+
+	// GetByHashFunc mocks the GetByHash method.
+	GetByHashFunc func(ctx context.Context, hash string, forUpdate bool) (*types.RefreshToken, error)
+
+	// GetByIDFunc mocks the GetByID method.
+	GetByIDFunc func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.RefreshToken, error)
+
+	// GetPageFunc mocks the GetPage method.
+	GetPageFunc func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.RefreshToken], error)
+
+	// MaxFunc mocks the Max method.
+	MaxFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// MinFunc mocks the Min method.
+	MinFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// PurgeFunc mocks the Purge method.
+	PurgeFunc func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error
+
+	// RestoreFunc mocks the Restore method.
+	RestoreFunc func(ctx context.Context, id uuid.UUID) error
+
+	// RevokeFamilyFunc mocks the RevokeFamily method.
+	RevokeFamilyFunc func(ctx context.Context, familyID uuid.UUID) error
+
+	// RevokeUserFunc mocks the RevokeUser method.
+	RevokeUserFunc func(ctx context.Context, userID uuid.UUID) error
+
+	// StreamFunc mocks the Stream method.
+	StreamFunc func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.RefreshToken, error]
+
+	// SumFunc mocks the Sum method.
+	SumFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(contextMoqParam context.Context, refreshToken *types.RefreshToken) error
+
+	// UpdateWhereFunc mocks the UpdateWhere method.
+	UpdateWhereFunc func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error)
+
+	// UpsertManyFunc mocks the UpsertMany method.
+	UpsertManyFunc func(ctx context.Context, ts []types.RefreshToken, opts storage.UpsertOptions) (int64, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Count holds details about calls to the Count method.
+		Count []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// CountBy holds details about calls to the CountBy method.
+		CountBy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// RefreshToken is the refreshToken argument value.
+			RefreshToken *types.RefreshToken
+		}
+		// CreateMany holds details about calls to the CreateMany method.
+		CreateMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.RefreshToken
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// RefreshToken is the refreshToken argument value.
+			RefreshToken *types.RefreshToken
+		}
+		// DeleteWhere holds details about calls to the DeleteWhere method.
+		DeleteWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Exists holds details about calls to the Exists method.
+		Exists []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Find holds details about calls to the Find method.
+		Find []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Query is the query argument value.
+			Query storage.Query
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// SQLModifier is the sQLModifier argument value.
+			SQLModifier storage.SQLModifier
+		}
+		// GetByFields holds details about calls to the GetByFields method.
+		GetByFields []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Fields is the fields argument value.
+			Fields map[string]string
+			// ForUpdate is the forUpdate argument value.
+			ForUpdate bool
+		}
+		// GetByHash holds details about calls to the GetByHash method.
+		GetByHash []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Hash is the hash argument value.
+			Hash string
+			// ForUpdate is the forUpdate argument value.
+			ForUpdate bool
+		}
+		// GetByID holds details about calls to the GetByID method.
+		GetByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+			// ForUpdate is the forUpdate argument value.
+			ForUpdate bool
+		}
+		// GetPage holds details about calls to the GetPage method.
+		GetPage []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// PageRequest is the pageRequest argument value.
+			PageRequest storage.PageRequest
+			// Predicate is the predicate argument value.
+			Predicate storage.Predicate
+		}
+		// Max holds details about calls to the Max method.
+		Max []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Min holds details about calls to the Min method.
+		Min []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Purge holds details about calls to the Purge method.
+		Purge []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// RefreshToken is the refreshToken argument value.
+			RefreshToken *types.RefreshToken
+		}
+		// Restore holds details about calls to the Restore method.
+		Restore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// RevokeFamily holds details about calls to the RevokeFamily method.
+		RevokeFamily []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FamilyID is the familyID argument value.
+			FamilyID uuid.UUID
+		}
+		// RevokeUser holds details about calls to the RevokeUser method.
+		RevokeUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+		}
+		// Stream holds details about calls to the Stream method.
+		Stream []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// M is the m argument value.
+			M storage.SQLModifier
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
+		// Sum holds details about calls to the Sum method.
+		Sum []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// RefreshToken is the refreshToken argument value.
+			RefreshToken *types.RefreshToken
+		}
+		// UpdateWhere holds details about calls to the UpdateWhere method.
+		UpdateWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+			// Values is the values argument value.
+			Values map[string]any
+		}
+		// UpsertMany holds details about calls to the UpsertMany method.
+		UpsertMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.RefreshToken
+			// Opts is the opts argument value.
+			Opts storage.UpsertOptions
+		}
+	}
+	lockCount        sync.RWMutex
+	lockCountBy      sync.RWMutex
+	lockCreate       sync.RWMutex
+	lockCreateMany   sync.RWMutex
+	lockDelete       sync.RWMutex
+	lockDeleteWhere  sync.RWMutex
+	lockExists       sync.RWMutex
+	lockFind         sync.RWMutex
+	lockGetAll       sync.RWMutex
+	lockGetByFields  sync.RWMutex
+	lockGetByHash    sync.RWMutex
+	lockGetByID      sync.RWMutex
+	lockGetPage      sync.RWMutex
+	lockMax          sync.RWMutex
+	lockMin          sync.RWMutex
+	lockPurge        sync.RWMutex
+	lockRestore      sync.RWMutex
+	lockRevokeFamily sync.RWMutex
+	lockRevokeUser   sync.RWMutex
+	lockStream       sync.RWMutex
+	lockSum          sync.RWMutex
+	lockUpdate       sync.RWMutex
+	lockUpdateWhere  sync.RWMutex
+	lockUpsertMany   sync.RWMutex
+}
+
+// Count calls CountFunc.
+func (mock *MockRefreshTokenRepository) Count(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.CountFunc == nil {
+		panic("MockRefreshTokenRepository.CountFunc: method is nil but RefreshTokenRepository.Count was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockCount.Lock()
+	mock.calls.Count = append(mock.calls.Count, callInfo)
+	mock.lockCount.Unlock()
+	return mock.CountFunc(ctx, filter)
+}
+
+// CountCalls gets all the calls that were made to Count.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.CountCalls())
+func (mock *MockRefreshTokenRepository) CountCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockCount.RLock()
+	calls = mock.calls.Count
+	mock.lockCount.RUnlock()
+	return calls
+}
+
+// CountBy calls CountByFunc.
+func (mock *MockRefreshTokenRepository) CountBy(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+	if mock.CountByFunc == nil {
+		panic("MockRefreshTokenRepository.CountByFunc: method is nil but RefreshTokenRepository.CountBy was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockCountBy.Lock()
+	mock.calls.CountBy = append(mock.calls.CountBy, callInfo)
+	mock.lockCountBy.Unlock()
+	return mock.CountByFunc(ctx, column, filter)
+}
+
+// CountByCalls gets all the calls that were made to CountBy.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.CountByCalls())
+func (mock *MockRefreshTokenRepository) CountByCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockCountBy.RLock()
+	calls = mock.calls.CountBy
+	mock.lockCountBy.RUnlock()
+	return calls
+}
+
+// Create calls CreateFunc.
+func (mock *MockRefreshTokenRepository) Create(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+	if mock.CreateFunc == nil {
+		panic("MockRefreshTokenRepository.CreateFunc: method is nil but RefreshTokenRepository.Create was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}{
+		ContextMoqParam: contextMoqParam,
+		RefreshToken:    refreshToken,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(contextMoqParam, refreshToken)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.CreateCalls())
+func (mock *MockRefreshTokenRepository) CreateCalls() []struct {
+	ContextMoqParam context.Context
+	RefreshToken    *types.RefreshToken
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// CreateMany calls CreateManyFunc.
+func (mock *MockRefreshTokenRepository) CreateMany(ctx context.Context, ts []types.RefreshToken, batchSize int) (int64, error) {
+	if mock.CreateManyFunc == nil {
+		panic("MockRefreshTokenRepository.CreateManyFunc: method is nil but RefreshTokenRepository.CreateMany was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Ts        []types.RefreshToken
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		Ts:        ts,
+		BatchSize: batchSize,
+	}
+	mock.lockCreateMany.Lock()
+	mock.calls.CreateMany = append(mock.calls.CreateMany, callInfo)
+	mock.lockCreateMany.Unlock()
+	return mock.CreateManyFunc(ctx, ts, batchSize)
+}
+
+// CreateManyCalls gets all the calls that were made to CreateMany.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.CreateManyCalls())
+func (mock *MockRefreshTokenRepository) CreateManyCalls() []struct {
+	Ctx       context.Context
+	Ts        []types.RefreshToken
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Ts        []types.RefreshToken
+		BatchSize int
+	}
+	mock.lockCreateMany.RLock()
+	calls = mock.calls.CreateMany
+	mock.lockCreateMany.RUnlock()
+	return calls
+}
+
+// Delete calls DeleteFunc.
+func (mock *MockRefreshTokenRepository) Delete(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+	if mock.DeleteFunc == nil {
+		panic("MockRefreshTokenRepository.DeleteFunc: method is nil but RefreshTokenRepository.Delete was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}{
+		ContextMoqParam: contextMoqParam,
+		RefreshToken:    refreshToken,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(contextMoqParam, refreshToken)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.DeleteCalls())
+func (mock *MockRefreshTokenRepository) DeleteCalls() []struct {
+	ContextMoqParam context.Context
+	RefreshToken    *types.RefreshToken
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// DeleteWhere calls DeleteWhereFunc.
+func (mock *MockRefreshTokenRepository) DeleteWhere(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.DeleteWhereFunc == nil {
+		panic("MockRefreshTokenRepository.DeleteWhereFunc: method is nil but RefreshTokenRepository.DeleteWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockDeleteWhere.Lock()
+	mock.calls.DeleteWhere = append(mock.calls.DeleteWhere, callInfo)
+	mock.lockDeleteWhere.Unlock()
+	return mock.DeleteWhereFunc(ctx, filter)
+}
+
+// DeleteWhereCalls gets all the calls that were made to DeleteWhere.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.DeleteWhereCalls())
+func (mock *MockRefreshTokenRepository) DeleteWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockDeleteWhere.RLock()
+	calls = mock.calls.DeleteWhere
+	mock.lockDeleteWhere.RUnlock()
+	return calls
+}
+
+// Exists calls ExistsFunc.
+func (mock *MockRefreshTokenRepository) Exists(ctx context.Context, filter storage.Predicate) (bool, error) {
+	if mock.ExistsFunc == nil {
+		panic("MockRefreshTokenRepository.ExistsFunc: method is nil but RefreshTokenRepository.Exists was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockExists.Lock()
+	mock.calls.Exists = append(mock.calls.Exists, callInfo)
+	mock.lockExists.Unlock()
+	return mock.ExistsFunc(ctx, filter)
+}
+
+// ExistsCalls gets all the calls that were made to Exists.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.ExistsCalls())
+func (mock *MockRefreshTokenRepository) ExistsCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockExists.RLock()
+	calls = mock.calls.Exists
+	mock.lockExists.RUnlock()
+	return calls
+}
+
+// Find calls FindFunc.
+func (mock *MockRefreshTokenRepository) Find(contextMoqParam context.Context, query storage.Query) ([]types.RefreshToken, error) {
+	if mock.FindFunc == nil {
+		panic("MockRefreshTokenRepository.FindFunc: method is nil but RefreshTokenRepository.Find was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}{
+		ContextMoqParam: contextMoqParam,
+		Query:           query,
+	}
+	mock.lockFind.Lock()
+	mock.calls.Find = append(mock.calls.Find, callInfo)
+	mock.lockFind.Unlock()
+	return mock.FindFunc(contextMoqParam, query)
+}
+
+// FindCalls gets all the calls that were made to Find.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.FindCalls())
+func (mock *MockRefreshTokenRepository) FindCalls() []struct {
+	ContextMoqParam context.Context
+	Query           storage.Query
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}
+	mock.lockFind.RLock()
+	calls = mock.calls.Find
+	mock.lockFind.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *MockRefreshTokenRepository) GetAll(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.RefreshToken, error) {
+	if mock.GetAllFunc == nil {
+		panic("MockRefreshTokenRepository.GetAllFunc: method is nil but RefreshTokenRepository.GetAll was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		SQLModifier     storage.SQLModifier
+	}{
+		ContextMoqParam: contextMoqParam,
+		SQLModifier:     sQLModifier,
+	}
+	mock.lockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	mock.lockGetAll.Unlock()
+	return mock.GetAllFunc(contextMoqParam, sQLModifier)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.GetAllCalls())
+func (mock *MockRefreshTokenRepository) GetAllCalls() []struct {
+	ContextMoqParam context.Context
+	SQLModifier     storage.SQLModifier
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		SQLModifier     storage.SQLModifier
+	}
+	mock.lockGetAll.RLock()
+	calls = mock.calls.GetAll
+	mock.lockGetAll.RUnlock()
+	return calls
+}
+
+// GetByFields calls GetByFieldsFunc.
+func (mock *MockRefreshTokenRepository) GetByFields(ctx context.Context, fields map[string]string, forUpdate bool) (*types.RefreshToken, error) {
+	if mock.GetByFieldsFunc == nil {
+		panic("MockRefreshTokenRepository.GetByFieldsFunc: method is nil but RefreshTokenRepository.GetByFields was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Fields    map[string]string
+		ForUpdate bool
+	}{
+		Ctx:       ctx,
+		Fields:    fields,
+		ForUpdate: forUpdate,
+	}
+	mock.lockGetByFields.Lock()
+	mock.calls.GetByFields = append(mock.calls.GetByFields, callInfo)
+	mock.lockGetByFields.Unlock()
+	return mock.GetByFieldsFunc(ctx, fields, forUpdate)
+}
+
+// GetByFieldsCalls gets all the calls that were made to GetByFields.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.GetByFieldsCalls())
+func (mock *MockRefreshTokenRepository) GetByFieldsCalls() []struct {
+	Ctx       context.Context
+	Fields    map[string]string
+	ForUpdate bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Fields    map[string]string
+		ForUpdate bool
+	}
+	mock.lockGetByFields.RLock()
+	calls = mock.calls.GetByFields
+	mock.lockGetByFields.RUnlock()
+	return calls
+}
+
+// GetByHash calls GetByHashFunc.
+func (mock *MockRefreshTokenRepository) GetByHash(ctx context.Context, hash string, forUpdate bool) (*types.RefreshToken, error) {
+	if mock.GetByHashFunc == nil {
+		panic("MockRefreshTokenRepository.GetByHashFunc: method is nil but RefreshTokenRepository.GetByHash was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Hash      string
+		ForUpdate bool
+	}{
+		Ctx:       ctx,
+		Hash:      hash,
+		ForUpdate: forUpdate,
+	}
+	mock.lockGetByHash.Lock()
+	mock.calls.GetByHash = append(mock.calls.GetByHash, callInfo)
+	mock.lockGetByHash.Unlock()
+	return mock.GetByHashFunc(ctx, hash, forUpdate)
+}
+
+// GetByHashCalls gets all the calls that were made to GetByHash.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.GetByHashCalls())
+func (mock *MockRefreshTokenRepository) GetByHashCalls() []struct {
+	Ctx       context.Context
+	Hash      string
+	ForUpdate bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Hash      string
+		ForUpdate bool
+	}
+	mock.lockGetByHash.RLock()
+	calls = mock.calls.GetByHash
+	mock.lockGetByHash.RUnlock()
+	return calls
+}
+
+// GetByID calls GetByIDFunc.
+func (mock *MockRefreshTokenRepository) GetByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.RefreshToken, error) {
+	if mock.GetByIDFunc == nil {
+		panic("MockRefreshTokenRepository.GetByIDFunc: method is nil but RefreshTokenRepository.GetByID was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ID        uuid.UUID
+		ForUpdate bool
+	}{
+		Ctx:       ctx,
+		ID:        id,
+		ForUpdate: forUpdate,
+	}
+	mock.lockGetByID.Lock()
+	mock.calls.GetByID = append(mock.calls.GetByID, callInfo)
+	mock.lockGetByID.Unlock()
+	return mock.GetByIDFunc(ctx, id, forUpdate)
+}
+
+// GetByIDCalls gets all the calls that were made to GetByID.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.GetByIDCalls())
+func (mock *MockRefreshTokenRepository) GetByIDCalls() []struct {
+	Ctx       context.Context
+	ID        uuid.UUID
+	ForUpdate bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ID        uuid.UUID
+		ForUpdate bool
+	}
+	mock.lockGetByID.RLock()
+	calls = mock.calls.GetByID
+	mock.lockGetByID.RUnlock()
+	return calls
+}
+
+// GetPage calls GetPageFunc.
+func (mock *MockRefreshTokenRepository) GetPage(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.RefreshToken], error) {
+	if mock.GetPageFunc == nil {
+		panic("MockRefreshTokenRepository.GetPageFunc: method is nil but RefreshTokenRepository.GetPage was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}{
+		ContextMoqParam: contextMoqParam,
+		PageRequest:     pageRequest,
+		Predicate:       predicate,
+	}
+	mock.lockGetPage.Lock()
+	mock.calls.GetPage = append(mock.calls.GetPage, callInfo)
+	mock.lockGetPage.Unlock()
+	return mock.GetPageFunc(contextMoqParam, pageRequest, predicate)
+}
+
+// GetPageCalls gets all the calls that were made to GetPage.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.GetPageCalls())
+func (mock *MockRefreshTokenRepository) GetPageCalls() []struct {
+	ContextMoqParam context.Context
+	PageRequest     storage.PageRequest
+	Predicate       storage.Predicate
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}
+	mock.lockGetPage.RLock()
+	calls = mock.calls.GetPage
+	mock.lockGetPage.RUnlock()
+	return calls
+}
+
+// Max calls MaxFunc.
+func (mock *MockRefreshTokenRepository) Max(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MaxFunc == nil {
+		panic("MockRefreshTokenRepository.MaxFunc: method is nil but RefreshTokenRepository.Max was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMax.Lock()
+	mock.calls.Max = append(mock.calls.Max, callInfo)
+	mock.lockMax.Unlock()
+	return mock.MaxFunc(ctx, column, filter)
+}
+
+// MaxCalls gets all the calls that were made to Max.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.MaxCalls())
+func (mock *MockRefreshTokenRepository) MaxCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMax.RLock()
+	calls = mock.calls.Max
+	mock.lockMax.RUnlock()
+	return calls
+}
+
+// Min calls MinFunc.
+func (mock *MockRefreshTokenRepository) Min(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MinFunc == nil {
+		panic("MockRefreshTokenRepository.MinFunc: method is nil but RefreshTokenRepository.Min was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMin.Lock()
+	mock.calls.Min = append(mock.calls.Min, callInfo)
+	mock.lockMin.Unlock()
+	return mock.MinFunc(ctx, column, filter)
+}
+
+// MinCalls gets all the calls that were made to Min.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.MinCalls())
+func (mock *MockRefreshTokenRepository) MinCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMin.RLock()
+	calls = mock.calls.Min
+	mock.lockMin.RUnlock()
+	return calls
+}
+
+// Purge calls PurgeFunc.
+func (mock *MockRefreshTokenRepository) Purge(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+	if mock.PurgeFunc == nil {
+		panic("MockRefreshTokenRepository.PurgeFunc: method is nil but RefreshTokenRepository.Purge was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}{
+		ContextMoqParam: contextMoqParam,
+		RefreshToken:    refreshToken,
+	}
+	mock.lockPurge.Lock()
+	mock.calls.Purge = append(mock.calls.Purge, callInfo)
+	mock.lockPurge.Unlock()
+	return mock.PurgeFunc(contextMoqParam, refreshToken)
+}
+
+// PurgeCalls gets all the calls that were made to Purge.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.PurgeCalls())
+func (mock *MockRefreshTokenRepository) PurgeCalls() []struct {
+	ContextMoqParam context.Context
+	RefreshToken    *types.RefreshToken
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}
+	mock.lockPurge.RLock()
+	calls = mock.calls.Purge
+	mock.lockPurge.RUnlock()
+	return calls
+}
+
+// Restore calls RestoreFunc.
+func (mock *MockRefreshTokenRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	if mock.RestoreFunc == nil {
+		panic("MockRefreshTokenRepository.RestoreFunc: method is nil but RefreshTokenRepository.Restore was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestore.Lock()
+	mock.calls.Restore = append(mock.calls.Restore, callInfo)
+	mock.lockRestore.Unlock()
+	return mock.RestoreFunc(ctx, id)
+}
+
+// RestoreCalls gets all the calls that were made to Restore.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.RestoreCalls())
+func (mock *MockRefreshTokenRepository) RestoreCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockRestore.RLock()
+	calls = mock.calls.Restore
+	mock.lockRestore.RUnlock()
+	return calls
+}
+
+// RevokeFamily calls RevokeFamilyFunc.
+func (mock *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if mock.RevokeFamilyFunc == nil {
+		panic("MockRefreshTokenRepository.RevokeFamilyFunc: method is nil but RefreshTokenRepository.RevokeFamily was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FamilyID uuid.UUID
+	}{
+		Ctx:      ctx,
+		FamilyID: familyID,
+	}
+	mock.lockRevokeFamily.Lock()
+	mock.calls.RevokeFamily = append(mock.calls.RevokeFamily, callInfo)
+	mock.lockRevokeFamily.Unlock()
+	return mock.RevokeFamilyFunc(ctx, familyID)
+}
+
+// RevokeFamilyCalls gets all the calls that were made to RevokeFamily.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.RevokeFamilyCalls())
+func (mock *MockRefreshTokenRepository) RevokeFamilyCalls() []struct {
+	Ctx      context.Context
+	FamilyID uuid.UUID
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FamilyID uuid.UUID
+	}
+	mock.lockRevokeFamily.RLock()
+	calls = mock.calls.RevokeFamily
+	mock.lockRevokeFamily.RUnlock()
+	return calls
+}
+
+// RevokeUser calls RevokeUserFunc.
+func (mock *MockRefreshTokenRepository) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	if mock.RevokeUserFunc == nil {
+		panic("MockRefreshTokenRepository.RevokeUserFunc: method is nil but RefreshTokenRepository.RevokeUser was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockRevokeUser.Lock()
+	mock.calls.RevokeUser = append(mock.calls.RevokeUser, callInfo)
+	mock.lockRevokeUser.Unlock()
+	return mock.RevokeUserFunc(ctx, userID)
+}
+
+// RevokeUserCalls gets all the calls that were made to RevokeUser.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.RevokeUserCalls())
+func (mock *MockRefreshTokenRepository) RevokeUserCalls() []struct {
+	Ctx    context.Context
+	UserID uuid.UUID
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+	}
+	mock.lockRevokeUser.RLock()
+	calls = mock.calls.RevokeUser
+	mock.lockRevokeUser.RUnlock()
+	return calls
+}
+
+// Stream calls StreamFunc.
+func (mock *MockRefreshTokenRepository) Stream(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.RefreshToken, error] {
+	if mock.StreamFunc == nil {
+		panic("MockRefreshTokenRepository.StreamFunc: method is nil but RefreshTokenRepository.Stream was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		M:         m,
+		BatchSize: batchSize,
+	}
+	mock.lockStream.Lock()
+	mock.calls.Stream = append(mock.calls.Stream, callInfo)
+	mock.lockStream.Unlock()
+	return mock.StreamFunc(ctx, m, batchSize)
+}
+
+// StreamCalls gets all the calls that were made to Stream.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.StreamCalls())
+func (mock *MockRefreshTokenRepository) StreamCalls() []struct {
+	Ctx       context.Context
+	M         storage.SQLModifier
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}
+	mock.lockStream.RLock()
+	calls = mock.calls.Stream
+	mock.lockStream.RUnlock()
+	return calls
+}
+
+// Sum calls SumFunc.
+func (mock *MockRefreshTokenRepository) Sum(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.SumFunc == nil {
+		panic("MockRefreshTokenRepository.SumFunc: method is nil but RefreshTokenRepository.Sum was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockSum.Lock()
+	mock.calls.Sum = append(mock.calls.Sum, callInfo)
+	mock.lockSum.Unlock()
+	return mock.SumFunc(ctx, column, filter)
+}
+
+// SumCalls gets all the calls that were made to Sum.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.SumCalls())
+func (mock *MockRefreshTokenRepository) SumCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockSum.RLock()
+	calls = mock.calls.Sum
+	mock.lockSum.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *MockRefreshTokenRepository) Update(contextMoqParam context.Context, refreshToken *types.RefreshToken) error {
+	if mock.UpdateFunc == nil {
+		panic("MockRefreshTokenRepository.UpdateFunc: method is nil but RefreshTokenRepository.Update was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}{
+		ContextMoqParam: contextMoqParam,
+		RefreshToken:    refreshToken,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	return mock.UpdateFunc(contextMoqParam, refreshToken)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.UpdateCalls())
+func (mock *MockRefreshTokenRepository) UpdateCalls() []struct {
+	ContextMoqParam context.Context
+	RefreshToken    *types.RefreshToken
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		RefreshToken    *types.RefreshToken
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// UpdateWhere calls UpdateWhereFunc.
+func (mock *MockRefreshTokenRepository) UpdateWhere(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+	if mock.UpdateWhereFunc == nil {
+		panic("MockRefreshTokenRepository.UpdateWhereFunc: method is nil but RefreshTokenRepository.UpdateWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+		Values: values,
+	}
+	mock.lockUpdateWhere.Lock()
+	mock.calls.UpdateWhere = append(mock.calls.UpdateWhere, callInfo)
+	mock.lockUpdateWhere.Unlock()
+	return mock.UpdateWhereFunc(ctx, filter, values)
+}
+
+// UpdateWhereCalls gets all the calls that were made to UpdateWhere.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.UpdateWhereCalls())
+func (mock *MockRefreshTokenRepository) UpdateWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+	Values map[string]any
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}
+	mock.lockUpdateWhere.RLock()
+	calls = mock.calls.UpdateWhere
+	mock.lockUpdateWhere.RUnlock()
+	return calls
+}
+
+// UpsertMany calls UpsertManyFunc.
+func (mock *MockRefreshTokenRepository) UpsertMany(ctx context.Context, ts []types.RefreshToken, opts storage.UpsertOptions) (int64, error) {
+	if mock.UpsertManyFunc == nil {
+		panic("MockRefreshTokenRepository.UpsertManyFunc: method is nil but RefreshTokenRepository.UpsertMany was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Ts   []types.RefreshToken
+		Opts storage.UpsertOptions
+	}{
+		Ctx:  ctx,
+		Ts:   ts,
+		Opts: opts,
+	}
+	mock.lockUpsertMany.Lock()
+	mock.calls.UpsertMany = append(mock.calls.UpsertMany, callInfo)
+	mock.lockUpsertMany.Unlock()
+	return mock.UpsertManyFunc(ctx, ts, opts)
+}
+
+// UpsertManyCalls gets all the calls that were made to UpsertMany.
+// Check the length with:
+//
+//	len(mockedRefreshTokenRepository.UpsertManyCalls())
+func (mock *MockRefreshTokenRepository) UpsertManyCalls() []struct {
+	Ctx  context.Context
+	Ts   []types.RefreshToken
+	Opts storage.UpsertOptions
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Ts   []types.RefreshToken
+		Opts storage.UpsertOptions
+	}
+	mock.lockUpsertMany.RLock()
+	calls = mock.calls.UpsertMany
+	mock.lockUpsertMany.RUnlock()
+	return calls
+}
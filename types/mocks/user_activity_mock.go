@@ -0,0 +1,1213 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mocks
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/zechao158/ecomm/storage"
+	"github.com/zechao158/ecomm/types"
+	"iter"
+	"sync"
+)
+
+// Ensure, that MockUserActivityRepository does implement types.UserActivityRepository.
+// If this is not the case, regenerate this file with moq.
+var _ types.UserActivityRepository = &MockUserActivityRepository{}
+
+// MockUserActivityRepository is a mock implementation of types.UserActivityRepository.
+//
+//	func TestSomethingThatUsesUserActivityRepository(t *testing.T) {
+//
+//		// make and configure a mocked types.UserActivityRepository
+//		mockedUserActivityRepository := &MockUserActivityRepository{
+//			CountFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the Count method")
+//			},
+//			CountByFunc: func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+//				panic("mock out the CountBy method")
+//			},
+//			CreateFunc: func(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+//				panic("mock out the Create method")
+//			},
+//			CreateManyFunc: func(ctx context.Context, ts []types.UserActivity, batchSize int) (int64, error) {
+//				panic("mock out the CreateMany method")
+//			},
+//			DeleteFunc: func(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+//				panic("mock out the Delete method")
+//			},
+//			DeleteWhereFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the DeleteWhere method")
+//			},
+//			ExistsFunc: func(ctx context.Context, filter storage.Predicate) (bool, error) {
+//				panic("mock out the Exists method")
+//			},
+//			FindFunc: func(contextMoqParam context.Context, query storage.Query) ([]types.UserActivity, error) {
+//				panic("mock out the Find method")
+//			},
+//			GetAllFunc: func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.UserActivity, error) {
+//				panic("mock out the GetAll method")
+//			},
+//			GetByFieldsFunc: func(ctx context.Context, fields map[string]string, forUpdate bool) (*types.UserActivity, error) {
+//				panic("mock out the GetByFields method")
+//			},
+//			GetByIDFunc: func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.UserActivity, error) {
+//				panic("mock out the GetByID method")
+//			},
+//			GetPageFunc: func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.UserActivity], error) {
+//				panic("mock out the GetPage method")
+//			},
+//			GetUserActivityFunc: func(ctx context.Context, userID uuid.UUID, req storage.PageRequest, filter storage.Predicate) (*storage.Page[types.UserActivity], error) {
+//				panic("mock out the GetUserActivity method")
+//			},
+//			MaxFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Max method")
+//			},
+//			MinFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Min method")
+//			},
+//			PurgeFunc: func(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+//				panic("mock out the Purge method")
+//			},
+//			RestoreFunc: func(ctx context.Context, id uuid.UUID) error {
+//				panic("mock out the Restore method")
+//			},
+//			StreamFunc: func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.UserActivity, error] {
+//				panic("mock out the Stream method")
+//			},
+//			SumFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Sum method")
+//			},
+//			UpdateFunc: func(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+//				panic("mock out the Update method")
+//			},
+//			UpdateWhereFunc: func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+//				panic("mock out the UpdateWhere method")
+//			},
+//			UpsertManyFunc: func(ctx context.Context, ts []types.UserActivity, opts storage.UpsertOptions) (int64, error) {
+//				panic("mock out the UpsertMany method")
+//			},
+//		}
+//
+//		// use mockedUserActivityRepository in code that requires types.UserActivityRepository
+//		// and then make assertions.
+//
+//	}
+type MockUserActivityRepository struct {
+	// CountFunc mocks the Count method.
+	CountFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// CountByFunc mocks the CountBy method.
+	CountByFunc func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error)
+
+	// CreateFunc mocks the Create method.
+	CreateFunc func(contextMoqParam context.Context, userActivity *types.UserActivity) error
+
+	// CreateManyFunc mocks the CreateMany method.
+	CreateManyFunc func(ctx context.Context, ts []types.UserActivity, batchSize int) (int64, error)
+
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(contextMoqParam context.Context, userActivity *types.UserActivity) error
+
+	// DeleteWhereFunc mocks the DeleteWhere method.
+	DeleteWhereFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// ExistsFunc mocks the Exists method.
+	ExistsFunc func(ctx context.Context, filter storage.Predicate) (bool, error)
+
+	// FindFunc mocks the Find method.
+	FindFunc func(contextMoqParam context.Context, query storage.Query) ([]types.UserActivity, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.UserActivity, error)
+
+	// GetByFieldsFunc mocks the GetByFields method.
+	GetByFieldsFunc func(ctx context.Context, fields map[string]string, forUpdate bool) (*types.UserActivity, error)
+
+	// GetByIDFunc mocks the GetByID method.
+	GetByIDFunc func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.UserActivity, error)
+
+	// GetPageFunc mocks the GetPage method.
+	GetPageFunc func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.UserActivity], error)
+
+	// GetUserActivityFunc mocks the GetUserActivity method.
+	GetUserActivityFunc func(ctx context.Context, userID uuid.UUID, req storage.PageRequest, filter storage.Predicate) (*storage.Page[types.UserActivity], error)
+
+	// MaxFunc mocks the Max method.
+	MaxFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// MinFunc mocks the Min method.
+	MinFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// PurgeFunc mocks the Purge method.
+	PurgeFunc func(contextMoqParam context.Context, userActivity *types.UserActivity) error
+
+	// RestoreFunc mocks the Restore method.
+	RestoreFunc func(ctx context.Context, id uuid.UUID) error
+
+	// StreamFunc mocks the Stream method.
+	StreamFunc func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.UserActivity, error]
+
+	// SumFunc mocks the Sum method.
+	SumFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(contextMoqParam context.Context, userActivity *types.UserActivity) error
+
+	// UpdateWhereFunc mocks the UpdateWhere method.
+	UpdateWhereFunc func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error)
+
+	// UpsertManyFunc mocks the UpsertMany method.
+	UpsertManyFunc func(ctx context.Context, ts []types.UserActivity, opts storage.UpsertOptions) (int64, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Count holds details about calls to the Count method.
+		Count []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// CountBy holds details about calls to the CountBy method.
+		CountBy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// UserActivity is the userActivity argument value.
+			UserActivity *types.UserActivity
+		}
+		// CreateMany holds details about calls to the CreateMany method.
+		CreateMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.UserActivity
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// UserActivity is the userActivity argument value.
+			UserActivity *types.UserActivity
+		}
+		// DeleteWhere holds details about calls to the DeleteWhere method.
+		DeleteWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Exists holds details about calls to the Exists method.
+		Exists []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Find holds details about calls to the Find method.
+		Find []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Query is the query argument value.
+			Query storage.Query
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// SQLModifier is the sQLModifier argument value.
+			SQLModifier storage.SQLModifier
+		}
+		// GetByFields holds details about calls to the GetByFields method.
+		GetByFields []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Fields is the fields argument value.
+			Fields map[string]string
+			// ForUpdate is the forUpdate argument value.
+			ForUpdate bool
+		}
+		// GetByID holds details about calls to the GetByID method.
+		GetByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+			// ForUpdate is the forUpdate argument value.
+			ForUpdate bool
+		}
+		// GetPage holds details about calls to the GetPage method.
+		GetPage []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// PageRequest is the pageRequest argument value.
+			PageRequest storage.PageRequest
+			// Predicate is the predicate argument value.
+			Predicate storage.Predicate
+		}
+		// GetUserActivity holds details about calls to the GetUserActivity method.
+		GetUserActivity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+			// Req is the req argument value.
+			Req storage.PageRequest
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Max holds details about calls to the Max method.
+		Max []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Min holds details about calls to the Min method.
+		Min []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Purge holds details about calls to the Purge method.
+		Purge []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// UserActivity is the userActivity argument value.
+			UserActivity *types.UserActivity
+		}
+		// Restore holds details about calls to the Restore method.
+		Restore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// Stream holds details about calls to the Stream method.
+		Stream []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// M is the m argument value.
+			M storage.SQLModifier
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
+		// Sum holds details about calls to the Sum method.
+		Sum []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// UserActivity is the userActivity argument value.
+			UserActivity *types.UserActivity
+		}
+		// UpdateWhere holds details about calls to the UpdateWhere method.
+		UpdateWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+			// Values is the values argument value.
+			Values map[string]any
+		}
+		// UpsertMany holds details about calls to the UpsertMany method.
+		UpsertMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.UserActivity
+			// Opts is the opts argument value.
+			Opts storage.UpsertOptions
+		}
+	}
+	lockCount           sync.RWMutex
+	lockCountBy         sync.RWMutex
+	lockCreate          sync.RWMutex
+	lockCreateMany      sync.RWMutex
+	lockDelete          sync.RWMutex
+	lockDeleteWhere     sync.RWMutex
+	lockExists          sync.RWMutex
+	lockFind            sync.RWMutex
+	lockGetAll          sync.RWMutex
+	lockGetByFields     sync.RWMutex
+	lockGetByID         sync.RWMutex
+	lockGetPage         sync.RWMutex
+	lockGetUserActivity sync.RWMutex
+	lockMax             sync.RWMutex
+	lockMin             sync.RWMutex
+	lockPurge           sync.RWMutex
+	lockRestore         sync.RWMutex
+	lockStream          sync.RWMutex
+	lockSum             sync.RWMutex
+	lockUpdate          sync.RWMutex
+	lockUpdateWhere     sync.RWMutex
+	lockUpsertMany      sync.RWMutex
+}
+
+// Count calls CountFunc.
+func (mock *MockUserActivityRepository) Count(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.CountFunc == nil {
+		panic("MockUserActivityRepository.CountFunc: method is nil but UserActivityRepository.Count was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockCount.Lock()
+	mock.calls.Count = append(mock.calls.Count, callInfo)
+	mock.lockCount.Unlock()
+	return mock.CountFunc(ctx, filter)
+}
+
+// CountCalls gets all the calls that were made to Count.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.CountCalls())
+func (mock *MockUserActivityRepository) CountCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockCount.RLock()
+	calls = mock.calls.Count
+	mock.lockCount.RUnlock()
+	return calls
+}
+
+// CountBy calls CountByFunc.
+func (mock *MockUserActivityRepository) CountBy(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+	if mock.CountByFunc == nil {
+		panic("MockUserActivityRepository.CountByFunc: method is nil but UserActivityRepository.CountBy was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockCountBy.Lock()
+	mock.calls.CountBy = append(mock.calls.CountBy, callInfo)
+	mock.lockCountBy.Unlock()
+	return mock.CountByFunc(ctx, column, filter)
+}
+
+// CountByCalls gets all the calls that were made to CountBy.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.CountByCalls())
+func (mock *MockUserActivityRepository) CountByCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockCountBy.RLock()
+	calls = mock.calls.CountBy
+	mock.lockCountBy.RUnlock()
+	return calls
+}
+
+// Create calls CreateFunc.
+func (mock *MockUserActivityRepository) Create(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+	if mock.CreateFunc == nil {
+		panic("MockUserActivityRepository.CreateFunc: method is nil but UserActivityRepository.Create was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}{
+		ContextMoqParam: contextMoqParam,
+		UserActivity:    userActivity,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(contextMoqParam, userActivity)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.CreateCalls())
+func (mock *MockUserActivityRepository) CreateCalls() []struct {
+	ContextMoqParam context.Context
+	UserActivity    *types.UserActivity
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// CreateMany calls CreateManyFunc.
+func (mock *MockUserActivityRepository) CreateMany(ctx context.Context, ts []types.UserActivity, batchSize int) (int64, error) {
+	if mock.CreateManyFunc == nil {
+		panic("MockUserActivityRepository.CreateManyFunc: method is nil but UserActivityRepository.CreateMany was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Ts        []types.UserActivity
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		Ts:        ts,
+		BatchSize: batchSize,
+	}
+	mock.lockCreateMany.Lock()
+	mock.calls.CreateMany = append(mock.calls.CreateMany, callInfo)
+	mock.lockCreateMany.Unlock()
+	return mock.CreateManyFunc(ctx, ts, batchSize)
+}
+
+// CreateManyCalls gets all the calls that were made to CreateMany.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.CreateManyCalls())
+func (mock *MockUserActivityRepository) CreateManyCalls() []struct {
+	Ctx       context.Context
+	Ts        []types.UserActivity
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Ts        []types.UserActivity
+		BatchSize int
+	}
+	mock.lockCreateMany.RLock()
+	calls = mock.calls.CreateMany
+	mock.lockCreateMany.RUnlock()
+	return calls
+}
+
+// Delete calls DeleteFunc.
+func (mock *MockUserActivityRepository) Delete(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+	if mock.DeleteFunc == nil {
+		panic("MockUserActivityRepository.DeleteFunc: method is nil but UserActivityRepository.Delete was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}{
+		ContextMoqParam: contextMoqParam,
+		UserActivity:    userActivity,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(contextMoqParam, userActivity)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.DeleteCalls())
+func (mock *MockUserActivityRepository) DeleteCalls() []struct {
+	ContextMoqParam context.Context
+	UserActivity    *types.UserActivity
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// DeleteWhere calls DeleteWhereFunc.
+func (mock *MockUserActivityRepository) DeleteWhere(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.DeleteWhereFunc == nil {
+		panic("MockUserActivityRepository.DeleteWhereFunc: method is nil but UserActivityRepository.DeleteWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockDeleteWhere.Lock()
+	mock.calls.DeleteWhere = append(mock.calls.DeleteWhere, callInfo)
+	mock.lockDeleteWhere.Unlock()
+	return mock.DeleteWhereFunc(ctx, filter)
+}
+
+// DeleteWhereCalls gets all the calls that were made to DeleteWhere.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.DeleteWhereCalls())
+func (mock *MockUserActivityRepository) DeleteWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockDeleteWhere.RLock()
+	calls = mock.calls.DeleteWhere
+	mock.lockDeleteWhere.RUnlock()
+	return calls
+}
+
+// Exists calls ExistsFunc.
+func (mock *MockUserActivityRepository) Exists(ctx context.Context, filter storage.Predicate) (bool, error) {
+	if mock.ExistsFunc == nil {
+		panic("MockUserActivityRepository.ExistsFunc: method is nil but UserActivityRepository.Exists was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockExists.Lock()
+	mock.calls.Exists = append(mock.calls.Exists, callInfo)
+	mock.lockExists.Unlock()
+	return mock.ExistsFunc(ctx, filter)
+}
+
+// ExistsCalls gets all the calls that were made to Exists.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.ExistsCalls())
+func (mock *MockUserActivityRepository) ExistsCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockExists.RLock()
+	calls = mock.calls.Exists
+	mock.lockExists.RUnlock()
+	return calls
+}
+
+// Find calls FindFunc.
+func (mock *MockUserActivityRepository) Find(contextMoqParam context.Context, query storage.Query) ([]types.UserActivity, error) {
+	if mock.FindFunc == nil {
+		panic("MockUserActivityRepository.FindFunc: method is nil but UserActivityRepository.Find was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}{
+		ContextMoqParam: contextMoqParam,
+		Query:           query,
+	}
+	mock.lockFind.Lock()
+	mock.calls.Find = append(mock.calls.Find, callInfo)
+	mock.lockFind.Unlock()
+	return mock.FindFunc(contextMoqParam, query)
+}
+
+// FindCalls gets all the calls that were made to Find.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.FindCalls())
+func (mock *MockUserActivityRepository) FindCalls() []struct {
+	ContextMoqParam context.Context
+	Query           storage.Query
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}
+	mock.lockFind.RLock()
+	calls = mock.calls.Find
+	mock.lockFind.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *MockUserActivityRepository) GetAll(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.UserActivity, error) {
+	if mock.GetAllFunc == nil {
+		panic("MockUserActivityRepository.GetAllFunc: method is nil but UserActivityRepository.GetAll was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		SQLModifier     storage.SQLModifier
+	}{
+		ContextMoqParam: contextMoqParam,
+		SQLModifier:     sQLModifier,
+	}
+	mock.lockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	mock.lockGetAll.Unlock()
+	return mock.GetAllFunc(contextMoqParam, sQLModifier)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.GetAllCalls())
+func (mock *MockUserActivityRepository) GetAllCalls() []struct {
+	ContextMoqParam context.Context
+	SQLModifier     storage.SQLModifier
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		SQLModifier     storage.SQLModifier
+	}
+	mock.lockGetAll.RLock()
+	calls = mock.calls.GetAll
+	mock.lockGetAll.RUnlock()
+	return calls
+}
+
+// GetByFields calls GetByFieldsFunc.
+func (mock *MockUserActivityRepository) GetByFields(ctx context.Context, fields map[string]string, forUpdate bool) (*types.UserActivity, error) {
+	if mock.GetByFieldsFunc == nil {
+		panic("MockUserActivityRepository.GetByFieldsFunc: method is nil but UserActivityRepository.GetByFields was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Fields    map[string]string
+		ForUpdate bool
+	}{
+		Ctx:       ctx,
+		Fields:    fields,
+		ForUpdate: forUpdate,
+	}
+	mock.lockGetByFields.Lock()
+	mock.calls.GetByFields = append(mock.calls.GetByFields, callInfo)
+	mock.lockGetByFields.Unlock()
+	return mock.GetByFieldsFunc(ctx, fields, forUpdate)
+}
+
+// GetByFieldsCalls gets all the calls that were made to GetByFields.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.GetByFieldsCalls())
+func (mock *MockUserActivityRepository) GetByFieldsCalls() []struct {
+	Ctx       context.Context
+	Fields    map[string]string
+	ForUpdate bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Fields    map[string]string
+		ForUpdate bool
+	}
+	mock.lockGetByFields.RLock()
+	calls = mock.calls.GetByFields
+	mock.lockGetByFields.RUnlock()
+	return calls
+}
+
+// GetByID calls GetByIDFunc.
+func (mock *MockUserActivityRepository) GetByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.UserActivity, error) {
+	if mock.GetByIDFunc == nil {
+		panic("MockUserActivityRepository.GetByIDFunc: method is nil but UserActivityRepository.GetByID was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ID        uuid.UUID
+		ForUpdate bool
+	}{
+		Ctx:       ctx,
+		ID:        id,
+		ForUpdate: forUpdate,
+	}
+	mock.lockGetByID.Lock()
+	mock.calls.GetByID = append(mock.calls.GetByID, callInfo)
+	mock.lockGetByID.Unlock()
+	return mock.GetByIDFunc(ctx, id, forUpdate)
+}
+
+// GetByIDCalls gets all the calls that were made to GetByID.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.GetByIDCalls())
+func (mock *MockUserActivityRepository) GetByIDCalls() []struct {
+	Ctx       context.Context
+	ID        uuid.UUID
+	ForUpdate bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ID        uuid.UUID
+		ForUpdate bool
+	}
+	mock.lockGetByID.RLock()
+	calls = mock.calls.GetByID
+	mock.lockGetByID.RUnlock()
+	return calls
+}
+
+// GetPage calls GetPageFunc.
+func (mock *MockUserActivityRepository) GetPage(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.UserActivity], error) {
+	if mock.GetPageFunc == nil {
+		panic("MockUserActivityRepository.GetPageFunc: method is nil but UserActivityRepository.GetPage was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}{
+		ContextMoqParam: contextMoqParam,
+		PageRequest:     pageRequest,
+		Predicate:       predicate,
+	}
+	mock.lockGetPage.Lock()
+	mock.calls.GetPage = append(mock.calls.GetPage, callInfo)
+	mock.lockGetPage.Unlock()
+	return mock.GetPageFunc(contextMoqParam, pageRequest, predicate)
+}
+
+// GetPageCalls gets all the calls that were made to GetPage.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.GetPageCalls())
+func (mock *MockUserActivityRepository) GetPageCalls() []struct {
+	ContextMoqParam context.Context
+	PageRequest     storage.PageRequest
+	Predicate       storage.Predicate
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}
+	mock.lockGetPage.RLock()
+	calls = mock.calls.GetPage
+	mock.lockGetPage.RUnlock()
+	return calls
+}
+
+// GetUserActivity calls GetUserActivityFunc.
+func (mock *MockUserActivityRepository) GetUserActivity(ctx context.Context, userID uuid.UUID, req storage.PageRequest, filter storage.Predicate) (*storage.Page[types.UserActivity], error) {
+	if mock.GetUserActivityFunc == nil {
+		panic("MockUserActivityRepository.GetUserActivityFunc: method is nil but UserActivityRepository.GetUserActivity was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		Req    storage.PageRequest
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		Req:    req,
+		Filter: filter,
+	}
+	mock.lockGetUserActivity.Lock()
+	mock.calls.GetUserActivity = append(mock.calls.GetUserActivity, callInfo)
+	mock.lockGetUserActivity.Unlock()
+	return mock.GetUserActivityFunc(ctx, userID, req, filter)
+}
+
+// GetUserActivityCalls gets all the calls that were made to GetUserActivity.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.GetUserActivityCalls())
+func (mock *MockUserActivityRepository) GetUserActivityCalls() []struct {
+	Ctx    context.Context
+	UserID uuid.UUID
+	Req    storage.PageRequest
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		Req    storage.PageRequest
+		Filter storage.Predicate
+	}
+	mock.lockGetUserActivity.RLock()
+	calls = mock.calls.GetUserActivity
+	mock.lockGetUserActivity.RUnlock()
+	return calls
+}
+
+// Max calls MaxFunc.
+func (mock *MockUserActivityRepository) Max(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MaxFunc == nil {
+		panic("MockUserActivityRepository.MaxFunc: method is nil but UserActivityRepository.Max was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMax.Lock()
+	mock.calls.Max = append(mock.calls.Max, callInfo)
+	mock.lockMax.Unlock()
+	return mock.MaxFunc(ctx, column, filter)
+}
+
+// MaxCalls gets all the calls that were made to Max.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.MaxCalls())
+func (mock *MockUserActivityRepository) MaxCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMax.RLock()
+	calls = mock.calls.Max
+	mock.lockMax.RUnlock()
+	return calls
+}
+
+// Min calls MinFunc.
+func (mock *MockUserActivityRepository) Min(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MinFunc == nil {
+		panic("MockUserActivityRepository.MinFunc: method is nil but UserActivityRepository.Min was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMin.Lock()
+	mock.calls.Min = append(mock.calls.Min, callInfo)
+	mock.lockMin.Unlock()
+	return mock.MinFunc(ctx, column, filter)
+}
+
+// MinCalls gets all the calls that were made to Min.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.MinCalls())
+func (mock *MockUserActivityRepository) MinCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMin.RLock()
+	calls = mock.calls.Min
+	mock.lockMin.RUnlock()
+	return calls
+}
+
+// Purge calls PurgeFunc.
+func (mock *MockUserActivityRepository) Purge(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+	if mock.PurgeFunc == nil {
+		panic("MockUserActivityRepository.PurgeFunc: method is nil but UserActivityRepository.Purge was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}{
+		ContextMoqParam: contextMoqParam,
+		UserActivity:    userActivity,
+	}
+	mock.lockPurge.Lock()
+	mock.calls.Purge = append(mock.calls.Purge, callInfo)
+	mock.lockPurge.Unlock()
+	return mock.PurgeFunc(contextMoqParam, userActivity)
+}
+
+// PurgeCalls gets all the calls that were made to Purge.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.PurgeCalls())
+func (mock *MockUserActivityRepository) PurgeCalls() []struct {
+	ContextMoqParam context.Context
+	UserActivity    *types.UserActivity
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}
+	mock.lockPurge.RLock()
+	calls = mock.calls.Purge
+	mock.lockPurge.RUnlock()
+	return calls
+}
+
+// Restore calls RestoreFunc.
+func (mock *MockUserActivityRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	if mock.RestoreFunc == nil {
+		panic("MockUserActivityRepository.RestoreFunc: method is nil but UserActivityRepository.Restore was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestore.Lock()
+	mock.calls.Restore = append(mock.calls.Restore, callInfo)
+	mock.lockRestore.Unlock()
+	return mock.RestoreFunc(ctx, id)
+}
+
+// RestoreCalls gets all the calls that were made to Restore.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.RestoreCalls())
+func (mock *MockUserActivityRepository) RestoreCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockRestore.RLock()
+	calls = mock.calls.Restore
+	mock.lockRestore.RUnlock()
+	return calls
+}
+
+// Stream calls StreamFunc.
+func (mock *MockUserActivityRepository) Stream(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.UserActivity, error] {
+	if mock.StreamFunc == nil {
+		panic("MockUserActivityRepository.StreamFunc: method is nil but UserActivityRepository.Stream was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		M:         m,
+		BatchSize: batchSize,
+	}
+	mock.lockStream.Lock()
+	mock.calls.Stream = append(mock.calls.Stream, callInfo)
+	mock.lockStream.Unlock()
+	return mock.StreamFunc(ctx, m, batchSize)
+}
+
+// StreamCalls gets all the calls that were made to Stream.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.StreamCalls())
+func (mock *MockUserActivityRepository) StreamCalls() []struct {
+	Ctx       context.Context
+	M         storage.SQLModifier
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}
+	mock.lockStream.RLock()
+	calls = mock.calls.Stream
+	mock.lockStream.RUnlock()
+	return calls
+}
+
+// Sum calls SumFunc.
+func (mock *MockUserActivityRepository) Sum(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.SumFunc == nil {
+		panic("MockUserActivityRepository.SumFunc: method is nil but UserActivityRepository.Sum was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockSum.Lock()
+	mock.calls.Sum = append(mock.calls.Sum, callInfo)
+	mock.lockSum.Unlock()
+	return mock.SumFunc(ctx, column, filter)
+}
+
+// SumCalls gets all the calls that were made to Sum.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.SumCalls())
+func (mock *MockUserActivityRepository) SumCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockSum.RLock()
+	calls = mock.calls.Sum
+	mock.lockSum.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *MockUserActivityRepository) Update(contextMoqParam context.Context, userActivity *types.UserActivity) error {
+	if mock.UpdateFunc == nil {
+		panic("MockUserActivityRepository.UpdateFunc: method is nil but UserActivityRepository.Update was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}{
+		ContextMoqParam: contextMoqParam,
+		UserActivity:    userActivity,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	return mock.UpdateFunc(contextMoqParam, userActivity)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.UpdateCalls())
+func (mock *MockUserActivityRepository) UpdateCalls() []struct {
+	ContextMoqParam context.Context
+	UserActivity    *types.UserActivity
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		UserActivity    *types.UserActivity
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// UpdateWhere calls UpdateWhereFunc.
+func (mock *MockUserActivityRepository) UpdateWhere(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+	if mock.UpdateWhereFunc == nil {
+		panic("MockUserActivityRepository.UpdateWhereFunc: method is nil but UserActivityRepository.UpdateWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+		Values: values,
+	}
+	mock.lockUpdateWhere.Lock()
+	mock.calls.UpdateWhere = append(mock.calls.UpdateWhere, callInfo)
+	mock.lockUpdateWhere.Unlock()
+	return mock.UpdateWhereFunc(ctx, filter, values)
+}
+
+// UpdateWhereCalls gets all the calls that were made to UpdateWhere.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.UpdateWhereCalls())
+func (mock *MockUserActivityRepository) UpdateWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+	Values map[string]any
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}
+	mock.lockUpdateWhere.RLock()
+	calls = mock.calls.UpdateWhere
+	mock.lockUpdateWhere.RUnlock()
+	return calls
+}
+
+// UpsertMany calls UpsertManyFunc.
+func (mock *MockUserActivityRepository) UpsertMany(ctx context.Context, ts []types.UserActivity, opts storage.UpsertOptions) (int64, error) {
+	if mock.UpsertManyFunc == nil {
+		panic("MockUserActivityRepository.UpsertManyFunc: method is nil but UserActivityRepository.UpsertMany was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Ts   []types.UserActivity
+		Opts storage.UpsertOptions
+	}{
+		Ctx:  ctx,
+		Ts:   ts,
+		Opts: opts,
+	}
+	mock.lockUpsertMany.Lock()
+	mock.calls.UpsertMany = append(mock.calls.UpsertMany, callInfo)
+	mock.lockUpsertMany.Unlock()
+	return mock.UpsertManyFunc(ctx, ts, opts)
+}
+
+// UpsertManyCalls gets all the calls that were made to UpsertMany.
+// Check the length with:
+//
+//	len(mockedUserActivityRepository.UpsertManyCalls())
+func (mock *MockUserActivityRepository) UpsertManyCalls() []struct {
+	Ctx  context.Context
+	Ts   []types.UserActivity
+	Opts storage.UpsertOptions
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Ts   []types.UserActivity
+		Opts storage.UpsertOptions
+	}
+	mock.lockUpsertMany.RLock()
+	calls = mock.calls.UpsertMany
+	mock.lockUpsertMany.RUnlock()
+	return calls
+}
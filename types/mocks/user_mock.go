@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/zechao158/ecomm/storage"
 	"github.com/zechao158/ecomm/types"
+	"iter"
 	"sync"
 )
 
@@ -21,12 +22,30 @@ var _ types.UserRepository = &MockUserRepository{}
 //
 //		// make and configure a mocked types.UserRepository
 //		mockedUserRepository := &MockUserRepository{
+//			CountFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the Count method")
+//			},
+//			CountByFunc: func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+//				panic("mock out the CountBy method")
+//			},
 //			CreateFunc: func(contextMoqParam context.Context, user *types.User) error {
 //				panic("mock out the Create method")
 //			},
+//			CreateManyFunc: func(ctx context.Context, ts []types.User, batchSize int) (int64, error) {
+//				panic("mock out the CreateMany method")
+//			},
 //			DeleteFunc: func(contextMoqParam context.Context, user *types.User) error {
 //				panic("mock out the Delete method")
 //			},
+//			DeleteWhereFunc: func(ctx context.Context, filter storage.Predicate) (int64, error) {
+//				panic("mock out the DeleteWhere method")
+//			},
+//			ExistsFunc: func(ctx context.Context, filter storage.Predicate) (bool, error) {
+//				panic("mock out the Exists method")
+//			},
+//			FindFunc: func(contextMoqParam context.Context, query storage.Query) ([]types.User, error) {
+//				panic("mock out the Find method")
+//			},
 //			GetAllFunc: func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.User, error) {
 //				panic("mock out the GetAll method")
 //			},
@@ -36,12 +55,39 @@ var _ types.UserRepository = &MockUserRepository{}
 //			GetByIDFunc: func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.User, error) {
 //				panic("mock out the GetByID method")
 //			},
+//			GetPageFunc: func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.User], error) {
+//				panic("mock out the GetPage method")
+//			},
 //			GetUserByEmailFunc: func(ctx context.Context, email string) (*types.User, error) {
 //				panic("mock out the GetUserByEmail method")
 //			},
+//			MaxFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Max method")
+//			},
+//			MinFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Min method")
+//			},
+//			PurgeFunc: func(contextMoqParam context.Context, user *types.User) error {
+//				panic("mock out the Purge method")
+//			},
+//			RestoreFunc: func(ctx context.Context, id uuid.UUID) error {
+//				panic("mock out the Restore method")
+//			},
+//			StreamFunc: func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.User, error] {
+//				panic("mock out the Stream method")
+//			},
+//			SumFunc: func(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+//				panic("mock out the Sum method")
+//			},
 //			UpdateFunc: func(contextMoqParam context.Context, user *types.User) error {
 //				panic("mock out the Update method")
 //			},
+//			UpdateWhereFunc: func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+//				panic("mock out the UpdateWhere method")
+//			},
+//			UpsertManyFunc: func(ctx context.Context, ts []types.User, opts storage.UpsertOptions) (int64, error) {
+//				panic("mock out the UpsertMany method")
+//			},
 //		}
 //
 //		// use mockedUserRepository in code that requires types.UserRepository
@@ -49,12 +95,30 @@ var _ types.UserRepository = &MockUserRepository{}
 //
 //	}
 type MockUserRepository struct {
+	// CountFunc mocks the Count method.
+	CountFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// CountByFunc mocks the CountBy method.
+	CountByFunc func(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error)
+
 	// CreateFunc mocks the Create method.
 	CreateFunc func(contextMoqParam context.Context, user *types.User) error
 
+	// CreateManyFunc mocks the CreateMany method.
+	CreateManyFunc func(ctx context.Context, ts []types.User, batchSize int) (int64, error)
+
 	// DeleteFunc mocks the Delete method.
 	DeleteFunc func(contextMoqParam context.Context, user *types.User) error
 
+	// DeleteWhereFunc mocks the DeleteWhere method.
+	DeleteWhereFunc func(ctx context.Context, filter storage.Predicate) (int64, error)
+
+	// ExistsFunc mocks the Exists method.
+	ExistsFunc func(ctx context.Context, filter storage.Predicate) (bool, error)
+
+	// FindFunc mocks the Find method.
+	FindFunc func(contextMoqParam context.Context, query storage.Query) ([]types.User, error)
+
 	// GetAllFunc mocks the GetAll method.
 	GetAllFunc func(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.User, error)
 
@@ -64,14 +128,57 @@ type MockUserRepository struct {
 	// GetByIDFunc mocks the GetByID method.
 	GetByIDFunc func(ctx context.Context, id uuid.UUID, forUpdate bool) (*types.User, error)
 
+	// GetPageFunc mocks the GetPage method.
+	GetPageFunc func(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.User], error)
+
 	// GetUserByEmailFunc mocks the GetUserByEmail method.
 	GetUserByEmailFunc func(ctx context.Context, email string) (*types.User, error)
 
+	// MaxFunc mocks the Max method.
+	MaxFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// MinFunc mocks the Min method.
+	MinFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
+	// PurgeFunc mocks the Purge method.
+	PurgeFunc func(contextMoqParam context.Context, user *types.User) error
+
+	// RestoreFunc mocks the Restore method.
+	RestoreFunc func(ctx context.Context, id uuid.UUID) error
+
+	// StreamFunc mocks the Stream method.
+	StreamFunc func(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.User, error]
+
+	// SumFunc mocks the Sum method.
+	SumFunc func(ctx context.Context, column string, filter storage.Predicate) (float64, error)
+
 	// UpdateFunc mocks the Update method.
 	UpdateFunc func(contextMoqParam context.Context, user *types.User) error
 
+	// UpdateWhereFunc mocks the UpdateWhere method.
+	UpdateWhereFunc func(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error)
+
+	// UpsertManyFunc mocks the UpsertMany method.
+	UpsertManyFunc func(ctx context.Context, ts []types.User, opts storage.UpsertOptions) (int64, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// Count holds details about calls to the Count method.
+		Count []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// CountBy holds details about calls to the CountBy method.
+		CountBy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
 		// Create holds details about calls to the Create method.
 		Create []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -79,6 +186,15 @@ type MockUserRepository struct {
 			// User is the user argument value.
 			User *types.User
 		}
+		// CreateMany holds details about calls to the CreateMany method.
+		CreateMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.User
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
 		// Delete holds details about calls to the Delete method.
 		Delete []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -86,6 +202,27 @@ type MockUserRepository struct {
 			// User is the user argument value.
 			User *types.User
 		}
+		// DeleteWhere holds details about calls to the DeleteWhere method.
+		DeleteWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Exists holds details about calls to the Exists method.
+		Exists []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Find holds details about calls to the Find method.
+		Find []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Query is the query argument value.
+			Query storage.Query
+		}
 		// GetAll holds details about calls to the GetAll method.
 		GetAll []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -111,6 +248,15 @@ type MockUserRepository struct {
 			// ForUpdate is the forUpdate argument value.
 			ForUpdate bool
 		}
+		// GetPage holds details about calls to the GetPage method.
+		GetPage []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// PageRequest is the pageRequest argument value.
+			PageRequest storage.PageRequest
+			// Predicate is the predicate argument value.
+			Predicate storage.Predicate
+		}
 		// GetUserByEmail holds details about calls to the GetUserByEmail method.
 		GetUserByEmail []struct {
 			// Ctx is the ctx argument value.
@@ -118,6 +264,56 @@ type MockUserRepository struct {
 			// Email is the email argument value.
 			Email string
 		}
+		// Max holds details about calls to the Max method.
+		Max []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Min holds details about calls to the Min method.
+		Min []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
+		// Purge holds details about calls to the Purge method.
+		Purge []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// User is the user argument value.
+			User *types.User
+		}
+		// Restore holds details about calls to the Restore method.
+		Restore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// Stream holds details about calls to the Stream method.
+		Stream []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// M is the m argument value.
+			M storage.SQLModifier
+			// BatchSize is the batchSize argument value.
+			BatchSize int
+		}
+		// Sum holds details about calls to the Sum method.
+		Sum []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Column is the column argument value.
+			Column string
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -125,14 +321,123 @@ type MockUserRepository struct {
 			// User is the user argument value.
 			User *types.User
 		}
+		// UpdateWhere holds details about calls to the UpdateWhere method.
+		UpdateWhere []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter storage.Predicate
+			// Values is the values argument value.
+			Values map[string]any
+		}
+		// UpsertMany holds details about calls to the UpsertMany method.
+		UpsertMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ts is the ts argument value.
+			Ts []types.User
+			// Opts is the opts argument value.
+			Opts storage.UpsertOptions
+		}
 	}
+	lockCount          sync.RWMutex
+	lockCountBy        sync.RWMutex
 	lockCreate         sync.RWMutex
+	lockCreateMany     sync.RWMutex
 	lockDelete         sync.RWMutex
+	lockDeleteWhere    sync.RWMutex
+	lockExists         sync.RWMutex
+	lockFind           sync.RWMutex
 	lockGetAll         sync.RWMutex
 	lockGetByFields    sync.RWMutex
 	lockGetByID        sync.RWMutex
+	lockGetPage        sync.RWMutex
 	lockGetUserByEmail sync.RWMutex
+	lockMax            sync.RWMutex
+	lockMin            sync.RWMutex
+	lockPurge          sync.RWMutex
+	lockRestore        sync.RWMutex
+	lockStream         sync.RWMutex
+	lockSum            sync.RWMutex
 	lockUpdate         sync.RWMutex
+	lockUpdateWhere    sync.RWMutex
+	lockUpsertMany     sync.RWMutex
+}
+
+// Count calls CountFunc.
+func (mock *MockUserRepository) Count(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.CountFunc == nil {
+		panic("MockUserRepository.CountFunc: method is nil but UserRepository.Count was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockCount.Lock()
+	mock.calls.Count = append(mock.calls.Count, callInfo)
+	mock.lockCount.Unlock()
+	return mock.CountFunc(ctx, filter)
+}
+
+// CountCalls gets all the calls that were made to Count.
+// Check the length with:
+//
+//	len(mockedUserRepository.CountCalls())
+func (mock *MockUserRepository) CountCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockCount.RLock()
+	calls = mock.calls.Count
+	mock.lockCount.RUnlock()
+	return calls
+}
+
+// CountBy calls CountByFunc.
+func (mock *MockUserRepository) CountBy(ctx context.Context, column string, filter storage.Predicate) (map[string]int64, error) {
+	if mock.CountByFunc == nil {
+		panic("MockUserRepository.CountByFunc: method is nil but UserRepository.CountBy was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockCountBy.Lock()
+	mock.calls.CountBy = append(mock.calls.CountBy, callInfo)
+	mock.lockCountBy.Unlock()
+	return mock.CountByFunc(ctx, column, filter)
+}
+
+// CountByCalls gets all the calls that were made to CountBy.
+// Check the length with:
+//
+//	len(mockedUserRepository.CountByCalls())
+func (mock *MockUserRepository) CountByCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockCountBy.RLock()
+	calls = mock.calls.CountBy
+	mock.lockCountBy.RUnlock()
+	return calls
 }
 
 // Create calls CreateFunc.
@@ -171,6 +476,46 @@ func (mock *MockUserRepository) CreateCalls() []struct {
 	return calls
 }
 
+// CreateMany calls CreateManyFunc.
+func (mock *MockUserRepository) CreateMany(ctx context.Context, ts []types.User, batchSize int) (int64, error) {
+	if mock.CreateManyFunc == nil {
+		panic("MockUserRepository.CreateManyFunc: method is nil but UserRepository.CreateMany was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Ts        []types.User
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		Ts:        ts,
+		BatchSize: batchSize,
+	}
+	mock.lockCreateMany.Lock()
+	mock.calls.CreateMany = append(mock.calls.CreateMany, callInfo)
+	mock.lockCreateMany.Unlock()
+	return mock.CreateManyFunc(ctx, ts, batchSize)
+}
+
+// CreateManyCalls gets all the calls that were made to CreateMany.
+// Check the length with:
+//
+//	len(mockedUserRepository.CreateManyCalls())
+func (mock *MockUserRepository) CreateManyCalls() []struct {
+	Ctx       context.Context
+	Ts        []types.User
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Ts        []types.User
+		BatchSize int
+	}
+	mock.lockCreateMany.RLock()
+	calls = mock.calls.CreateMany
+	mock.lockCreateMany.RUnlock()
+	return calls
+}
+
 // Delete calls DeleteFunc.
 func (mock *MockUserRepository) Delete(contextMoqParam context.Context, user *types.User) error {
 	if mock.DeleteFunc == nil {
@@ -207,6 +552,114 @@ func (mock *MockUserRepository) DeleteCalls() []struct {
 	return calls
 }
 
+// DeleteWhere calls DeleteWhereFunc.
+func (mock *MockUserRepository) DeleteWhere(ctx context.Context, filter storage.Predicate) (int64, error) {
+	if mock.DeleteWhereFunc == nil {
+		panic("MockUserRepository.DeleteWhereFunc: method is nil but UserRepository.DeleteWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockDeleteWhere.Lock()
+	mock.calls.DeleteWhere = append(mock.calls.DeleteWhere, callInfo)
+	mock.lockDeleteWhere.Unlock()
+	return mock.DeleteWhereFunc(ctx, filter)
+}
+
+// DeleteWhereCalls gets all the calls that were made to DeleteWhere.
+// Check the length with:
+//
+//	len(mockedUserRepository.DeleteWhereCalls())
+func (mock *MockUserRepository) DeleteWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockDeleteWhere.RLock()
+	calls = mock.calls.DeleteWhere
+	mock.lockDeleteWhere.RUnlock()
+	return calls
+}
+
+// Exists calls ExistsFunc.
+func (mock *MockUserRepository) Exists(ctx context.Context, filter storage.Predicate) (bool, error) {
+	if mock.ExistsFunc == nil {
+		panic("MockUserRepository.ExistsFunc: method is nil but UserRepository.Exists was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockExists.Lock()
+	mock.calls.Exists = append(mock.calls.Exists, callInfo)
+	mock.lockExists.Unlock()
+	return mock.ExistsFunc(ctx, filter)
+}
+
+// ExistsCalls gets all the calls that were made to Exists.
+// Check the length with:
+//
+//	len(mockedUserRepository.ExistsCalls())
+func (mock *MockUserRepository) ExistsCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+	}
+	mock.lockExists.RLock()
+	calls = mock.calls.Exists
+	mock.lockExists.RUnlock()
+	return calls
+}
+
+// Find calls FindFunc.
+func (mock *MockUserRepository) Find(contextMoqParam context.Context, query storage.Query) ([]types.User, error) {
+	if mock.FindFunc == nil {
+		panic("MockUserRepository.FindFunc: method is nil but UserRepository.Find was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}{
+		ContextMoqParam: contextMoqParam,
+		Query:           query,
+	}
+	mock.lockFind.Lock()
+	mock.calls.Find = append(mock.calls.Find, callInfo)
+	mock.lockFind.Unlock()
+	return mock.FindFunc(contextMoqParam, query)
+}
+
+// FindCalls gets all the calls that were made to Find.
+// Check the length with:
+//
+//	len(mockedUserRepository.FindCalls())
+func (mock *MockUserRepository) FindCalls() []struct {
+	ContextMoqParam context.Context
+	Query           storage.Query
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Query           storage.Query
+	}
+	mock.lockFind.RLock()
+	calls = mock.calls.Find
+	mock.lockFind.RUnlock()
+	return calls
+}
+
 // GetAll calls GetAllFunc.
 func (mock *MockUserRepository) GetAll(contextMoqParam context.Context, sQLModifier storage.SQLModifier) ([]types.User, error) {
 	if mock.GetAllFunc == nil {
@@ -323,6 +776,46 @@ func (mock *MockUserRepository) GetByIDCalls() []struct {
 	return calls
 }
 
+// GetPage calls GetPageFunc.
+func (mock *MockUserRepository) GetPage(contextMoqParam context.Context, pageRequest storage.PageRequest, predicate storage.Predicate) (*storage.Page[types.User], error) {
+	if mock.GetPageFunc == nil {
+		panic("MockUserRepository.GetPageFunc: method is nil but UserRepository.GetPage was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}{
+		ContextMoqParam: contextMoqParam,
+		PageRequest:     pageRequest,
+		Predicate:       predicate,
+	}
+	mock.lockGetPage.Lock()
+	mock.calls.GetPage = append(mock.calls.GetPage, callInfo)
+	mock.lockGetPage.Unlock()
+	return mock.GetPageFunc(contextMoqParam, pageRequest, predicate)
+}
+
+// GetPageCalls gets all the calls that were made to GetPage.
+// Check the length with:
+//
+//	len(mockedUserRepository.GetPageCalls())
+func (mock *MockUserRepository) GetPageCalls() []struct {
+	ContextMoqParam context.Context
+	PageRequest     storage.PageRequest
+	Predicate       storage.Predicate
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		PageRequest     storage.PageRequest
+		Predicate       storage.Predicate
+	}
+	mock.lockGetPage.RLock()
+	calls = mock.calls.GetPage
+	mock.lockGetPage.RUnlock()
+	return calls
+}
+
 // GetUserByEmail calls GetUserByEmailFunc.
 func (mock *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
 	if mock.GetUserByEmailFunc == nil {
@@ -359,6 +852,238 @@ func (mock *MockUserRepository) GetUserByEmailCalls() []struct {
 	return calls
 }
 
+// Max calls MaxFunc.
+func (mock *MockUserRepository) Max(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MaxFunc == nil {
+		panic("MockUserRepository.MaxFunc: method is nil but UserRepository.Max was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMax.Lock()
+	mock.calls.Max = append(mock.calls.Max, callInfo)
+	mock.lockMax.Unlock()
+	return mock.MaxFunc(ctx, column, filter)
+}
+
+// MaxCalls gets all the calls that were made to Max.
+// Check the length with:
+//
+//	len(mockedUserRepository.MaxCalls())
+func (mock *MockUserRepository) MaxCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMax.RLock()
+	calls = mock.calls.Max
+	mock.lockMax.RUnlock()
+	return calls
+}
+
+// Min calls MinFunc.
+func (mock *MockUserRepository) Min(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.MinFunc == nil {
+		panic("MockUserRepository.MinFunc: method is nil but UserRepository.Min was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockMin.Lock()
+	mock.calls.Min = append(mock.calls.Min, callInfo)
+	mock.lockMin.Unlock()
+	return mock.MinFunc(ctx, column, filter)
+}
+
+// MinCalls gets all the calls that were made to Min.
+// Check the length with:
+//
+//	len(mockedUserRepository.MinCalls())
+func (mock *MockUserRepository) MinCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockMin.RLock()
+	calls = mock.calls.Min
+	mock.lockMin.RUnlock()
+	return calls
+}
+
+// Purge calls PurgeFunc.
+func (mock *MockUserRepository) Purge(contextMoqParam context.Context, user *types.User) error {
+	if mock.PurgeFunc == nil {
+		panic("MockUserRepository.PurgeFunc: method is nil but UserRepository.Purge was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		User            *types.User
+	}{
+		ContextMoqParam: contextMoqParam,
+		User:            user,
+	}
+	mock.lockPurge.Lock()
+	mock.calls.Purge = append(mock.calls.Purge, callInfo)
+	mock.lockPurge.Unlock()
+	return mock.PurgeFunc(contextMoqParam, user)
+}
+
+// PurgeCalls gets all the calls that were made to Purge.
+// Check the length with:
+//
+//	len(mockedUserRepository.PurgeCalls())
+func (mock *MockUserRepository) PurgeCalls() []struct {
+	ContextMoqParam context.Context
+	User            *types.User
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		User            *types.User
+	}
+	mock.lockPurge.RLock()
+	calls = mock.calls.Purge
+	mock.lockPurge.RUnlock()
+	return calls
+}
+
+// Restore calls RestoreFunc.
+func (mock *MockUserRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	if mock.RestoreFunc == nil {
+		panic("MockUserRepository.RestoreFunc: method is nil but UserRepository.Restore was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestore.Lock()
+	mock.calls.Restore = append(mock.calls.Restore, callInfo)
+	mock.lockRestore.Unlock()
+	return mock.RestoreFunc(ctx, id)
+}
+
+// RestoreCalls gets all the calls that were made to Restore.
+// Check the length with:
+//
+//	len(mockedUserRepository.RestoreCalls())
+func (mock *MockUserRepository) RestoreCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockRestore.RLock()
+	calls = mock.calls.Restore
+	mock.lockRestore.RUnlock()
+	return calls
+}
+
+// Stream calls StreamFunc.
+func (mock *MockUserRepository) Stream(ctx context.Context, m storage.SQLModifier, batchSize int) iter.Seq2[types.User, error] {
+	if mock.StreamFunc == nil {
+		panic("MockUserRepository.StreamFunc: method is nil but UserRepository.Stream was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}{
+		Ctx:       ctx,
+		M:         m,
+		BatchSize: batchSize,
+	}
+	mock.lockStream.Lock()
+	mock.calls.Stream = append(mock.calls.Stream, callInfo)
+	mock.lockStream.Unlock()
+	return mock.StreamFunc(ctx, m, batchSize)
+}
+
+// StreamCalls gets all the calls that were made to Stream.
+// Check the length with:
+//
+//	len(mockedUserRepository.StreamCalls())
+func (mock *MockUserRepository) StreamCalls() []struct {
+	Ctx       context.Context
+	M         storage.SQLModifier
+	BatchSize int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		M         storage.SQLModifier
+		BatchSize int
+	}
+	mock.lockStream.RLock()
+	calls = mock.calls.Stream
+	mock.lockStream.RUnlock()
+	return calls
+}
+
+// Sum calls SumFunc.
+func (mock *MockUserRepository) Sum(ctx context.Context, column string, filter storage.Predicate) (float64, error) {
+	if mock.SumFunc == nil {
+		panic("MockUserRepository.SumFunc: method is nil but UserRepository.Sum was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}{
+		Ctx:    ctx,
+		Column: column,
+		Filter: filter,
+	}
+	mock.lockSum.Lock()
+	mock.calls.Sum = append(mock.calls.Sum, callInfo)
+	mock.lockSum.Unlock()
+	return mock.SumFunc(ctx, column, filter)
+}
+
+// SumCalls gets all the calls that were made to Sum.
+// Check the length with:
+//
+//	len(mockedUserRepository.SumCalls())
+func (mock *MockUserRepository) SumCalls() []struct {
+	Ctx    context.Context
+	Column string
+	Filter storage.Predicate
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Column string
+		Filter storage.Predicate
+	}
+	mock.lockSum.RLock()
+	calls = mock.calls.Sum
+	mock.lockSum.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
 func (mock *MockUserRepository) Update(contextMoqParam context.Context, user *types.User) error {
 	if mock.UpdateFunc == nil {
@@ -394,3 +1119,83 @@ func (mock *MockUserRepository) UpdateCalls() []struct {
 	mock.lockUpdate.RUnlock()
 	return calls
 }
+
+// UpdateWhere calls UpdateWhereFunc.
+func (mock *MockUserRepository) UpdateWhere(ctx context.Context, filter storage.Predicate, values map[string]any) (int64, error) {
+	if mock.UpdateWhereFunc == nil {
+		panic("MockUserRepository.UpdateWhereFunc: method is nil but UserRepository.UpdateWhere was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+		Values: values,
+	}
+	mock.lockUpdateWhere.Lock()
+	mock.calls.UpdateWhere = append(mock.calls.UpdateWhere, callInfo)
+	mock.lockUpdateWhere.Unlock()
+	return mock.UpdateWhereFunc(ctx, filter, values)
+}
+
+// UpdateWhereCalls gets all the calls that were made to UpdateWhere.
+// Check the length with:
+//
+//	len(mockedUserRepository.UpdateWhereCalls())
+func (mock *MockUserRepository) UpdateWhereCalls() []struct {
+	Ctx    context.Context
+	Filter storage.Predicate
+	Values map[string]any
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter storage.Predicate
+		Values map[string]any
+	}
+	mock.lockUpdateWhere.RLock()
+	calls = mock.calls.UpdateWhere
+	mock.lockUpdateWhere.RUnlock()
+	return calls
+}
+
+// UpsertMany calls UpsertManyFunc.
+func (mock *MockUserRepository) UpsertMany(ctx context.Context, ts []types.User, opts storage.UpsertOptions) (int64, error) {
+	if mock.UpsertManyFunc == nil {
+		panic("MockUserRepository.UpsertManyFunc: method is nil but UserRepository.UpsertMany was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Ts   []types.User
+		Opts storage.UpsertOptions
+	}{
+		Ctx:  ctx,
+		Ts:   ts,
+		Opts: opts,
+	}
+	mock.lockUpsertMany.Lock()
+	mock.calls.UpsertMany = append(mock.calls.UpsertMany, callInfo)
+	mock.lockUpsertMany.Unlock()
+	return mock.UpsertManyFunc(ctx, ts, opts)
+}
+
+// UpsertManyCalls gets all the calls that were made to UpsertMany.
+// Check the length with:
+//
+//	len(mockedUserRepository.UpsertManyCalls())
+func (mock *MockUserRepository) UpsertManyCalls() []struct {
+	Ctx  context.Context
+	Ts   []types.User
+	Opts storage.UpsertOptions
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Ts   []types.User
+		Opts storage.UpsertOptions
+	}
+	mock.lockUpsertMany.RLock()
+	calls = mock.calls.UpsertMany
+	mock.lockUpsertMany.RUnlock()
+	return calls
+}
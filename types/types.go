@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 
 	"github.com/zechao158/ecomm/storage"
 )
@@ -30,51 +32,155 @@ type UserRepository interface {
 
 type User struct {
 	ID        uuid.UUID `gorm:"type:uuid;primarykey"`
+	TenantID  string
 	FirstName string
 	LastName  string
 	Email     string
-	Password  string
+	Password  string `audit:"redact"`
+	IsAdmin   bool
 	CreatedAt time.Time
 	UpdatedAt *time.Time
+	DeletedAt gorm.DeletedAt
 }
 
-func (User) TableName() string {
-	return "ecom.users"
+func (User) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "users")
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=3,max=130"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+//go:generate moq -rm -pkg mocks -out mocks/refresh_token_mock.go . RefreshTokenRepository:MockRefreshTokenRepository
+type RefreshTokenRepository interface {
+	storage.CRUDStorer[RefreshToken]
+	// GetByHash returns the refresh token with the given hash.
+	GetByHash(ctx context.Context, hash string, forUpdate bool) (*RefreshToken, error)
+	// RevokeFamily revokes the tokens of the family that are not revoked yet.
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	// RevokeUser revokes the tokens of the user that are not revoked yet, whatever their family.
+	RevokeUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// RefreshToken is a refresh token issued to a user, only the hash of the token is stored. Every use replaces the
+// token with a new one of the same family, so a token used twice reveals that it was stolen.
+type RefreshToken struct {
+	ID       uuid.UUID `gorm:"type:uuid;primarykey"`
+	TenantID string
+	UserID   uuid.UUID `gorm:"type:uuid"`
+	// FamilyID groups the tokens issued from the same login.
+	FamilyID  uuid.UUID `gorm:"type:uuid"`
+	TokenHash string
+	ExpiresAt time.Time
+	// UsedAt is the time the token was replaced, nil while it can still be used.
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (RefreshToken) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "refresh_tokens")
+}
+
+// SkipAudit implements storage.AuditSkipper, the tokens are secrets and change on every refresh.
+func (RefreshToken) SkipAudit() bool {
+	return true
+}
+
+// The actions recorded in the activity history of the users.
+const (
+	ActivityRegister       = "register"
+	ActivityLogin          = "login"
+	ActivityLoginFailed    = "login_failed"
+	ActivityPasswordChange = "password_change"
+	ActivityCheckout       = "checkout"
+)
+
+//go:generate moq -rm -pkg mocks -out mocks/user_activity_mock.go . UserActivityRepository:MockUserActivityRepository
+type UserActivityRepository interface {
+	storage.CRUDStorer[UserActivity]
+	// GetUserActivity returns a page of the activity of the user matching the filter.
+	GetUserActivity(ctx context.Context, userID uuid.UUID, req storage.PageRequest, filter storage.Predicate) (*storage.Page[UserActivity], error)
+}
+
+// UserActivity is an entry of the activity history of a user, such as a login or a checkout.
+type UserActivity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primarykey"`
+	TenantID  string
+	UserID    uuid.UUID `gorm:"type:uuid"`
+	Action    string
+	IP        string
+	UserAgent string
+	// Details describes the action, such as the order of a checkout.
+	Details   string
+	CreatedAt time.Time
+}
+
+func (UserActivity) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "user_activity")
+}
+
+// SkipAudit implements storage.AuditSkipper, the history is already a record of the actions.
+func (UserActivity) SkipAudit() bool {
+	return true
 }
 
 type Product struct {
 	ID          uuid.UUID `gorm:"type:uuid;primarykey"`
+	TenantID    string
 	Name        string
 	Description string
 	Image       string
 	Price       float64
 	Quantity    int
+	Version     int
 	CreatedAt   time.Time
 	UpdatedAt   *time.Time
+	DeletedAt   gorm.DeletedAt
+}
+
+type UpdateProductPayload struct {
+	Name        string  `json:"name" validate:"required"`
+	Description string  `json:"description" validate:"required"`
+	Image       string  `json:"image" validate:"required"`
+	Price       float64 `json:"price" validate:"gte=0"`
+	Quantity    int     `json:"quantity" validate:"gte=0"`
+	// Version is the version of the product being edited, only used when the If-Match header is missing.
+	Version int `json:"version,omitempty"`
 }
 
 //go:generate moq -rm -pkg mocks -out mocks/product_mock.go . ProductRepository:MockProductRepository
 type ProductRepository interface {
 	storage.CRUDStorer[Product]
-	GetProductsByIDs(context.Context, []uuid.UUID) ([]Product, error)
+	// GetProductsByIDs retrieves the products with the given ids, forUpdate locks them until the end of the transaction.
+	GetProductsByIDs(ctx context.Context, ids []uuid.UUID, forUpdate bool) ([]Product, error)
+	// GetTotalInStock returns the number of units in stock, all products included.
+	GetTotalInStock(ctx context.Context) (int64, error)
 }
 
-func (Product) TableName() string {
-	return "ecom.products"
+func (Product) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "products")
 }
 
 type Order struct {
 	ID        uuid.UUID `gorm:"type:uuid;primarykey"`
+	TenantID  string
 	UserID    uuid.UUID `gorm:"type:uuid"`
 	Total     float64
 	Status    string
 	Address   string
 	CreatedAt time.Time
 	UpdatedAt *time.Time
+	DeletedAt gorm.DeletedAt
 }
 
-func (Order) TableName() string {
-	return "ecom.orders"
+func (Order) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "orders")
 }
 
 type OrderItem struct {
@@ -87,13 +193,15 @@ type OrderItem struct {
 	UpdatedAt *time.Time
 }
 
-func (Order) OrderItem() string {
-	return "ecom.order_items"
+func (OrderItem) TableName(namer schema.Namer) string {
+	return storage.TableName(namer, "order_items")
 }
 
 //go:generate moq -rm -pkg mocks -out mocks/product_mock.go . ProductRepository:MockProductRepository
 type OrderRepository interface {
 	storage.CRUDStorer[Order]
+	// CountOrdersByStatus returns the number of orders per status.
+	CountOrdersByStatus(ctx context.Context) (map[string]int64, error)
 }
 
 //go:generate moq -rm -pkg mocks -out mocks/product_mock.go . ProductRepository:MockProductRepository
@@ -106,6 +214,17 @@ type CartItem struct {
 	Quantity  int
 }
 
+// TopicOrderCreated is the outbox topic of the OrderCreatedEvent.
+const TopicOrderCreated = "order.created"
+
+// OrderCreatedEvent is published when a checkout creates an order.
+type OrderCreatedEvent struct {
+	OrderID uuid.UUID  `json:"orderId"`
+	UserID  uuid.UUID  `json:"userId"`
+	Total   float64    `json:"total"`
+	Items   []CartItem `json:"items"`
+}
+
 type CartCheckoutPayload struct {
 	Items []CartItem `json:"items" validate:"required"`
 }